@@ -7,7 +7,12 @@ import (
 	"auth-service/pkg/config"
 	"auth-service/pkg/database"
 	"auth-service/pkg/jwt"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -31,25 +36,77 @@ func main() {
 	}
 
 	// Initialize JWT manager
-	jwtManager, err := jwt.NewJWTManager(
-		cfg.JWT.PrivateKeyPath,
-		cfg.JWT.PublicKeyPath,
-		cfg.JWT.AccessTokenDuration,
-		cfg.JWT.RefreshTokenDuration,
-	)
+	jwtManager, err := jwt.NewJWTManager(&cfg.JWT)
 	if err != nil {
 		log.Fatalf("Failed to initialize JWT manager: %v", err)
 	}
+	log.Printf("Signing tokens with key %s", jwtManager.GetActiveKeyID())
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	securityEventRepo := repository.NewSecurityEventRepository(db)
+	revokedAccessTokenRepo := repository.NewRevokedAccessTokenRepository(db)
+	clientRepo := repository.NewClientRepository(db)
+	authorizationCodeRepo := repository.NewAuthorizationCodeRepository(db)
+	usedClientAssertionRepo := repository.NewUsedClientAssertionRepository(db)
+	usedDPoPProofRepo := repository.NewUsedDPoPProofRepository(db)
+	deviceCodeRepo := repository.NewDeviceCodeRepository(db)
+	consentRepo := repository.NewConsentRepository(db)
+	pushedAuthorizationRequestRepo := repository.NewPushedAuthorizationRequestRepository(db)
+	logoutDeliveryRepo := repository.NewLogoutDeliveryRepository(db)
+	ssoSessionRepo := repository.NewSSOSessionRepository(db)
+
+	// Hash refresh tokens stored in plaintext by earlier versions
+	migrated, err := refreshTokenRepo.BackfillTokenHashes(context.Background(), jwtManager.HashRefreshToken)
+	if err != nil {
+		log.Fatalf("Failed to hash stored refresh tokens: %v", err)
+	}
+	if migrated > 0 {
+		log.Printf("Hashed %d plaintext refresh tokens", migrated)
+	}
+
+	// Keep the access token denylist in sync with revocations from all instances
+	accessTokenDenylist := usecase.NewAccessTokenDenylist(revokedAccessTokenRepo)
+	go accessTokenDenylist.Run(context.Background())
+
+	// Deliver back-channel logout notifications scheduled by any instance
+	logoutNotifier := usecase.NewLogoutNotifier(logoutDeliveryRepo, clientRepo, jwtManager)
+	go logoutNotifier.Run(context.Background())
+
+	go usecase.RunCleanup(context.Background(), "authorization codes", authorizationCodeRepo)
+	go usecase.RunCleanup(context.Background(), "client assertions", usedClientAssertionRepo)
+	go usecase.RunCleanup(context.Background(), "DPoP proofs", usedDPoPProofRepo)
+	go usecase.RunCleanup(context.Background(), "device codes", deviceCodeRepo)
+	go usecase.RunCleanup(context.Background(), "pushed authorization requests", pushedAuthorizationRequestRepo)
+	go usecase.RunCleanup(context.Background(), "logout deliveries", logoutDeliveryRepo)
+	go usecase.RunCleanup(context.Background(), "SSO sessions", ssoSessionRepo)
 
 	// Initialize use cases
-	authUseCase := usecase.NewAuthUseCase(userRepo, refreshTokenRepo, jwtManager)
+	authUseCase := usecase.NewAuthUseCase(userRepo, refreshTokenRepo, securityEventRepo, clientRepo, consentRepo, usedDPoPProofRepo, ssoSessionRepo, jwtManager, accessTokenDenylist, logoutNotifier, &cfg.Session)
+	oauthUseCase := usecase.NewOAuthUseCase(
+		authUseCase,
+		clientRepo,
+		refreshTokenRepo,
+		authorizationCodeRepo,
+		usedClientAssertionRepo,
+		deviceCodeRepo,
+		consentRepo,
+		pushedAuthorizationRequestRepo,
+		securityEventRepo,
+		jwtManager,
+		cfg.Device.VerificationURI,
+	)
+	clientUseCase := usecase.NewClientUseCase(clientRepo, refreshTokenRepo, logoutDeliveryRepo, jwtManager)
+
+	// Load the CAs of tls_client_auth client certificates
+	clientCAs, err := loadCertPool(cfg.Server.TLSClientCAFile)
+	if err != nil {
+		log.Fatalf("Failed to load client CAs: %v", err)
+	}
 
 	// Initialize dependency container
-	container := http.NewContainer(authUseCase, jwtManager)
+	container := http.NewContainer(authUseCase, oauthUseCase, clientUseCase, jwtManager, cfg.Admin.APIKey, cfg.Server.ClientCertHeader, clientCAs, cfg.Registration.InitialAccessToken)
 
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
@@ -69,7 +126,56 @@ func main() {
 
 	// Start server
 	log.Printf("Starting server on port %s...", cfg.Server.Port)
-	if err := app.Listen(":" + cfg.Server.Port); err != nil {
+	if cfg.Server.TLSCertFile != "" {
+		err = listenTLS(app, &cfg.Server, clientCAs)
+	} else {
+		err = app.Listen(":" + cfg.Server.Port)
+	}
+	if err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// loadCertPool loads the PEM certificates of a file, nil if path is empty
+func loadCertPool(path string) (*x509.CertPool, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	caPEM, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in %s", path)
+	}
+	return pool, nil
+}
+
+// listenTLS serves HTTPS and requests client certificates, which clients use
+// for mutual TLS client authentication and certificate-bound tokens (RFC 8705)
+// Certificates are optional, so other clients can still connect. With client
+// CAs, only certificates issued by them are accepted
+func listenTLS(app *fiber.App, cfg *config.ServerConfig, clientCAs *x509.CertPool) error {
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequestClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAs != nil {
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	ln, err := tls.Listen("tcp", ":"+cfg.Port, tlsConfig)
+	if err != nil {
+		return err
+	}
+	return app.Listener(ln)
+}
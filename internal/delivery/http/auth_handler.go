@@ -54,6 +54,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 		})
 	}
 
+	req.Client = clientMetadata(c)
 	resp, err := h.authUseCase.Register(c.Context(), req)
 	if err != nil {
 		if err == domain.ErrUserAlreadyExists {
@@ -79,6 +80,7 @@ func (h *AuthHandler) Register(c *fiber.Ctx) error {
 // @Success 200 {object} usecase.AuthResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *fiber.Ctx) error {
 	var req usecase.LoginRequest
@@ -94,13 +96,29 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 		})
 	}
 
+	req.Client = clientMetadata(c)
 	resp, err := h.authUseCase.Login(c.Context(), req)
 	if err != nil {
+		if err == domain.ErrInvalidScope || err == domain.ErrClientIDRequired {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		if err == domain.ErrInvalidCredentials {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid credentials",
 			})
 		}
+		if err == domain.ErrUserSuspended {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "user suspended",
+			})
+		}
+		if err == domain.ErrInvalidClient {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "unknown client",
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to login",
 		})
@@ -119,6 +137,8 @@ func (h *AuthHandler) Login(c *fiber.Ctx) error {
 // @Success 200 {object} usecase.AuthResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /auth/refresh [post]
 func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 	var req usecase.RefreshTokenRequest
@@ -134,13 +154,25 @@ func (h *AuthHandler) RefreshToken(c *fiber.Ctx) error {
 		})
 	}
 
+	req.Client = clientMetadata(c)
 	resp, err := h.authUseCase.RefreshToken(c.Context(), req)
 	if err != nil {
-		if err == domain.ErrInvalidToken || err == domain.ErrRefreshTokenExpired || err == domain.ErrRefreshTokenRevoked {
+		if err == domain.ErrInvalidToken || err == domain.ErrRefreshTokenExpired || err == domain.ErrRefreshTokenRevoked || err == domain.ErrRefreshTokenReused {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
+		if err == domain.ErrUserSuspended {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "user suspended",
+			})
+		}
+		if err == domain.ErrRefreshTokenRotated {
+			// Lost a race against a concurrent refresh with the same token
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to refresh token",
 		})
@@ -214,6 +246,302 @@ func (h *AuthHandler) LogoutAll(c *fiber.Ctx) error {
 	})
 }
 
+// RevokeAccessToken revokes a single access token of the authenticated user
+// @Summary Revoke access token
+// @Description Revoke an access token before it expires; defaults to the token used for this request
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body usecase.RevokeAccessTokenRequest false "Revoke access token request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /auth/revoke-access-token [post]
+func (h *AuthHandler) RevokeAccessToken(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req usecase.RevokeAccessTokenRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+	if req.AccessToken == "" {
+		req.AccessToken, _ = GetAccessTokenFromContext(c)
+	}
+
+	err := h.authUseCase.RevokeAccessToken(c.Context(), userID, req.AccessToken)
+	if err != nil {
+		if err == domain.ErrInvalidToken {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid access token",
+			})
+		}
+		if err == domain.ErrUnauthorized {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "access token belongs to another user",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to revoke access token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "access token revoked",
+	})
+}
+
+// ChangePassword changes the authenticated user's password
+// @Summary Change password
+// @Description Change the password of the authenticated user and sign out all devices
+// @Tags auth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body usecase.ChangePasswordRequest true "Change password request"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /auth/password [put]
+func (h *AuthHandler) ChangePassword(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req usecase.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if req.CurrentPassword == "" || req.NewPassword == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "current password and new password are required",
+		})
+	}
+
+	if len(req.NewPassword) < 8 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "password must be at least 8 characters",
+		})
+	}
+
+	err := h.authUseCase.ChangePassword(c.Context(), userID, req)
+	if err != nil {
+		if err == domain.ErrInvalidCredentials {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid credentials",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to change password",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "password changed, please log in again",
+	})
+}
+
+// DeleteAccount deletes the authenticated user's account
+// @Summary Delete account
+// @Description Delete the authenticated user and invalidate all of their tokens
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /auth/account [delete]
+func (h *AuthHandler) DeleteAccount(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	if err := h.authUseCase.DeleteUser(c.Context(), userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to delete account",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "account deleted",
+	})
+}
+
+// SuspendUser suspends a user on behalf of an administrator
+// @Summary Suspend user
+// @Description Suspend a user, who can no longer sign in, and invalidate all of their tokens and sessions
+// @Tags admin
+// @Security AdminAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/users/{id}/suspend [post]
+func (h *AuthHandler) SuspendUser(c *fiber.Ctx) error {
+	if err := h.authUseCase.SuspendUser(c.Context(), c.Params("id")); err != nil {
+		if err == domain.ErrUserNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to suspend user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "user suspended",
+	})
+}
+
+// ListSessions lists the authenticated user's active sessions
+// @Summary List sessions
+// @Description List the active sessions (devices) of the authenticated user
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} usecase.SessionResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	sessionID, _ := GetSessionIDFromContext(c)
+	sessions, err := h.authUseCase.ListSessions(c.Context(), userID, sessionID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list sessions",
+		})
+	}
+
+	return c.JSON(sessions)
+}
+
+// RevokeSession revokes one of the authenticated user's sessions
+// @Summary Revoke session
+// @Description Revoke a single session (device) of the authenticated user
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	err := h.authUseCase.RevokeSession(c.Context(), userID, c.Params("id"))
+	if err != nil {
+		if err == domain.ErrSessionNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "session not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to revoke session",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "session revoked",
+	})
+}
+
+// ListConsents lists the third-party clients the authenticated user granted access
+// @Summary List consents
+// @Description List the third-party clients the authenticated user consented to, with the granted scopes
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} usecase.ConsentResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /auth/consents [get]
+func (h *AuthHandler) ListConsents(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	consents, err := h.authUseCase.ListConsents(c.Context(), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list consents",
+		})
+	}
+
+	return c.JSON(consents)
+}
+
+// RevokeConsent revokes a third-party client's access to the authenticated user's account
+// @Summary Revoke consent
+// @Description Withdraw the consent for a third-party client and revoke its refresh tokens
+// @Tags auth
+// @Security BearerAuth
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /auth/consents/{client_id} [delete]
+func (h *AuthHandler) RevokeConsent(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	err := h.authUseCase.RevokeConsent(c.Context(), userID, c.Params("client_id"))
+	if err != nil {
+		if err == domain.ErrConsentNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "consent not found",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to revoke consent",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "consent revoked",
+	})
+}
+
 // GetProfile retrieves the authenticated user's profile
 // @Summary Get user profile
 // @Description Get the profile of the authenticated user
@@ -258,3 +586,11 @@ func (h *AuthHandler) GetJWKS(c *fiber.Ctx) error {
 
 	return c.JSON(jwks)
 }
+
+// clientMetadata extracts the client metadata recorded with a session
+func clientMetadata(c *fiber.Ctx) usecase.ClientMetadata {
+	return usecase.ClientMetadata{
+		IPAddress: c.IP(),
+		UserAgent: c.Get(fiber.HeaderUserAgent),
+	}
+}
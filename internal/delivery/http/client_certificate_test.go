@@ -0,0 +1,141 @@
+package http
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testCertHeader = "X-Client-Cert"
+
+// newTestCertificate creates a client certificate signed by the parent, or
+// self-signed if parent is nil
+func newTestCertificate(t *testing.T, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: "worker"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestClientCertificateFromHeader(t *testing.T) {
+	ca, caKey := newTestCertificate(t, true, nil, nil)
+	issued, _ := newTestCertificate(t, false, ca, caKey)
+	selfSigned, _ := newTestCertificate(t, false, nil, nil)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	tests := []struct {
+		name         string
+		cert         *x509.Certificate
+		clientCAs    *x509.CertPool
+		wantCert     bool
+		wantVerified bool
+	}{
+		{"issued by a client CA", issued, clientCAs, true, true},
+		{"self-signed", selfSigned, clientCAs, true, false},
+		{"no client CAs configured", issued, nil, true, false},
+		{"no certificate", nil, clientCAs, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := fiber.New()
+			app.Get("/", func(c *fiber.Ctx) error {
+				cert, verified := clientCertificate(c, testCertHeader, tt.clientCAs)
+				if (cert != nil) != tt.wantCert || verified != tt.wantVerified {
+					return c.SendStatus(fiber.StatusTeapot)
+				}
+				return c.SendStatus(fiber.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.cert != nil {
+				encoded := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tt.cert.Raw})
+				req.Header.Set(testCertHeader, url.PathEscape(string(encoded)))
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusOK {
+				t.Errorf("unexpected certificate or verification result (status %d)", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestClientCertificateIgnoresHeaderOnTLS(t *testing.T) {
+	serverCert, serverKey := newTestCertificate(t, false, nil, nil)
+	clientCert, _ := newTestCertificate(t, false, nil, nil)
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/", func(c *fiber.Ctx) error {
+		if cert, _ := clientCertificate(c, testCertHeader, nil); cert != nil {
+			return c.SendStatus(fiber.StatusTeapot)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequestClientCert,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	defer app.Shutdown()
+
+	// A client connecting directly can't pass a certificate in the header
+	req, err := http.NewRequest("GET", "https://"+ln.Addr().String()+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Raw})
+	req.Header.Set(testCertHeader, url.PathEscape(string(encoded)))
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != fiber.StatusOK {
+		t.Errorf("the forwarded certificate header was read on a TLS connection (status %d)", resp.StatusCode)
+	}
+}
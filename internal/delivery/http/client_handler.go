@@ -0,0 +1,177 @@
+package http
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/usecase"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientHandler handles OAuth client management HTTP requests
+type ClientHandler struct {
+	clientUseCase usecase.ClientUseCase
+}
+
+// NewClientHandler creates a new client handler
+func NewClientHandler(clientUseCase usecase.ClientUseCase) *ClientHandler {
+	return &ClientHandler{
+		clientUseCase: clientUseCase,
+	}
+}
+
+// Create handles OAuth client registration by an administrator
+// @Summary Create client
+// @Description Register an OAuth client; the client secret of confidential clients is only returned once
+// @Tags admin
+// @Security AdminAuth
+// @Accept json
+// @Produce json
+// @Param request body usecase.ClientRequest true "Client request"
+// @Success 201 {object} usecase.ClientResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /admin/clients [post]
+func (h *ClientHandler) Create(c *fiber.Ctx) error {
+	var req usecase.ClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	resp, err := h.clientUseCase.CreateClient(c.Context(), req)
+	if err != nil {
+		return clientError(c, err, "failed to create client")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// List handles listing OAuth clients
+// @Summary List clients
+// @Description List all registered OAuth clients
+// @Tags admin
+// @Security AdminAuth
+// @Produce json
+// @Success 200 {array} usecase.ClientResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /admin/clients [get]
+func (h *ClientHandler) List(c *fiber.Ctx) error {
+	clients, err := h.clientUseCase.ListClients(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to list clients",
+		})
+	}
+
+	return c.JSON(clients)
+}
+
+// Get handles retrieving an OAuth client
+// @Summary Get client
+// @Description Get a registered OAuth client
+// @Tags admin
+// @Security AdminAuth
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} usecase.ClientResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/clients/{id} [get]
+func (h *ClientHandler) Get(c *fiber.Ctx) error {
+	resp, err := h.clientUseCase.GetClient(c.Context(), c.Params("id"))
+	if err != nil {
+		return clientError(c, err, "failed to get client")
+	}
+
+	return c.JSON(resp)
+}
+
+// Update handles replacing the settings of an OAuth client
+// @Summary Update client
+// @Description Replace the settings of a registered OAuth client; the secret is kept
+// @Tags admin
+// @Security AdminAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Client ID"
+// @Param request body usecase.ClientRequest true "Client request"
+// @Success 200 {object} usecase.ClientResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/clients/{id} [put]
+func (h *ClientHandler) Update(c *fiber.Ctx) error {
+	var req usecase.ClientRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	resp, err := h.clientUseCase.UpdateClient(c.Context(), c.Params("id"), req)
+	if err != nil {
+		return clientError(c, err, "failed to update client")
+	}
+
+	return c.JSON(resp)
+}
+
+// Delete handles deleting an OAuth client
+// @Summary Delete client
+// @Description Delete a registered OAuth client
+// @Tags admin
+// @Security AdminAuth
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/clients/{id} [delete]
+func (h *ClientHandler) Delete(c *fiber.Ctx) error {
+	if err := h.clientUseCase.DeleteClient(c.Context(), c.Params("id")); err != nil {
+		return clientError(c, err, "failed to delete client")
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "client deleted",
+	})
+}
+
+// ListLogoutDeliveries handles listing the back-channel logout notifications of a client
+// @Summary List logout deliveries
+// @Description List the recent back-channel logout notifications of a client and their delivery status
+// @Tags admin
+// @Security AdminAuth
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {array} usecase.LogoutDeliveryResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/clients/{id}/logout-deliveries [get]
+func (h *ClientHandler) ListLogoutDeliveries(c *fiber.Ctx) error {
+	deliveries, err := h.clientUseCase.ListLogoutDeliveries(c.Context(), c.Params("id"))
+	if err != nil {
+		return clientError(c, err, "failed to list logout deliveries")
+	}
+
+	return c.JSON(deliveries)
+}
+
+// clientError maps client management errors to HTTP responses
+func clientError(c *fiber.Ctx, err error, message string) error {
+	if err == domain.ErrClientNotFound {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "client not found",
+		})
+	}
+	if errors.Is(err, domain.ErrInvalidClientMetadata) || errors.Is(err, domain.ErrInvalidRedirectURI) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": message,
+	})
+}
@@ -3,12 +3,15 @@ package http
 import (
 	"auth-service/internal/usecase"
 	"auth-service/pkg/jwt"
+	"crypto/x509"
 )
 
 // Container holds all dependencies for HTTP handlers
 type Container struct {
 	// Use cases
-	AuthUseCase usecase.AuthUseCase
+	AuthUseCase   usecase.AuthUseCase
+	OAuthUseCase  usecase.OAuthUseCase
+	ClientUseCase usecase.ClientUseCase
 	// Add more use cases here as your application grows
 	// UserUseCase usecase.UserUseCase
 	// ProductUseCase usecase.ProductUseCase
@@ -20,15 +23,34 @@ type Container struct {
 	// EmailService *email.Service
 	// StorageService *storage.Service
 	// etc.
+
+	// Configuration
+	AdminAPIKey                    string
+	ClientCertHeader               string
+	ClientCAs                      *x509.CertPool
+	RegistrationInitialAccessToken string
 }
 
 // NewContainer creates a new dependency container
 func NewContainer(
 	authUseCase usecase.AuthUseCase,
+	oauthUseCase usecase.OAuthUseCase,
+	clientUseCase usecase.ClientUseCase,
 	jwtManager *jwt.JWTManager,
+	adminAPIKey string,
+	clientCertHeader string,
+	clientCAs *x509.CertPool,
+	registrationInitialAccessToken string,
 ) *Container {
 	return &Container{
-		AuthUseCase: authUseCase,
-		JWTManager:  jwtManager,
+		AuthUseCase:   authUseCase,
+		OAuthUseCase:  oauthUseCase,
+		ClientUseCase: clientUseCase,
+		JWTManager:    jwtManager,
+		AdminAPIKey:   adminAPIKey,
+
+		ClientCertHeader:               clientCertHeader,
+		ClientCAs:                      clientCAs,
+		RegistrationInitialAccessToken: registrationInitialAccessToken,
 	}
 }
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSRFToken(t *testing.T) {
+	app := fiber.New()
+	app.Get("/form", func(c *fiber.Ctx) error {
+		return c.SendString(csrfToken(c))
+	})
+	app.Post("/form", func(c *fiber.Ctx) error {
+		if !validCSRFToken(c) {
+			return c.SendStatus(fiber.StatusForbidden)
+		}
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/form", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookie || !cookies[0].Secure || !cookies[0].HttpOnly {
+		t.Fatalf("expected a secure HttpOnly CSRF cookie, got %v", cookies)
+	}
+	token := cookies[0].Value
+
+	tests := []struct {
+		name       string
+		cookie     string
+		field      string
+		wantStatus int
+	}{
+		{"matching token", token, token, fiber.StatusOK},
+		{"missing field", token, "", fiber.StatusForbidden},
+		{"missing cookie", "", token, fiber.StatusForbidden},
+		{"other token", token, "other", fiber.StatusForbidden},
+		{"both missing", "", "", fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{csrfField: {tt.field}}
+			req := httptest.NewRequest("POST", "/form", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				req.Header.Set("Cookie", csrfCookie+"="+tt.cookie)
+			}
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,14 +1,36 @@
 package http
 
 import (
+	"auth-service/internal/domain"
 	"auth-service/internal/usecase"
+	"auth-service/pkg/jwt"
+	"crypto/subtle"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// Principal types of authenticated requests
+const (
+	PrincipalUser    = "user"
+	PrincipalMachine = "machine" // a client acting on its own behalf
+)
+
+// DPoP request and response headers (RFC 9449)
+const (
+	HeaderDPoP      = "DPoP"
+	HeaderDPoPNonce = "DPoP-Nonce"
+)
+
 // AuthMiddleware validates JWT access token
-func AuthMiddleware(authUseCase usecase.AuthUseCase) fiber.Handler {
+// Both users and machine principals are accepted; use RequireUser for
+// routes that act on behalf of a user. Pass jwt.WithAudience to only accept
+// tokens intended for the protected API. Tokens bound to a DPoP key must be
+// sent with the DPoP scheme and a proof of possession of the key, tokens
+// bound to a client certificate over mutual TLS with the same certificate.
+// clientCertHeader is the header a TLS-terminating proxy forwards client
+// certificates in, if any
+func AuthMiddleware(authUseCase usecase.AuthUseCase, clientCertHeader string, opts ...jwt.ValidationOption) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get authorization header
 		authHeader := c.Get("Authorization")
@@ -18,32 +40,199 @@ func AuthMiddleware(authUseCase usecase.AuthUseCase) fiber.Handler {
 			})
 		}
 
-		// Check if it's a Bearer token
+		// Check if it's a Bearer or DPoP token
 		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
+		if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "DPoP") {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid authorization header format",
 			})
 		}
 
-		token := parts[1]
+		scheme, token := parts[0], parts[1]
 
 		// Validate token
-		claims, err := authUseCase.ValidateAccessToken(c.Context(), token)
+		claims, err := authUseCase.ValidateAccessToken(c.Context(), token, opts...)
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid or expired token",
 			})
 		}
 
-		// Store user info in context
-		c.Locals("userID", claims.UserID)
-		c.Locals("email", claims.Email)
+		// Certificate-bound tokens are only accepted over a connection with
+		// the same client certificate (RFC 8705 section 3)
+		if x5t := claims.CertificateThumbprint(); x5t != "" {
+			cert, _ := clientCertificate(c, clientCertHeader, nil)
+			if cert == nil || jwt.CertificateThumbprint(cert) != x5t {
+				c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "client certificate does not match the token",
+				})
+			}
+		}
+
+		// Verify the proof of possession of DPoP-bound tokens (RFC 9449 section 7)
+		if jkt := claims.DPoPKeyThumbprint(); jkt != "" || scheme == "DPoP" {
+			if jkt == "" {
+				return dpopChallenge(c, "invalid_token", "token is not bound to a DPoP key")
+			}
+			if scheme != "DPoP" {
+				return dpopChallenge(c, "invalid_token", "DPoP-bound token requires the DPoP authorization scheme")
+			}
+
+			// Clients learn the nonce for their next proof from every response
+			c.Set(HeaderDPoPNonce, authUseCase.DPoPNonce())
+
+			proof, ok := dpopProof(c)
+			if !ok || proof == nil {
+				return dpopChallenge(c, usecase.ErrorInvalidDPoPProof, "a single DPoP proof is required")
+			}
+			proof.AccessToken = token
+
+			proofJKT, err := authUseCase.VerifyDPoPProof(c.Context(), *proof)
+			if err == domain.ErrDPoPNonceRequired {
+				return dpopChallenge(c, usecase.ErrorUseDPoPNonce, "DPoP proof must include the server nonce")
+			}
+			if err != nil || proofJKT != jkt {
+				return dpopChallenge(c, usecase.ErrorInvalidDPoPProof, "invalid DPoP proof")
+			}
+		}
+
+		// Store principal info in context
+		if claims.IsMachine() {
+			c.Locals("principalType", PrincipalMachine)
+		} else {
+			c.Locals("principalType", PrincipalUser)
+			c.Locals("userID", claims.UserID)
+			c.Locals("email", claims.Email)
+			c.Locals("sessionID", claims.SessionID)
+		}
+		c.Locals("clientID", claims.ClientID)
+		c.Locals("scope", claims.Scope)
+		c.Locals("accessToken", token)
+
+		return c.Next()
+	}
+}
+
+// RequireUser rejects machine principals
+// Must be used after AuthMiddleware
+func RequireUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if principalType, _ := GetPrincipalTypeFromContext(c); principalType != PrincipalUser {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "a user access token is required",
+			})
+		}
 
 		return c.Next()
 	}
 }
 
+// RequireScope rejects tokens of OAuth clients that were not granted a scope
+// Tokens of first-party apps signing in directly carry no client and are
+// not limited by scopes. Must be used after AuthMiddleware
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if clientID, _ := GetClientIDFromContext(c); clientID == "" {
+			return c.Next()
+		}
+
+		granted, _ := GetScopeFromContext(c)
+		for _, s := range strings.Fields(granted) {
+			if s == scope {
+				return c.Next()
+			}
+		}
+
+		// RFC 6750 section 3.1
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="`+scope+`"`)
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "insufficient scope",
+		})
+	}
+}
+
+// AdminMiddleware authenticates admin API requests with the configured API key
+// The admin API is disabled if no key is configured
+func AdminMiddleware(apiKey string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if apiKey == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "admin API is disabled",
+			})
+		}
+
+		key, ok := strings.CutPrefix(c.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(key), []byte(apiKey)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "invalid admin API key",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// RegistrationMiddleware authenticates dynamic client registration requests
+// with the configured initial access token (RFC 7591 section 3)
+// Registration is disabled if no token is configured
+func RegistrationMiddleware(initialAccessToken string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if initialAccessToken == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "dynamic client registration is disabled",
+			})
+		}
+
+		token := bearerToken(c)
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(initialAccessToken)) != 1 {
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return oauthError(c, fiber.StatusUnauthorized, "invalid_token", "invalid initial access token")
+		}
+
+		return c.Next()
+	}
+}
+
+// dpopProof returns the DPoP proof sent with a request, nil if there is none
+// Requests with more than one proof are rejected (RFC 9449 section 4.3)
+func dpopProof(c *fiber.Ctx) (*usecase.DPoPProofRequest, bool) {
+	proofs := c.Request().Header.PeekAll(HeaderDPoP)
+	if len(proofs) == 0 {
+		return nil, true
+	}
+	if len(proofs) > 1 {
+		return nil, false
+	}
+
+	return &usecase.DPoPProofRequest{
+		Proof:  string(proofs[0]),
+		Method: c.Method(),
+		Path:   c.Path(),
+	}, true
+}
+
+// dpopChallenge rejects a request with a DPoP-bound token (RFC 9449 section 7.1)
+func dpopChallenge(c *fiber.Ctx, code, description string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `DPoP error="`+code+`", algs="`+strings.Join(jwt.SupportedAlgorithms, " ")+`"`)
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": description,
+	})
+}
+
+// GetPrincipalTypeFromContext retrieves the principal type (user or machine) from the context
+func GetPrincipalTypeFromContext(c *fiber.Ctx) (string, bool) {
+	principalType, ok := c.Locals("principalType").(string)
+	return principalType, ok
+}
+
+// GetClientIDFromContext retrieves the OAuth client the token was issued to
+// from the context, empty for first-party apps
+func GetClientIDFromContext(c *fiber.Ctx) (string, bool) {
+	clientID, ok := c.Locals("clientID").(string)
+	return clientID, ok
+}
+
 // GetUserIDFromContext retrieves the user ID from the context
 func GetUserIDFromContext(c *fiber.Ctx) (string, bool) {
 	userID, ok := c.Locals("userID").(string)
@@ -55,3 +244,21 @@ func GetEmailFromContext(c *fiber.Ctx) (string, bool) {
 	email, ok := c.Locals("email").(string)
 	return email, ok
 }
+
+// GetSessionIDFromContext retrieves the session ID from the context
+func GetSessionIDFromContext(c *fiber.Ctx) (string, bool) {
+	sessionID, ok := c.Locals("sessionID").(string)
+	return sessionID, ok
+}
+
+// GetScopeFromContext retrieves the granted scopes from the context
+func GetScopeFromContext(c *fiber.Ctx) (string, bool) {
+	scope, ok := c.Locals("scope").(string)
+	return scope, ok
+}
+
+// GetAccessTokenFromContext retrieves the raw access token from the context
+func GetAccessTokenFromContext(c *fiber.Ctx) (string, bool) {
+	token, ok := c.Locals("accessToken").(string)
+	return token, ok
+}
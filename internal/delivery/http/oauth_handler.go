@@ -0,0 +1,765 @@
+package http
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/usecase"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// OAuthHandler handles OAuth 2.0 protocol HTTP requests
+// Errors use the OAuth 2.0 error response format (RFC 6749 section 5.2)
+type OAuthHandler struct {
+	oauthUseCase        usecase.OAuthUseCase
+	clientCertHeader    string
+	clientCAs           *x509.CertPool
+	registrationEnabled bool
+}
+
+// NewOAuthHandler creates a new OAuth handler
+// clientCertHeader is the header client certificates are forwarded in by a
+// TLS-terminating proxy, empty if there is none. clientCAs are the CAs
+// tls_client_auth certificates must be issued by, nil if there are none.
+// registrationEnabled advertises dynamic client registration in the
+// discovery document
+func NewOAuthHandler(oauthUseCase usecase.OAuthUseCase, clientCertHeader string, clientCAs *x509.CertPool, registrationEnabled bool) *OAuthHandler {
+	return &OAuthHandler{
+		oauthUseCase:        oauthUseCase,
+		clientCertHeader:    clientCertHeader,
+		clientCAs:           clientCAs,
+		registrationEnabled: registrationEnabled,
+	}
+}
+
+// Introspect handles token introspection (RFC 7662)
+// @Summary Introspect token
+// @Description Get the state and metadata of an access or refresh token. Requires resource server authentication.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200 {object} usecase.IntrospectionResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(c *fiber.Ctx) error {
+	if _, err := h.oauthUseCase.AuthenticateClient(c.Context(), h.clientCredentials(c)); err != nil {
+		return invalidClient(c)
+	}
+
+	var req usecase.IntrospectionRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "token is required")
+	}
+
+	resp, err := h.oauthUseCase.Introspect(c.Context(), req)
+	if err != nil {
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", "failed to introspect token")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(resp)
+}
+
+// Revoke handles token revocation (RFC 7009)
+// @Summary Revoke token
+// @Description Revoke an access or refresh token issued to the authenticated client. Requires client authentication.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to revoke"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Success 200
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(c *fiber.Ctx) error {
+	clientID, err := h.oauthUseCase.AuthenticateClient(c.Context(), h.clientCredentials(c))
+	if err != nil {
+		return invalidClient(c)
+	}
+
+	var req usecase.RevocationRequest
+	if err := c.BodyParser(&req); err != nil || req.Token == "" {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_request", "token is required")
+	}
+	req.ClientID = clientID
+
+	if err := h.oauthUseCase.Revoke(c.Context(), req); err != nil {
+		return oauthError(c, fiber.StatusServiceUnavailable, "temporarily_unavailable", "failed to revoke token")
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// UserInfo returns the claims of the authenticated user (OpenID Connect)
+// @Summary Get userinfo
+// @Description Get the claims of the authenticated user, filtered by the scopes of the access token. Requires the openid scope.
+// @Tags oauth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} usecase.UserInfoResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /userinfo [get]
+func (h *OAuthHandler) UserInfo(c *fiber.Ctx) error {
+	userID, _ := GetUserIDFromContext(c)
+	scope, _ := GetScopeFromContext(c)
+
+	resp, err := h.oauthUseCase.UserInfo(c.Context(), userID, scope)
+	if err != nil {
+		switch err {
+		case domain.ErrInsufficientScope:
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="openid"`)
+			return oauthError(c, fiber.StatusForbidden, "insufficient_scope", "access token lacks the openid scope")
+		case domain.ErrUserNotFound:
+			c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+			return oauthError(c, fiber.StatusUnauthorized, "invalid_token", "user not found")
+		default:
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "failed to get userinfo")
+		}
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(resp)
+}
+
+// Discovery returns the OpenID Connect discovery document
+// @Summary Get OpenID Connect configuration
+// @Description Get the OpenID Provider metadata
+// @Tags oauth
+// @Produce json
+// @Success 200 {object} usecase.ProviderMetadata
+// @Router /.well-known/openid-configuration [get]
+func (h *OAuthHandler) Discovery(c *fiber.Ctx) error {
+	metadata := h.oauthUseCase.Discovery()
+	if h.registrationEnabled {
+		metadata.RegistrationEndpoint = metadata.Issuer + "/oauth/register"
+	}
+	return c.JSON(metadata)
+}
+
+// Authorize starts the authorization code flow, signing the user in through
+// the browser's SSO session or showing the sign-in form
+// @Summary Authorization endpoint
+// @Description Start the authorization code flow with PKCE (S256). Users with an SSO session are redirected to the client right away, others see the sign-in form.
+// @Tags oauth
+// @Produce html
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string false "Space-separated scopes"
+// @Param state query string false "Opaque value returned to the client"
+// @Param nonce query string false "OpenID Connect nonce"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Param prompt query string false "none to fail with login_required or consent_required instead of showing a form, login to always show the sign-in form"
+// @Param max_age query int false "Maximum seconds since the user signed in, after which the sign-in form is shown"
+// @Param request_uri query string false "Request URI of a pushed authorization request, which replaces the other parameters except client_id"
+// @Success 200
+// @Success 302
+// @Failure 400
+// @Router /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(c *fiber.Ctx) error {
+	var req usecase.AuthorizationRequest
+	if err := c.QueryParser(&req); err != nil {
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "Invalid authorization request.")
+	}
+	req.Client = clientMetadata(c)
+	req.SessionToken = c.Cookies(ssoSessionCookie)
+
+	req, err := h.oauthUseCase.ResolveAuthorizationRequest(c.Context(), req)
+	if err != nil {
+		return authorizationError(c, req, err)
+	}
+
+	resp, err := h.oauthUseCase.AuthorizeWithSession(c.Context(), req)
+	if err != nil {
+		if err == domain.ErrLoginRequired {
+			return renderPage(c, fiber.StatusOK, loginPage, loginPageData{Request: req, CSRFToken: csrfToken(c)})
+		}
+		return authorizationError(c, req, err)
+	}
+
+	return authorizationResult(c, resp)
+}
+
+// AuthorizeSubmit handles the sign-in form of the authorization code flow
+// @Summary Submit sign-in form
+// @Description Authenticate the user and redirect to the client with an authorization code, or show the consent form to third-party clients
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param email formData string true "Email"
+// @Param password formData string true "Password"
+// @Param csrf_token formData string true "CSRF token of the sign-in form"
+// @Success 302
+// @Failure 400
+// @Failure 401
+// @Failure 403
+// @Router /oauth/authorize [post]
+func (h *OAuthHandler) AuthorizeSubmit(c *fiber.Ctx) error {
+	// Otherwise another site could sign the browser in to an account of its choosing
+	if !validCSRFToken(c) {
+		return renderPage(c, fiber.StatusForbidden, errorPage, "The form expired, please reload the page and try again.")
+	}
+
+	var req usecase.AuthorizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "Invalid authorization request.")
+	}
+	req.Client = clientMetadata(c)
+	req.SessionToken = c.Cookies(ssoSessionCookie)
+
+	req, err := h.oauthUseCase.ResolveAuthorizationRequest(c.Context(), req)
+	if err != nil {
+		return authorizationError(c, req, err)
+	}
+
+	email := c.FormValue("email")
+	resp, err := h.oauthUseCase.Authorize(c.Context(), req, email, c.FormValue("password"))
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidCredentials:
+			return renderPage(c, fiber.StatusUnauthorized, loginPage, loginPageData{
+				Request:   req,
+				Email:     email,
+				CSRFToken: csrfToken(c),
+				Error:     "Invalid email or password.",
+			})
+		case domain.ErrUserSuspended:
+			err = usecase.NewOAuthError(usecase.ErrorAccessDenied, "user suspended")
+		}
+		return authorizationError(c, req, err)
+	}
+	setSSOSessionCookie(c, resp.Session)
+
+	return authorizationResult(c, resp)
+}
+
+// AuthorizeConsent handles the consent form of the authorization code flow
+// @Summary Submit consent form
+// @Description Grant or deny a third-party client the requested scopes and redirect to the client
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param consent_challenge formData string true "Consent challenge of the consent form"
+// @Param action formData string true "approve or deny"
+// @Success 302
+// @Failure 400
+// @Router /oauth/authorize/consent [post]
+func (h *OAuthHandler) AuthorizeConsent(c *fiber.Ctx) error {
+	var req usecase.ConsentDecisionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "Invalid request.")
+	}
+	req.Approve = c.FormValue("action") == "approve"
+
+	resp, err := h.oauthUseCase.DecideConsent(c.Context(), req)
+	if err != nil {
+		if err == domain.ErrAuthCodeNotFound {
+			return renderPage(c, fiber.StatusBadRequest, errorPage, "The authorization request expired, please try again.")
+		}
+		return renderPage(c, fiber.StatusInternalServerError, errorPage, "Something went wrong, please try again.")
+	}
+
+	return authorizationResponse(c, resp)
+}
+
+// authorizationResult shows the consent form if the user has to consent to
+// the request, or redirects to the client otherwise
+func authorizationResult(c *fiber.Ctx, resp *usecase.AuthorizationResponse) error {
+	if resp.ConsentChallenge != "" {
+		return renderPage(c, fiber.StatusOK, consentPage, consentPageData{
+			Challenge: resp.ConsentChallenge,
+			Prompt:    resp.Consent,
+		})
+	}
+	return authorizationResponse(c, resp)
+}
+
+// authorizationResponse redirects to the client with the authorization code,
+// or with an access_denied error if the user denied the request
+func authorizationResponse(c *fiber.Ctx, resp *usecase.AuthorizationResponse) error {
+	params := url.Values{"code": {resp.Code}}
+	if resp.Code == "" {
+		params = url.Values{
+			"error":             {usecase.ErrorAccessDenied},
+			"error_description": {"the user denied the request"},
+		}
+	}
+	if resp.State != "" {
+		params.Set("state", resp.State)
+	}
+	return redirectToClient(c, resp.RedirectURI, params)
+}
+
+// Token handles the token endpoint (RFC 6749 section 3.2)
+// @Summary Token endpoint
+// @Description Exchange an authorization code (with PKCE verifier), a refresh token, client credentials, a device code or another access token (token exchange) for tokens
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token, client_credentials, urn:ietf:params:oauth:grant-type:device_code or urn:ietf:params:oauth:grant-type:token-exchange"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI of the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Requested scope (client_credentials)"
+// @Param resource formData []string false "Resource servers the access token is for (RFC 8707)"
+// @Param device_code formData string false "Device code (device_code)"
+// @Param subject_token formData string false "Access token of the user (token exchange)"
+// @Param subject_token_type formData string false "urn:ietf:params:oauth:token-type:access_token (token exchange)"
+// @Param actor_token formData string false "Access token of the acting party (token exchange)"
+// @Param actor_token_type formData string false "urn:ietf:params:oauth:token-type:access_token (token exchange)"
+// @Param audience formData string false "Target service of the exchanged token (token exchange)"
+// @Param client_id formData string false "Client ID of public clients"
+// @Param client_assertion_type formData string false "urn:ietf:params:oauth:client-assertion-type:jwt-bearer (private_key_jwt)"
+// @Param client_assertion formData string false "Client assertion JWT (private_key_jwt)"
+// @Param DPoP header string false "DPoP proof binding the issued tokens to its key (RFC 9449)"
+// @Success 200 {object} usecase.AuthResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/token [post]
+func (h *OAuthHandler) Token(c *fiber.Ctx) error {
+	var req usecase.TokenRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, usecase.ErrorInvalidRequest, "invalid request body")
+	}
+	req.Credentials = h.clientCredentials(c)
+	req.Client = clientMetadata(c)
+
+	proof, ok := dpopProof(c)
+	if !ok {
+		return oauthError(c, fiber.StatusBadRequest, usecase.ErrorInvalidDPoPProof, "only one DPoP proof is allowed")
+	}
+	if proof != nil {
+		req.DPoP = proof
+		// Clients learn the nonce for their next proof from every response
+		c.Set(HeaderDPoPNonce, h.oauthUseCase.DPoPNonce())
+	}
+
+	resp, err := h.oauthUseCase.Token(c.Context(), req)
+	if err != nil {
+		var oauthErr *usecase.OAuthError
+		if !errors.As(err, &oauthErr) {
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "failed to issue tokens")
+		}
+		if oauthErr.Code == usecase.ErrorInvalidClient {
+			return invalidClient(c)
+		}
+		return oauthError(c, fiber.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderPragma, "no-cache")
+	return c.JSON(resp)
+}
+
+// PushAuthorizationRequest handles pushed authorization requests (RFC 9126)
+// @Summary Pushed authorization request endpoint
+// @Description Push the parameters of an authorization request and get a request_uri to send the browser to the authorization endpoint with
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param response_type formData string true "code"
+// @Param client_id formData string false "Client ID of public clients"
+// @Param redirect_uri formData string true "Registered redirect URI"
+// @Param scope formData string false "Space-separated scopes"
+// @Param state formData string false "Opaque value returned to the client"
+// @Param nonce formData string false "OpenID Connect nonce"
+// @Param code_challenge formData string true "PKCE code challenge"
+// @Param code_challenge_method formData string true "S256"
+// @Success 201 {object} usecase.PushedAuthorizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/par [post]
+func (h *OAuthHandler) PushAuthorizationRequest(c *fiber.Ctx) error {
+	var req usecase.AuthorizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, usecase.ErrorInvalidRequest, "invalid request body")
+	}
+
+	resp, err := h.oauthUseCase.PushAuthorizationRequest(c.Context(), req, h.clientCredentials(c))
+	if err != nil {
+		var oauthErr *usecase.OAuthError
+		if !errors.As(err, &oauthErr) {
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "failed to save authorization request")
+		}
+		if oauthErr.Code == usecase.ErrorInvalidClient {
+			return invalidClient(c)
+		}
+		return oauthError(c, fiber.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// DeviceAuthorization handles device authorization requests (RFC 8628)
+// @Summary Device authorization endpoint
+// @Description Start the device flow: get a device code to poll the token endpoint with, and a user code to approve on another device
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param scope formData string false "Space-separated scopes"
+// @Param client_id formData string false "Client ID of public clients"
+// @Success 200 {object} usecase.DeviceAuthorizationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/device_authorization [post]
+func (h *OAuthHandler) DeviceAuthorization(c *fiber.Ctx) error {
+	var req usecase.DeviceAuthorizationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, usecase.ErrorInvalidRequest, "invalid request body")
+	}
+	req.Credentials = h.clientCredentials(c)
+
+	resp, err := h.oauthUseCase.DeviceAuthorization(c.Context(), req)
+	if err != nil {
+		var oauthErr *usecase.OAuthError
+		if !errors.As(err, &oauthErr) {
+			return oauthError(c, fiber.StatusInternalServerError, "server_error", "failed to start device authorization")
+		}
+		if oauthErr.Code == usecase.ErrorInvalidClient {
+			return invalidClient(c)
+		}
+		return oauthError(c, fiber.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(resp)
+}
+
+// DeviceVerification describes a pending device authorization to the signed-in user
+// @Summary Look up device authorization
+// @Description Get the client and scopes of the device authorization with a user code, for the verification page to show before approving
+// @Tags oauth
+// @Security BearerAuth
+// @Produce json
+// @Param user_code query string true "User code shown on the device"
+// @Success 200 {object} usecase.DeviceVerificationResponse
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /oauth/device [get]
+func (h *OAuthHandler) DeviceVerification(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	verification, err := h.oauthUseCase.DeviceVerification(c.Context(), userID, c.Query("user_code"))
+	if err != nil {
+		return deviceError(c, err)
+	}
+
+	return c.JSON(verification)
+}
+
+// DecideDevice approves or denies a device authorization as the signed-in user
+// @Summary Approve or deny device
+// @Description Approve or deny the device authorization with a user code. The device is granted tokens for the user of the session
+// @Tags oauth
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body usecase.DeviceDecisionRequest true "Device decision"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 429 {object} map[string]interface{}
+// @Router /oauth/device [post]
+func (h *OAuthHandler) DecideDevice(c *fiber.Ctx) error {
+	userID, ok := GetUserIDFromContext(c)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "unauthorized",
+		})
+	}
+
+	var req usecase.DeviceDecisionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+	if req.UserCode == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "user_code is required",
+		})
+	}
+	req.UserID = userID
+	req.SessionID, _ = GetSessionIDFromContext(c)
+
+	if err := h.oauthUseCase.DecideDevice(c.Context(), req); err != nil {
+		return deviceError(c, err)
+	}
+
+	message := "device denied"
+	if req.Approve {
+		message = "device approved"
+	}
+	return c.JSON(fiber.Map{
+		"message": message,
+	})
+}
+
+// deviceError responds to a failed device verification or decision
+func deviceError(c *fiber.Ctx, err error) error {
+	switch err {
+	case domain.ErrDeviceCodeNotFound:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "unknown or expired user code",
+		})
+	case domain.ErrTooManyAttempts:
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "too many unknown user codes, try again later",
+		})
+	case domain.ErrSessionNotFound:
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "session ended, sign in again",
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to process the user code",
+		})
+	}
+}
+
+// EndSession handles RP-initiated logout (OpenID Connect RP-Initiated Logout 1.0)
+// @Summary End session endpoint
+// @Description Log the user out of the browser's SSO session and the session of an ID token, notify the clients of the sessions through front-channel and back-channel logout, and redirect to the client. Without id_token_hint, the user confirms the logout first.
+// @Tags oauth
+// @Accept x-www-form-urlencoded
+// @Produce html
+// @Param id_token_hint query string false "ID token issued to the client for the session, may be expired"
+// @Param client_id query string false "Client ID, must match the audience of the ID token. Required for post_logout_redirect_uri without id_token_hint"
+// @Param post_logout_redirect_uri query string false "Registered post-logout redirect URI"
+// @Param state query string false "Opaque value returned to the client"
+// @Param csrf_token formData string false "CSRF token of the confirmation form, required without id_token_hint"
+// @Success 200
+// @Success 302
+// @Failure 400
+// @Router /oauth/logout [get]
+// @Router /oauth/logout [post]
+func (h *OAuthHandler) EndSession(c *fiber.Ctx) error {
+	var req usecase.EndSessionRequest
+	var err error
+	if c.Method() == fiber.MethodPost {
+		err = c.BodyParser(&req)
+	} else {
+		err = c.QueryParser(&req)
+	}
+	if err != nil {
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "Invalid logout request.")
+	}
+	req.SessionToken = c.Cookies(ssoSessionCookie)
+
+	// Without an ID token the request may come from any site, so the user
+	// confirms it on a form of this server
+	if req.IDTokenHint == "" && (c.Method() != fiber.MethodPost || !validCSRFToken(c)) {
+		return renderPage(c, fiber.StatusOK, logoutConfirmPage, logoutConfirmPageData{Request: req, CSRFToken: csrfToken(c)})
+	}
+
+	resp, err := h.oauthUseCase.EndSession(c.Context(), req)
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidIDTokenHint, domain.ErrInvalidClient:
+			return renderPage(c, fiber.StatusBadRequest, errorPage, "The logout request was not sent by a known application.")
+		case domain.ErrInvalidRedirectURI:
+			return renderPage(c, fiber.StatusBadRequest, errorPage, "The post-logout redirect URI is not registered for this client.")
+		default:
+			return renderPage(c, fiber.StatusInternalServerError, errorPage, "Something went wrong, please try again.")
+		}
+	}
+
+	if resp.SessionEnded {
+		clearSSOSessionCookie(c)
+	}
+
+	// Front-channel logout needs a page to load the clients' logout URIs in
+	if len(resp.FrontchannelLogoutURIs) == 0 && resp.RedirectURI != "" {
+		c.Set(fiber.HeaderCacheControl, "no-store")
+		return c.Redirect(resp.RedirectURI, fiber.StatusFound)
+	}
+	return renderLogoutPage(c, resp)
+}
+
+// authorizationError responds to a failed authorization request
+// Errors are redirected to the client only once the redirect URI is trusted
+func authorizationError(c *fiber.Ctx, req usecase.AuthorizationRequest, err error) error {
+	var oauthErr *usecase.OAuthError
+	if errors.As(err, &oauthErr) {
+		params := url.Values{
+			"error":             {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+		}
+		if req.State != "" {
+			params.Set("state", req.State)
+		}
+		return redirectToClient(c, req.RedirectURI, params)
+	}
+
+	switch err {
+	case domain.ErrInvalidClient:
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "Unknown client.")
+	case domain.ErrInvalidRedirectURI:
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "The redirect URI is not registered for this client.")
+	case domain.ErrRequestURINotFound:
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "The authorization request expired, please return to the application and try again.")
+	default:
+		return renderPage(c, fiber.StatusInternalServerError, errorPage, "Something went wrong, please try again.")
+	}
+}
+
+// ssoSessionCookie is the cookie of the browser's SSO session. The __Host-
+// prefix keeps it to the auth domain over HTTPS. SameSite=Lax still sends it
+// on the redirects of clients to the authorization endpoint
+const ssoSessionCookie = "__Host-sso_session"
+
+// setSSOSessionCookie stores a new session token in the browser
+func setSSOSessionCookie(c *fiber.Ctx, session *usecase.SSOSessionResponse) {
+	if session == nil || session.Token == "" {
+		return
+	}
+	c.Cookie(&fiber.Cookie{
+		Name:     ssoSessionCookie,
+		Value:    session.Token,
+		Path:     "/",
+		Expires:  session.ExpiresAt,
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// clearSSOSessionCookie removes the session token from the browser
+func clearSSOSessionCookie(c *fiber.Ctx) {
+	c.Cookie(&fiber.Cookie{
+		Name:     ssoSessionCookie,
+		Path:     "/",
+		Expires:  time.Unix(0, 0),
+		Secure:   true,
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+// redirectToClient redirects to a client's redirect URI with added query parameters
+func redirectToClient(c *fiber.Ctx, redirectURI string, params url.Values) error {
+	target, err := url.Parse(redirectURI)
+	if err != nil {
+		return renderPage(c, fiber.StatusBadRequest, errorPage, "Invalid redirect URI.")
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Redirect(target.String(), fiber.StatusFound)
+}
+
+// clientCredentials extracts client credentials from the Authorization
+// header (client_secret_basic) or the request body (client_secret_post,
+// private_key_jwt), along with the TLS client certificate
+func (h *OAuthHandler) clientCredentials(c *fiber.Ctx) usecase.ClientCredentials {
+	credentials := usecase.ClientCredentials{
+		ClientID:            c.FormValue("client_id"),
+		ClientSecret:        c.FormValue("client_secret"),
+		ClientAssertionType: c.FormValue("client_assertion_type"),
+		ClientAssertion:     c.FormValue("client_assertion"),
+	}
+	credentials.Certificate, credentials.CertificateVerified = clientCertificate(c, h.clientCertHeader, h.clientCAs)
+
+	if authHeader := c.Get(fiber.HeaderAuthorization); strings.HasPrefix(authHeader, "Basic ") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(authHeader, "Basic "))
+		if err == nil {
+			id, secret, _ := strings.Cut(string(decoded), ":")
+			// Credentials are form-encoded before being base64 encoded
+			credentials.ClientID, _ = url.QueryUnescape(id)
+			credentials.ClientSecret, _ = url.QueryUnescape(secret)
+		}
+	}
+
+	return credentials
+}
+
+// clientCertificate returns the client certificate of the TLS connection,
+// or the one forwarded by a TLS-terminating proxy in header, and whether its
+// chain was verified. Forwarded certificates are verified against clientCAs,
+// since the proxy may accept any certificate. The header is ignored on TLS
+// connections, which come from clients directly rather than from the proxy
+func clientCertificate(c *fiber.Ctx, header string, clientCAs *x509.CertPool) (*x509.Certificate, bool) {
+	if c.Context().IsTLS() {
+		if state := c.Context().TLSConnectionState(); state != nil && len(state.PeerCertificates) > 0 {
+			return state.PeerCertificates[0], len(state.VerifiedChains) > 0
+		}
+		return nil, false
+	}
+
+	if header == "" {
+		return nil, false
+	}
+	value := c.Get(header)
+	if value == "" {
+		return nil, false
+	}
+
+	// PathUnescape keeps "+" of the base64 encoded certificate
+	decoded, err := url.PathUnescape(value)
+	if err != nil {
+		return nil, false
+	}
+	block, _ := pem.Decode([]byte(decoded))
+	if block == nil {
+		return nil, false
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, false
+	}
+	return cert, verifyClientCertificate(cert, clientCAs)
+}
+
+// verifyClientCertificate checks that a client certificate was issued by
+// one of the client CAs
+func verifyClientCertificate(cert *x509.Certificate, clientCAs *x509.CertPool) bool {
+	if clientCAs == nil {
+		return false
+	}
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:     clientCAs,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err == nil
+}
+
+// invalidClient responds to a failed client authentication
+func invalidClient(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="auth-service"`)
+	return oauthError(c, fiber.StatusUnauthorized, "invalid_client", "client authentication failed")
+}
+
+// oauthError responds with an OAuth 2.0 error response
+func oauthError(c *fiber.Ctx, status int, code, description string) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(status).JSON(fiber.Map{
+		"error":             code,
+		"error_description": description,
+	})
+}
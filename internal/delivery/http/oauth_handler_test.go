@@ -0,0 +1,203 @@
+package http
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/usecase"
+	"context"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const testCSRFToken = "csrf-token"
+
+// fakeOAuthUseCase accepts any authorization request of a browser without
+// an SSO session, and records sign-ins and logouts
+type fakeOAuthUseCase struct {
+	usecase.OAuthUseCase
+	signedIn     []string
+	endSessions  []usecase.EndSessionRequest
+	sessionEnded bool
+}
+
+func (uc *fakeOAuthUseCase) ResolveAuthorizationRequest(ctx context.Context, req usecase.AuthorizationRequest) (usecase.AuthorizationRequest, error) {
+	return req, nil
+}
+
+func (uc *fakeOAuthUseCase) AuthorizeWithSession(ctx context.Context, req usecase.AuthorizationRequest) (*usecase.AuthorizationResponse, error) {
+	return nil, domain.ErrLoginRequired
+}
+
+func (uc *fakeOAuthUseCase) Authorize(ctx context.Context, req usecase.AuthorizationRequest, email, password string) (*usecase.AuthorizationResponse, error) {
+	uc.signedIn = append(uc.signedIn, email)
+	return &usecase.AuthorizationResponse{Code: "code", RedirectURI: "https://app.example.com/callback"}, nil
+}
+
+func (uc *fakeOAuthUseCase) EndSession(ctx context.Context, req usecase.EndSessionRequest) (*usecase.EndSessionResponse, error) {
+	uc.endSessions = append(uc.endSessions, req)
+	return &usecase.EndSessionResponse{SessionEnded: uc.sessionEnded}, nil
+}
+
+func newOAuthHandlerTestApp() (*fiber.App, *fakeOAuthUseCase) {
+	uc := &fakeOAuthUseCase{}
+	handler := NewOAuthHandler(uc, "", nil, false)
+	app := fiber.New()
+	app.Get("/oauth/authorize", handler.Authorize)
+	app.Post("/oauth/authorize", handler.AuthorizeSubmit)
+	app.Get("/oauth/logout", handler.EndSession)
+	app.Post("/oauth/logout", handler.EndSession)
+	return app, uc
+}
+
+func postForm(t *testing.T, app *fiber.App, path string, form url.Values, cookie string) (int, string) {
+	t.Helper()
+
+	req := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if cookie != "" {
+		req.Header.Set("Cookie", csrfCookie+"="+cookie)
+	}
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestAuthorizeSubmitRequiresCSRFToken(t *testing.T) {
+	app, _ := newOAuthHandlerTestApp()
+
+	// The sign-in form carries the token of the cookie set before sign-in
+	resp, err := app.Test(httptest.NewRequest("GET", "/oauth/authorize?client_id=web-app", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookie {
+		t.Fatalf("expected a CSRF cookie with the sign-in form, got %v", cookies)
+	}
+	if !strings.Contains(string(body), `name="csrf_token" value="`+cookies[0].Value+`"`) {
+		t.Error("the sign-in form doesn't carry the CSRF token")
+	}
+
+	tests := []struct {
+		name       string
+		cookie     string
+		field      string
+		wantStatus int
+	}{
+		{"matching token", testCSRFToken, testCSRFToken, fiber.StatusFound},
+		{"missing token", testCSRFToken, "", fiber.StatusForbidden},
+		{"cross-site post without cookie", "", testCSRFToken, fiber.StatusForbidden},
+		{"other token", testCSRFToken, "other", fiber.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, uc := newOAuthHandlerTestApp()
+			form := url.Values{
+				"client_id": {"web-app"},
+				"email":     {"user@example.com"},
+				"password":  {"password"},
+				csrfField:   {tt.field},
+			}
+
+			status, _ := postForm(t, app, "/oauth/authorize", form, tt.cookie)
+			if status != tt.wantStatus {
+				t.Errorf("got status %d, want %d", status, tt.wantStatus)
+			}
+			if signedIn := len(uc.signedIn) > 0; signedIn != (tt.wantStatus == fiber.StatusFound) {
+				t.Errorf("signed in = %v", signedIn)
+			}
+		})
+	}
+}
+
+func TestEndSessionConfirmation(t *testing.T) {
+	t.Run("with id_token_hint", func(t *testing.T) {
+		app, uc := newOAuthHandlerTestApp()
+		resp, err := app.Test(httptest.NewRequest("GET", "/oauth/logout?id_token_hint=id-token", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK || len(uc.endSessions) != 1 {
+			t.Errorf("expected the session to end right away, got status %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("bare GET", func(t *testing.T) {
+		app, uc := newOAuthHandlerTestApp()
+		resp, err := app.Test(httptest.NewRequest("GET", "/oauth/logout?client_id=web-app&state=xyz", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if len(uc.endSessions) != 0 {
+			t.Fatal("the session ended without confirmation")
+		}
+		cookies := resp.Cookies()
+		if len(cookies) != 1 || !strings.Contains(string(body), `name="csrf_token" value="`+cookies[0].Value+`"`) {
+			t.Error("the confirmation form doesn't carry the CSRF token")
+		}
+		if !strings.Contains(string(body), `name="state" value="xyz"`) {
+			t.Error("the confirmation form doesn't carry the request")
+		}
+	})
+
+	tests := []struct {
+		name      string
+		cookie    string
+		field     string
+		wantEnded bool
+	}{
+		{"confirmed", testCSRFToken, testCSRFToken, true},
+		{"cross-site post", "", testCSRFToken, false},
+		{"post without token", testCSRFToken, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, uc := newOAuthHandlerTestApp()
+			form := url.Values{"client_id": {"web-app"}, csrfField: {tt.field}}
+
+			_, body := postForm(t, app, "/oauth/logout", form, tt.cookie)
+			if ended := len(uc.endSessions) > 0; ended != tt.wantEnded {
+				t.Errorf("session ended = %v, want %v", ended, tt.wantEnded)
+			}
+			if !tt.wantEnded && !strings.Contains(body, `action="/oauth/logout"`) {
+				t.Error("expected the confirmation form")
+			}
+		})
+	}
+}
+
+func TestEndSessionClearsSessionCookie(t *testing.T) {
+	for _, sessionEnded := range []bool{true, false} {
+		app, uc := newOAuthHandlerTestApp()
+		uc.sessionEnded = sessionEnded
+
+		req := httptest.NewRequest("GET", "/oauth/logout?id_token_hint=id-token", nil)
+		req.Header.Set("Cookie", ssoSessionCookie+"=session-token")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cleared := false
+		for _, cookie := range resp.Cookies() {
+			if cookie.Name == ssoSessionCookie && cookie.Value == "" {
+				cleared = true
+			}
+		}
+		if cleared != sessionEnded {
+			t.Errorf("session ended = %v: cookie cleared = %v", sessionEnded, cleared)
+		}
+		if uc.endSessions[0].SessionToken != "session-token" {
+			t.Errorf("expected the session cookie to be passed on, got %q", uc.endSessions[0].SessionToken)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package http
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/usecase"
+	"errors"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RegistrationHandler handles dynamic client registration (RFC 7591) and
+// client configuration (RFC 7592) HTTP requests
+type RegistrationHandler struct {
+	clientUseCase usecase.ClientUseCase
+}
+
+// NewRegistrationHandler creates a new registration handler
+func NewRegistrationHandler(clientUseCase usecase.ClientUseCase) *RegistrationHandler {
+	return &RegistrationHandler{
+		clientUseCase: clientUseCase,
+	}
+}
+
+// Register handles dynamic client registration
+// @Summary Register client
+// @Description Register an OAuth client with an initial access token (RFC 7591). The client secret and registration access token are only returned once
+// @Tags oauth
+// @Security InitialAccessToken
+// @Accept json
+// @Produce json
+// @Param request body usecase.RegistrationRequest true "Client metadata"
+// @Success 201 {object} usecase.RegistrationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/register [post]
+func (h *RegistrationHandler) Register(c *fiber.Ctx) error {
+	var req usecase.RegistrationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_client_metadata", "invalid request body")
+	}
+
+	resp, err := h.clientUseCase.RegisterClient(c.Context(), req)
+	if err != nil {
+		return registrationError(c, err, "failed to register client")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// Get handles reading the configuration of a dynamically registered client
+// @Summary Get client configuration
+// @Description Get the metadata of a dynamically registered client (RFC 7592)
+// @Tags oauth
+// @Security RegistrationAccessToken
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Success 200 {object} usecase.RegistrationResponse
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/register/{client_id} [get]
+func (h *RegistrationHandler) Get(c *fiber.Ctx) error {
+	resp, err := h.clientUseCase.GetRegistration(c.Context(), c.Params("client_id"), bearerToken(c))
+	if err != nil {
+		return registrationError(c, err, "failed to get client")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(resp)
+}
+
+// Update handles replacing the metadata of a dynamically registered client
+// @Summary Update client configuration
+// @Description Replace the metadata of a dynamically registered client (RFC 7592); omitted fields are removed
+// @Tags oauth
+// @Security RegistrationAccessToken
+// @Accept json
+// @Produce json
+// @Param client_id path string true "Client ID"
+// @Param request body usecase.RegistrationRequest true "Client metadata, including client_id"
+// @Success 200 {object} usecase.RegistrationResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/register/{client_id} [put]
+func (h *RegistrationHandler) Update(c *fiber.Ctx) error {
+	var req usecase.RegistrationRequest
+	if err := c.BodyParser(&req); err != nil {
+		return oauthError(c, fiber.StatusBadRequest, "invalid_client_metadata", "invalid request body")
+	}
+
+	resp, err := h.clientUseCase.UpdateRegistration(c.Context(), c.Params("client_id"), bearerToken(c), req)
+	if err != nil {
+		return registrationError(c, err, "failed to update client")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.JSON(resp)
+}
+
+// Delete handles deregistering a dynamically registered client
+// @Summary Delete client configuration
+// @Description Deregister a dynamically registered client and revoke its refresh tokens (RFC 7592)
+// @Tags oauth
+// @Security RegistrationAccessToken
+// @Param client_id path string true "Client ID"
+// @Success 204
+// @Failure 401 {object} map[string]interface{}
+// @Router /oauth/register/{client_id} [delete]
+func (h *RegistrationHandler) Delete(c *fiber.Ctx) error {
+	if err := h.clientUseCase.DeleteRegistration(c.Context(), c.Params("client_id"), bearerToken(c)); err != nil {
+		return registrationError(c, err, "failed to delete client")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// registrationError maps registration errors to RFC 7591 section 3.2.2 responses
+func registrationError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case err == domain.ErrInvalidToken:
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		return oauthError(c, fiber.StatusUnauthorized, "invalid_token", "invalid registration access token")
+	case errors.Is(err, domain.ErrInvalidRedirectURI):
+		return oauthError(c, fiber.StatusBadRequest, "invalid_redirect_uri", err.Error())
+	case errors.Is(err, domain.ErrInvalidClientMetadata):
+		return oauthError(c, fiber.StatusBadRequest, "invalid_client_metadata", err.Error())
+	default:
+		return oauthError(c, fiber.StatusInternalServerError, "server_error", message)
+	}
+}
+
+// bearerToken returns the bearer token of the Authorization header, if any
+func bearerToken(c *fiber.Ctx) string {
+	token, _ := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+	return token
+}
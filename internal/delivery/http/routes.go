@@ -1,6 +1,9 @@
 package http
 
 import (
+	"auth-service/internal/usecase"
+	"auth-service/pkg/jwt"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
@@ -13,13 +16,21 @@ func SetupRoutes(app *fiber.App, container *Container) {
 	app.Use(recover.New())
 	app.Use(logger.New())
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		// Only origins registered by a client may call the API from a browser
+		AllowOriginsFunc: container.ClientUseCase.IsOriginAllowed,
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, " + HeaderDPoP,
+		AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+		ExposeHeaders:    HeaderDPoPNonce + ", " + fiber.HeaderWWWAuthenticate,
 	}))
 
 	// Initialize handlers
 	authHandler := NewAuthHandler(container.AuthUseCase, container.JWTManager)
+	oauthHandler := NewOAuthHandler(container.OAuthUseCase, container.ClientCertHeader, container.ClientCAs, container.RegistrationInitialAccessToken != "")
+	clientHandler := NewClientHandler(container.ClientUseCase)
+	registrationHandler := NewRegistrationHandler(container.ClientUseCase)
+
+	// Only tokens intended for this service's own API are accepted
+	requireToken := AuthMiddleware(container.AuthUseCase, container.ClientCertHeader, jwt.WithAudience(container.JWTManager.GetAudience()))
 
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -32,6 +43,11 @@ func SetupRoutes(app *fiber.App, container *Container) {
 	// JWKS endpoint (public key for token verification)
 	app.Get("/.well-known/jwks.json", authHandler.GetJWKS)
 
+	// OpenID Connect discovery and userinfo
+	app.Get("/.well-known/openid-configuration", oauthHandler.Discovery)
+	app.Get("/userinfo", requireToken, RequireUser(), oauthHandler.UserInfo)
+	app.Post("/userinfo", requireToken, RequireUser(), oauthHandler.UserInfo)
+
 	// Auth routes
 	auth := app.Group("/auth")
 	{
@@ -41,9 +57,54 @@ func SetupRoutes(app *fiber.App, container *Container) {
 		auth.Post("/refresh", authHandler.RefreshToken)
 		auth.Post("/logout", authHandler.Logout)
 
-		// Protected routes (require authentication)
-		protected := auth.Group("", AuthMiddleware(container.AuthUseCase))
-		protected.Get("/profile", authHandler.GetProfile)
-		protected.Post("/logout-all", authHandler.LogoutAll)
+		// Protected routes (require a user's access token). Tokens of OAuth
+		// clients need the profile or account scope
+		protected := auth.Group("", requireToken, RequireUser())
+		requireAccount := RequireScope(usecase.ScopeAccount)
+		protected.Get("/profile", RequireScope(usecase.ScopeProfile), authHandler.GetProfile)
+		protected.Post("/logout-all", requireAccount, authHandler.LogoutAll)
+		protected.Post("/revoke-access-token", requireAccount, authHandler.RevokeAccessToken)
+		protected.Get("/sessions", requireAccount, authHandler.ListSessions)
+		protected.Delete("/sessions/:id", requireAccount, authHandler.RevokeSession)
+		protected.Get("/consents", requireAccount, authHandler.ListConsents)
+		protected.Delete("/consents/:client_id", requireAccount, authHandler.RevokeConsent)
+		protected.Put("/password", requireAccount, authHandler.ChangePassword)
+		protected.Delete("/account", requireAccount, authHandler.DeleteAccount)
+	}
+
+	// OAuth 2.0 protocol routes (form-encoded, client authentication)
+	oauth := app.Group("/oauth")
+	{
+		oauth.Get("/authorize", oauthHandler.Authorize)
+		oauth.Post("/authorize", oauthHandler.AuthorizeSubmit)
+		oauth.Post("/authorize/consent", oauthHandler.AuthorizeConsent)
+		oauth.Post("/par", oauthHandler.PushAuthorizationRequest)
+		oauth.Post("/token", oauthHandler.Token)
+		oauth.Post("/device_authorization", oauthHandler.DeviceAuthorization)
+		// Device verification by a signed-in user (JSON, user access token)
+		oauth.Get("/device", AuthMiddleware(container.AuthUseCase, container.ClientCertHeader), RequireUser(), oauthHandler.DeviceVerification)
+		oauth.Post("/device", AuthMiddleware(container.AuthUseCase, container.ClientCertHeader), RequireUser(), oauthHandler.DecideDevice)
+		oauth.Post("/introspect", oauthHandler.Introspect)
+		oauth.Post("/revoke", oauthHandler.Revoke)
+		oauth.Get("/logout", oauthHandler.EndSession)
+		oauth.Post("/logout", oauthHandler.EndSession)
+
+		// Dynamic client registration (JSON, RFC 7591 and RFC 7592)
+		oauth.Post("/register", RegistrationMiddleware(container.RegistrationInitialAccessToken), registrationHandler.Register)
+		oauth.Get("/register/:client_id", registrationHandler.Get)
+		oauth.Put("/register/:client_id", registrationHandler.Update)
+		oauth.Delete("/register/:client_id", registrationHandler.Delete)
+	}
+
+	// Admin routes (require the admin API key)
+	admin := app.Group("/admin", AdminMiddleware(container.AdminAPIKey))
+	{
+		admin.Post("/clients", clientHandler.Create)
+		admin.Get("/clients", clientHandler.List)
+		admin.Get("/clients/:id", clientHandler.Get)
+		admin.Put("/clients/:id", clientHandler.Update)
+		admin.Delete("/clients/:id", clientHandler.Delete)
+		admin.Post("/users/:id/suspend", authHandler.SuspendUser)
+		admin.Get("/clients/:id/logout-deliveries", clientHandler.ListLogoutDeliveries)
 	}
 }
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"auth-service/internal/usecase"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// loginPage is the sign-in form of the authorization endpoint
+// The authorization request is carried through hidden fields, or by its
+// request_uri if it was pushed
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Sign in</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #f5f5f5; display: flex; justify-content: center; padding-top: 10vh; }
+    form { background: #fff; padding: 2rem; border-radius: 8px; width: 320px; box-shadow: 0 1px 4px rgba(0, 0, 0, .1); }
+    label { display: block; margin-top: 1rem; font-size: .9rem; }
+    input[type=email], input[type=password] { width: 100%; padding: .5rem; margin-top: .25rem; box-sizing: border-box; }
+    button { margin-top: 1.5rem; width: 100%; padding: .6rem; }
+    .error { color: #b00020; font-size: .9rem; }
+  </style>
+</head>
+<body>
+  <form method="post" action="/oauth/authorize">
+    <h1>Sign in</h1>
+    <p>to continue to <strong>{{.Request.ClientID}}</strong></p>
+    {{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+    {{if .Request.RequestURI}}
+    <input type="hidden" name="request_uri" value="{{.Request.RequestURI}}">
+    {{else}}
+    <input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+    <input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+    <input type="hidden" name="scope" value="{{.Request.Scope}}">
+    <input type="hidden" name="state" value="{{.Request.State}}">
+    <input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+    <input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+    <input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+    {{end}}
+    <label>Email <input type="email" name="email" value="{{.Email}}" required autofocus></label>
+    <label>Password <input type="password" name="password" required></label>
+    <button type="submit">Sign in</button>
+  </form>
+</body>
+</html>
+`))
+
+// consentPage asks the user to grant a third-party client the requested scopes
+var consentPage = template.Must(template.New("consent").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Authorize access</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #f5f5f5; display: flex; justify-content: center; padding-top: 10vh; }
+    form { background: #fff; padding: 2rem; border-radius: 8px; width: 320px; box-shadow: 0 1px 4px rgba(0, 0, 0, .1); }
+    li { margin-top: .5rem; }
+    button { margin-top: 1.5rem; width: 100%; padding: .6rem; }
+  </style>
+</head>
+<body>
+  <form method="post" action="/oauth/authorize/consent">
+    <h1>Authorize access</h1>
+    <p><strong>{{.Prompt.ClientName}}</strong> would like to:</p>
+    <ul>
+      {{range .Prompt.Scopes}}<li>{{.Description}}</li>{{else}}<li>Access your account</li>{{end}}
+    </ul>
+    <input type="hidden" name="consent_challenge" value="{{.Challenge}}">
+    <button type="submit" name="action" value="approve">Allow</button>
+    <button type="submit" name="action" value="deny">Deny</button>
+  </form>
+</body>
+</html>
+`))
+
+// logoutConfirmPage asks the user to confirm a logout request that doesn't
+// prove it was sent by a client of the session, which any site could send
+var logoutConfirmPage = template.Must(template.New("logout_confirm").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Sign out</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #f5f5f5; display: flex; justify-content: center; padding-top: 10vh; }
+    form { background: #fff; padding: 2rem; border-radius: 8px; width: 320px; box-shadow: 0 1px 4px rgba(0, 0, 0, .1); }
+    button { margin-top: 1.5rem; width: 100%; padding: .6rem; }
+  </style>
+</head>
+<body>
+  <form method="post" action="/oauth/logout">
+    <h1>Sign out</h1>
+    <p>Do you want to sign out{{if .Request.ClientID}} of <strong>{{.Request.ClientID}}</strong> and your other applications{{end}}?</p>
+    <input type="hidden" name="csrf_token" value="{{.CSRFToken}}">
+    <input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+    <input type="hidden" name="post_logout_redirect_uri" value="{{.Request.PostLogoutRedirectURI}}">
+    <input type="hidden" name="state" value="{{.Request.State}}">
+    <button type="submit">Sign out</button>
+  </form>
+</body>
+</html>
+`))
+
+// logoutPage confirms that the user signed out. It loads the front-channel
+// logout URIs of the clients of the ended session in hidden frames, then
+// returns to the client if it asked to
+var logoutPage = template.Must(template.New("logout").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  {{if .RedirectURI}}<meta http-equiv="refresh" content="2;url={{.RedirectURI}}">{{end}}
+  <title>Signed out</title>
+  <style>
+    body { font-family: system-ui, sans-serif; background: #f5f5f5; display: flex; justify-content: center; padding-top: 10vh; }
+    section { background: #fff; padding: 2rem; border-radius: 8px; width: 320px; box-shadow: 0 1px 4px rgba(0, 0, 0, .1); }
+    iframe { display: none; }
+  </style>
+</head>
+<body>
+  <section>
+    <h1>Signed out</h1>
+    {{if .RedirectURI}}
+    <p>Returning to the application&hellip; <a href="{{.RedirectURI}}">Continue</a></p>
+    {{else}}
+    <p>You can close this window.</p>
+    {{end}}
+  </section>
+  {{range .FrontchannelLogoutURIs}}<iframe src="{{.}}" title="Sign out"></iframe>{{end}}
+</body>
+</html>
+`))
+
+// errorPage is shown for errors that can't be returned to the client
+var errorPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Authorization error</title>
+</head>
+<body>
+  <h1>Authorization error</h1>
+  <p>{{.}}</p>
+</body>
+</html>
+`))
+
+// loginPageData holds the data rendered by loginPage
+type loginPageData struct {
+	Request   usecase.AuthorizationRequest
+	Email     string
+	CSRFToken string
+	Error     string
+}
+
+// consentPageData holds the data rendered by consentPage
+type consentPageData struct {
+	Challenge string
+	Prompt    *usecase.ConsentPrompt
+}
+
+// logoutConfirmPageData holds the data rendered by logoutConfirmPage
+type logoutConfirmPageData struct {
+	Request   usecase.EndSessionRequest
+	CSRFToken string
+}
+
+// renderLogoutPage renders logoutPage, which may frame the front-channel
+// logout URIs of clients but nothing else
+func renderLogoutPage(c *fiber.Ctx, resp *usecase.EndSessionResponse) error {
+	frameSources := make([]string, 0, len(resp.FrontchannelLogoutURIs))
+	for _, logoutURI := range resp.FrontchannelLogoutURIs {
+		if u, err := url.Parse(logoutURI); err == nil {
+			frameSources = append(frameSources, u.Scheme+"://"+u.Host)
+		}
+	}
+
+	err := renderPage(c, fiber.StatusOK, logoutPage, resp)
+	if len(frameSources) > 0 {
+		// Headers are only sent once the handler returns
+		c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'; frame-src "+
+			strings.Join(frameSources, " ")+"; frame-ancestors 'none'")
+	}
+	return err
+}
+
+// renderPage renders an HTML page that must not be cached or framed
+// form-action is left open, since the login form redirects to client redirect URIs
+func renderPage(c *fiber.Ctx, status int, page *template.Template, data interface{}) error {
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set(fiber.HeaderXFrameOptions, "DENY")
+	c.Set(fiber.HeaderContentSecurityPolicy, "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Type("html", "utf-8")
+	c.Status(status)
+	return page.Execute(c.Response().BodyWriter(), data)
+}
@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"time"
+)
+
+// AuthorizationCode represents an OAuth 2.0 authorization code
+// Only a hash of the code is stored. Codes are short-lived, single-use and
+// bound to the client, redirect URI and PKCE challenge of the request
+type AuthorizationCode struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	CodeHash      string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ClientID      string     `gorm:"not null;size:255" json:"client_id"`
+	UserID        string     `gorm:"not null;index;size:16" json:"user_id"`
+	RedirectURI   string     `gorm:"not null;type:text" json:"redirect_uri"`
+	Scope         string     `gorm:"type:text" json:"scope"`
+	Nonce         string     `gorm:"type:text" json:"-"`
+	State         string     `gorm:"type:text" json:"-"` // returned to the client once the user decided on consent
+	CodeChallenge string     `gorm:"not null;size:128" json:"-"`
+	ExpiresAt     time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	// ConsentPending is set while the user is asked for consent; the code
+	// can't be exchanged until the user approved
+	ConsentPending bool      `gorm:"not null;default:false" json:"consent_pending"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Session metadata of the browser that authenticated
+	AuthenticatedAt time.Time `gorm:"not null" json:"authenticated_at"`
+	IPAddress       string    `gorm:"size:64" json:"ip_address"`
+	UserAgent       string    `gorm:"type:text" json:"user_agent"`
+	SSOSessionID    string    `gorm:"column:sso_session_id;size:16" json:"-"`
+
+	// Tokens issued for the code, revoked if the code is used again
+	// (RFC 6749 section 4.1.2)
+	SessionID            string     `gorm:"size:16" json:"-"`
+	AccessTokenID        string     `gorm:"size:64" json:"-"`
+	AccessTokenExpiresAt *time.Time `json:"-"`
+	ReusedAt             *time.Time `json:"-"`
+}
+
+// TableName specifies the table name for AuthorizationCode
+func (AuthorizationCode) TableName() string {
+	return "authorization_codes"
+}
+
+// IsExpired checks if the authorization code has expired
+func (ac *AuthorizationCode) IsExpired() bool {
+	return time.Now().After(ac.ExpiresAt)
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Client authentication methods at the token endpoint
+const (
+	ClientAuthNone          = "none"
+	ClientAuthSecretBasic   = "client_secret_basic"
+	ClientAuthSecretPost    = "client_secret_post"
+	ClientAuthPrivateKeyJWT = "private_key_jwt"             // RFC 7523
+	ClientAuthTLS           = "tls_client_auth"             // RFC 8705, PKI certificate
+	ClientAuthSelfSignedTLS = "self_signed_tls_client_auth" // RFC 8705, certificate key in JWKS
+)
+
+// FirstPartySubjectClient stands for the service's own sign-in (/auth/login)
+// in TokenExchangeSubjectClients, whose tokens are issued to no client
+const FirstPartySubjectClient = "first_party"
+
+// Client represents a registered OAuth 2.0 client
+// Public clients (browser and mobile apps) have no secret and rely on PKCE.
+// Token lifetimes of zero fall back to the service defaults
+type Client struct {
+	ID                                 string    `gorm:"primaryKey;size:255" json:"client_id"`
+	SecretHash                         string    `gorm:"size:60" json:"-"`
+	RegistrationAccessTokenHash        string    `gorm:"size:64" json:"-"` // set for dynamically registered clients (RFC 7592)
+	TokenEndpointAuthMethod            string    `gorm:"size:64" json:"token_endpoint_auth_method"`
+	JWKS                               string    `gorm:"type:text" json:"jwks,omitempty"`                       // public keys for private_key_jwt and self_signed_tls_client_auth
+	JWKSURI                            string    `gorm:"type:text" json:"jwks_uri,omitempty"`                   // URL of the JWKS, instead of inline keys
+	TLSClientAuthSubjectDN             string    `gorm:"type:text" json:"tls_client_auth_subject_dn,omitempty"` // certificate subject for tls_client_auth
+	Name                               string    `gorm:"not null" json:"name"`
+	FirstParty                         bool      `gorm:"not null;default:false" json:"first_party"` // trusted apps of the service itself, which skip user consent
+	RedirectURIs                       []string  `gorm:"type:text;serializer:json" json:"redirect_uris"`
+	GrantTypes                         []string  `gorm:"type:text;serializer:json" json:"grant_types"`
+	Scopes                             []string  `gorm:"type:text;serializer:json" json:"scopes"`
+	AllowedOrigins                     []string  `gorm:"type:text;serializer:json" json:"allowed_origins"`
+	Resources                          []string  `gorm:"type:text;serializer:json" json:"resources"`                          // resource servers the client may request tokens for (RFC 8707)
+	TokenExchangeAudiences             []string  `gorm:"type:text;serializer:json" json:"token_exchange_audiences"`           // audiences the client may exchange tokens for (RFC 8693)
+	TokenExchangeSubjectClients        []string  `gorm:"type:text;serializer:json" json:"token_exchange_subject_clients"`     // other clients whose tokens the client may exchange
+	TokenExchangeSubjectAudiences      []string  `gorm:"type:text;serializer:json" json:"token_exchange_subject_audiences"`   // audiences besides its ID the client receives tokens for
+	RequirePushedAuthorizationRequests bool      `gorm:"not null;default:false" json:"require_pushed_authorization_requests"` // authorization requests must be pushed (RFC 9126)
+	AccessTokenLifetime                int       `gorm:"not null;default:0" json:"access_token_lifetime"`                     // in seconds
+	RefreshTokenLifetime               int       `gorm:"not null;default:0" json:"refresh_token_lifetime"`                    // in seconds
+	CreatedAt                          time.Time `json:"created_at"`
+	UpdatedAt                          time.Time `json:"updated_at"`
+
+	// Logout of the client's sessions (OpenID Connect RP-Initiated,
+	// Back-Channel and Front-Channel Logout)
+	PostLogoutRedirectURIs []string `gorm:"type:text;serializer:json" json:"post_logout_redirect_uris"`
+	BackchannelLogoutURI   string   `gorm:"type:text" json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI  string   `gorm:"type:text" json:"frontchannel_logout_uri,omitempty"`
+}
+
+// TableName specifies the table name for Client
+func (Client) TableName() string {
+	return "clients"
+}
+
+// AuthMethod returns the client's token endpoint authentication method
+// Clients registered before the method was recorded use their secret, if any
+func (c *Client) AuthMethod() string {
+	if c.TokenEndpointAuthMethod != "" {
+		return c.TokenEndpointAuthMethod
+	}
+	if c.SecretHash != "" {
+		return ClientAuthSecretBasic
+	}
+	return ClientAuthNone
+}
+
+// IsConfidential checks if the client can authenticate
+func (c *Client) IsConfidential() bool {
+	return c.AuthMethod() != ClientAuthNone
+}
+
+// IsDynamicallyRegistered checks if the client registered itself and can
+// be managed with its registration access token
+func (c *Client) IsDynamicallyRegistered() bool {
+	return c.RegistrationAccessTokenHash != ""
+}
+
+// AllowsGrantType checks if the client may use a grant type
+func (c *Client) AllowsGrantType(grantType string) bool {
+	return slices.Contains(c.GrantTypes, grantType)
+}
+
+// AllowsRedirectURI checks if a redirect URI exactly matches a registered one
+func (c *Client) AllowsRedirectURI(redirectURI string) bool {
+	return slices.Contains(c.RedirectURIs, redirectURI)
+}
+
+// AllowsPostLogoutRedirectURI checks if a post-logout redirect URI exactly matches a registered one
+func (c *Client) AllowsPostLogoutRedirectURI(redirectURI string) bool {
+	return slices.Contains(c.PostLogoutRedirectURIs, redirectURI)
+}
+
+// AllowsScope checks if the client may request a scope
+func (c *Client) AllowsScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// AllowsResource checks if the client may request tokens for a resource server
+func (c *Client) AllowsResource(resource string) bool {
+	return slices.Contains(c.Resources, resource)
+}
+
+// AllowsExchangeAudience checks if the client may exchange tokens for a token audienced to a resource
+func (c *Client) AllowsExchangeAudience(audience string) bool {
+	return slices.Contains(c.TokenExchangeAudiences, audience)
+}
+
+// AllowsExchangeSubjectClient checks if the client may exchange tokens issued to another client
+// Tokens issued to no client, by the service's own sign-in, need the FirstPartySubjectClient entry
+func (c *Client) AllowsExchangeSubjectClient(clientID string) bool {
+	if clientID == "" {
+		clientID = FirstPartySubjectClient
+	}
+	return clientID == c.ID || slices.Contains(c.TokenExchangeSubjectClients, clientID)
+}
+
+// IsExchangeSubjectAudience checks if a token to exchange was intended for the client,
+// which is named in its audience by ID or by an audience it receives tokens for
+func (c *Client) IsExchangeSubjectAudience(audience []string) bool {
+	for _, aud := range audience {
+		if aud == c.ID || slices.Contains(c.TokenExchangeSubjectAudiences, aud) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin checks if browsers may call the service from an origin on behalf of the client
+func (c *Client) AllowsOrigin(origin string) bool {
+	return slices.Contains(c.AllowedOrigins, origin)
+}
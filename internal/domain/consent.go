@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Consent records the scopes a user granted to a third-party client
+// Users are only asked again when the client requests further scopes
+type Consent struct {
+	UserID    string    `gorm:"primaryKey;size:16" json:"user_id"`
+	ClientID  string    `gorm:"primaryKey;size:255" json:"client_id"`
+	Scope     string    `gorm:"type:text" json:"scope"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Consent
+func (Consent) TableName() string {
+	return "consents"
+}
+
+// Covers checks if the consent includes all scopes of a space-separated scope string
+func (c *Consent) Covers(scope string) bool {
+	granted := strings.Fields(c.Scope)
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
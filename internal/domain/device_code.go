@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"time"
+)
+
+// Device code statuses
+const (
+	DeviceCodePending  = "pending"
+	DeviceCodeApproved = "approved"
+	DeviceCodeDenied   = "denied"
+)
+
+// DeviceCode represents a device authorization request (RFC 8628)
+// Only a hash of the device code is stored. The user approves or denies the
+// request on another device by entering the user code, while the device
+// polls the token endpoint
+type DeviceCode struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	DeviceCodeHash  string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	UserCode        string     `gorm:"uniqueIndex;size:8;not null" json:"-"`
+	ClientID        string     `gorm:"not null;size:255" json:"client_id"`
+	Scope           string     `gorm:"type:text" json:"scope"`
+	Status          string     `gorm:"not null;size:16" json:"status"`
+	UserID          string     `gorm:"size:16" json:"user_id,omitempty"` // the user who approved or denied
+	AuthenticatedAt *time.Time `json:"authenticated_at,omitempty"`
+	PollInterval    int        `gorm:"not null" json:"poll_interval"` // minimum polling interval in seconds
+	LastPolledAt    *time.Time `json:"last_polled_at,omitempty"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt          *time.Time `json:"used_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for DeviceCode
+func (DeviceCode) TableName() string {
+	return "device_codes"
+}
+
+// IsExpired checks if the device code has expired
+func (dc *DeviceCode) IsExpired() bool {
+	return time.Now().After(dc.ExpiresAt)
+}
@@ -4,12 +4,37 @@ import "errors"
 
 // Common errors
 var (
-	ErrUserNotFound         = errors.New("user not found")
-	ErrUserAlreadyExists    = errors.New("user already exists")
-	ErrInvalidCredentials   = errors.New("invalid credentials")
-	ErrRefreshTokenNotFound = errors.New("refresh token not found")
-	ErrRefreshTokenExpired  = errors.New("refresh token expired")
-	ErrRefreshTokenRevoked  = errors.New("refresh token revoked")
-	ErrInvalidToken         = errors.New("invalid token")
-	ErrUnauthorized         = errors.New("unauthorized")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrUserAlreadyExists       = errors.New("user already exists")
+	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrUserSuspended           = errors.New("user suspended")
+	ErrRefreshTokenNotFound    = errors.New("refresh token not found")
+	ErrRefreshTokenExpired     = errors.New("refresh token expired")
+	ErrRefreshTokenRevoked     = errors.New("refresh token revoked")
+	ErrRefreshTokenRotated     = errors.New("refresh token already rotated")
+	ErrRefreshTokenReused      = errors.New("refresh token reuse detected")
+	ErrSessionNotFound         = errors.New("session not found")
+	ErrLoginRequired           = errors.New("login required")
+	ErrAuthCodeNotFound        = errors.New("authorization code not found")
+	ErrAuthCodeUsed            = errors.New("authorization code already used")
+	ErrDeviceCodeNotFound      = errors.New("device code not found")
+	ErrDeviceCodeUsed          = errors.New("device code already used")
+	ErrTooManyAttempts         = errors.New("too many attempts")
+	ErrRequestURINotFound      = errors.New("request URI not found")
+	ErrConsentNotFound         = errors.New("consent not found")
+	ErrInvalidToken            = errors.New("invalid token")
+	ErrInvalidIDTokenHint      = errors.New("invalid ID token hint")
+	ErrUnauthorized            = errors.New("unauthorized")
+	ErrClientNotFound          = errors.New("client not found")
+	ErrInvalidClient           = errors.New("invalid client")
+	ErrInvalidClientMetadata   = errors.New("invalid client metadata")
+	ErrInvalidRedirectURI      = errors.New("invalid redirect URI")
+	ErrInvalidScope            = errors.New("invalid scope")
+	ErrInsufficientScope       = errors.New("insufficient scope")
+	ErrClientIDRequired        = errors.New("client_id is required for the openid scope")
+	ErrClientAssertionReplayed = errors.New("client assertion already used")
+	ErrInvalidDPoPProof        = errors.New("invalid DPoP proof")
+	ErrDPoPProofReplayed       = errors.New("DPoP proof already used")
+	ErrDPoPNonceRequired       = errors.New("DPoP proof requires a fresh server nonce")
+	ErrCertificateMismatch     = errors.New("client certificate does not match the token")
 )
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"time"
+)
+
+// Logout delivery statuses
+const (
+	LogoutDeliveryPending   = "pending"
+	LogoutDeliveryDelivered = "delivered"
+	LogoutDeliveryFailed    = "failed" // gave up after the last retry
+)
+
+// LogoutDelivery represents a back-channel logout notification of a client
+// about the end of a user's session. The logout token is generated on each
+// attempt, so that it is fresh when the client receives it
+type LogoutDelivery struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	ClientID      string     `gorm:"not null;index;size:255" json:"client_id"`
+	UserID        string     `gorm:"not null;size:16" json:"user_id"`
+	SessionID     string     `gorm:"not null;size:16" json:"session_id"`
+	Status        string     `gorm:"not null;size:16;default:pending" json:"status"`
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastError     string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for LogoutDelivery
+func (LogoutDelivery) TableName() string {
+	return "logout_deliveries"
+}
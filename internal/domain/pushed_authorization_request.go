@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"time"
+)
+
+// PushedAuthorizationRequest represents an authorization request a client
+// sent directly to the server (RFC 9126), referenced by a request URI in
+// the browser. Only a hash of the request URI is stored
+type PushedAuthorizationRequest struct {
+	ID                  uint       `gorm:"primarykey" json:"id"`
+	RequestURIHash      string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	ClientID            string     `gorm:"not null;size:255" json:"client_id"`
+	ResponseType        string     `gorm:"not null;size:32" json:"response_type"`
+	RedirectURI         string     `gorm:"not null;type:text" json:"redirect_uri"`
+	Scope               string     `gorm:"type:text" json:"scope"`
+	State               string     `gorm:"type:text" json:"-"`
+	Nonce               string     `gorm:"type:text" json:"-"`
+	CodeChallenge       string     `gorm:"not null;size:128" json:"-"`
+	CodeChallengeMethod string     `gorm:"not null;size:16" json:"-"`
+	Prompt              string     `gorm:"size:64" json:"-"`
+	MaxAge              *int       `json:"-"`
+	ExpiresAt           time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt              *time.Time `json:"used_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for PushedAuthorizationRequest
+func (PushedAuthorizationRequest) TableName() string {
+	return "pushed_authorization_requests"
+}
@@ -5,14 +5,41 @@ import (
 )
 
 // RefreshToken represents a refresh token stored in the database
+// Only a keyed hash of the token is stored. Tokens rotated from the same
+// login share a FamilyID, which identifies the session, and each rotated
+// token points to the token it replaced through ParentID
 type RefreshToken struct {
-	ID        uint      `gorm:"primarykey" json:"id"`
-	UserID    string    `gorm:"not null;index;size:16" json:"user_id"`
-	Token     string    `gorm:"uniqueIndex;not null;type:text" json:"token"`
-	ExpiresAt time.Time `gorm:"not null" json:"expires_at"`
-	IsRevoked bool      `gorm:"default:false" json:"is_revoked"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    string     `gorm:"not null;index;size:16" json:"user_id"`
+	ClientID  string     `gorm:"index;size:255" json:"client_id,omitempty"` // empty for first-party apps
+	TokenHash string     `gorm:"uniqueIndex;size:64" json:"-"`
+	FamilyID  string     `gorm:"not null;index;size:16" json:"family_id"`
+	ParentID  *uint      `json:"parent_id,omitempty"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	IsRevoked bool       `gorm:"default:false" json:"is_revoked"`
+	RotatedAt *time.Time `json:"rotated_at,omitempty"`
+	Scope     string     `gorm:"type:text" json:"scope"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+
+	// Session metadata, carried over or refreshed on rotation
+	AuthenticatedAt time.Time `gorm:"not null" json:"authenticated_at"`
+	IPAddress       string    `gorm:"size:64" json:"ip_address"`
+	UserAgent       string    `gorm:"type:text" json:"user_agent"`
+	SSOSessionID    string    `gorm:"column:sso_session_id;index;size:16" json:"-"` // browser session the user signed in with, if any
+
+	// Access token issued along with the token, revoked when the user
+	// revokes the client's access
+	AccessTokenID        string     `gorm:"size:64" json:"-"`
+	AccessTokenExpiresAt *time.Time `json:"-"`
+
+	// Keys the tokens of public clients are bound to (RFC 9449, RFC 8705)
+	DPoPJKT               string `gorm:"column:dpop_jkt;size:64" json:"-"`
+	CertificateThumbprint string `gorm:"column:x5t_s256;size:64" json:"-"`
+
+	// LegacyToken holds the plaintext of tokens issued before hashing at rest,
+	// until BackfillTokenHashes moves them to TokenHash
+	LegacyToken *string `gorm:"column:token;type:text" json:"-"`
 
 	// Relations
 	User User `gorm:"foreignKey:UserID" json:"-"`
@@ -32,3 +59,8 @@ func (rt *RefreshToken) IsExpired() bool {
 func (rt *RefreshToken) IsValid() bool {
 	return !rt.IsExpired() && !rt.IsRevoked
 }
+
+// IsRotated checks if the refresh token was revoked by being exchanged for a new one
+func (rt *RefreshToken) IsRotated() bool {
+	return rt.RotatedAt != nil
+}
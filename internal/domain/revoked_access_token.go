@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"time"
+)
+
+// RevokedAccessToken represents an access token revoked before its expiry,
+// identified by its "jti" claim
+type RevokedAccessToken struct {
+	JTI       string    `gorm:"primaryKey;size:64" json:"jti"`
+	UserID    string    `gorm:"index;size:16" json:"user_id"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for RevokedAccessToken
+func (RevokedAccessToken) TableName() string {
+	return "revoked_access_tokens"
+}
+
+// IsExpired checks if the revoked token has expired and no longer needs to be denied
+func (t *RevokedAccessToken) IsExpired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"time"
+)
+
+// Security event types
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+	SecurityEventAuthCodeReuse     = "authorization_code_reuse"
+	SecurityEventUserCodeMiss      = "device_user_code_miss" // an unknown user code was entered
+)
+
+// SecurityEvent represents a security-relevant event recorded for auditing
+type SecurityEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	UserID    string    `gorm:"index;size:16" json:"user_id"`
+	Type      string    `gorm:"not null;index;size:64" json:"type"`
+	Details   string    `gorm:"type:text" json:"details"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for SecurityEvent
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"time"
+)
+
+// SSOSession represents a user's sign-in in a browser, identified by a
+// session cookie of which only a hash is stored. While the session is
+// active, authorization requests of the browser skip the sign-in form.
+// Refresh tokens issued through the session reference it, so that they are
+// revoked when the user logs out of the session
+type SSOSession struct {
+	ID              string     `gorm:"primaryKey;size:16" json:"id"`
+	TokenHash       string     `gorm:"uniqueIndex;size:64;not null" json:"-"`
+	UserID          string     `gorm:"not null;index;size:16" json:"user_id"`
+	AuthenticatedAt time.Time  `gorm:"not null" json:"authenticated_at"`
+	LastActiveAt    time.Time  `gorm:"not null" json:"last_active_at"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expires_at"` // absolute timeout
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	IPAddress       string     `gorm:"size:64" json:"ip_address"`
+	UserAgent       string     `gorm:"type:text" json:"user_agent"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for SSOSession
+func (SSOSession) TableName() string {
+	return "sso_sessions"
+}
+
+// IsActive checks if the session is neither revoked nor timed out
+func (s *SSOSession) IsActive(idleTimeout time.Duration) bool {
+	now := time.Now()
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt) && now.Before(s.LastActiveAt.Add(idleTimeout))
+}
@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"time"
+)
+
+// UsedClientAssertion records the "jti" of a client assertion (RFC 7523)
+// until the assertion expires, so that it can't be replayed
+type UsedClientAssertion struct {
+	ClientID  string    `gorm:"primaryKey;size:255" json:"client_id"`
+	JTI       string    `gorm:"primaryKey;size:255" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for UsedClientAssertion
+func (UsedClientAssertion) TableName() string {
+	return "used_client_assertions"
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"time"
+)
+
+// UsedDPoPProof records the "jti" of a DPoP proof (RFC 9449 section 11.1)
+// until the proof is too old to be accepted, so that it can't be replayed
+// against any instance
+type UsedDPoPProof struct {
+	JKT       string    `gorm:"primaryKey;size:64" json:"jkt"`
+	JTI       string    `gorm:"primaryKey;size:255" json:"jti"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for UsedDPoPProof
+func (UsedDPoPProof) TableName() string {
+	return "used_dpop_proofs"
+}
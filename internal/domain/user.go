@@ -8,17 +8,26 @@ import (
 )
 
 // User represents a user in the system
+// TokenVersion is embedded in access tokens; bumping it invalidates all
+// access tokens issued to the user
 type User struct {
 	models.BaseModelNanoID
-	Email     string         `gorm:"uniqueIndex;not null" json:"email"`
-	Password  string         `gorm:"not null" json:"-"`
-	Name      string         `gorm:"not null" json:"name"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	Email        string         `gorm:"uniqueIndex;not null" json:"email"`
+	Password     string         `gorm:"not null" json:"-"`
+	Name         string         `gorm:"not null" json:"name"`
+	TokenVersion int            `gorm:"not null;default:0" json:"-"`
+	SuspendedAt  *time.Time     `json:"suspended_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // TableName specifies the table name for User
 func (User) TableName() string {
 	return "users"
 }
+
+// IsSuspended checks if the user has been suspended
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
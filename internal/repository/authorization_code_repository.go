@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type authorizationCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewAuthorizationCodeRepository creates a new authorization code repository
+func NewAuthorizationCodeRepository(db *gorm.DB) AuthorizationCodeRepository {
+	return &authorizationCodeRepository{db: db}
+}
+
+func (r *authorizationCodeRepository) Create(ctx context.Context, code *domain.AuthorizationCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+func (r *authorizationCodeRepository) Consume(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	var code domain.AuthorizationCode
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return domain.ErrAuthCodeNotFound
+			}
+			return err
+		}
+
+		// Compare-and-swap, so only one of several concurrent exchanges succeeds
+		result := tx.Model(&domain.AuthorizationCode{}).
+			Where("id = ? AND used_at IS NULL", code.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrAuthCodeUsed
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+func (r *authorizationCodeRepository) MarkReused(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	var code domain.AuthorizationCode
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// The update waits for a concurrent RecordIssuedTokens, so the code
+		// is read with its tokens, or RecordIssuedTokens sees the flag
+		err := tx.Model(&domain.AuthorizationCode{}).
+			Where("code_hash = ? AND reused_at IS NULL", codeHash).
+			Update("reused_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return domain.ErrAuthCodeNotFound
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+func (r *authorizationCodeRepository) RecordIssuedTokens(ctx context.Context, id uint, sessionID, accessTokenID string, accessTokenExpiresAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&domain.AuthorizationCode{}).
+		Where("id = ? AND reused_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"session_id":              sessionID,
+			"access_token_id":         accessTokenID,
+			"access_token_expires_at": accessTokenExpiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrAuthCodeUsed
+	}
+	return nil
+}
+
+func (r *authorizationCodeRepository) FindPendingConsent(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	var code domain.AuthorizationCode
+	err := r.db.WithContext(ctx).
+		Where("code_hash = ? AND consent_pending AND used_at IS NULL AND expires_at > ?", codeHash, time.Now()).
+		First(&code).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrAuthCodeNotFound
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *authorizationCodeRepository) ApproveConsent(ctx context.Context, id uint, expiresAt time.Time) error {
+	// Compare-and-swap, so a code can't be approved after it was denied
+	result := r.db.WithContext(ctx).Model(&domain.AuthorizationCode{}).
+		Where("id = ? AND consent_pending AND used_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"consent_pending": false,
+			"expires_at":      expiresAt,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrAuthCodeNotFound
+	}
+	return nil
+}
+
+func (r *authorizationCodeRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.AuthorizationCode{}).Error
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type clientRepository struct {
+	db *gorm.DB
+}
+
+// NewClientRepository creates a new OAuth client repository
+func NewClientRepository(db *gorm.DB) ClientRepository {
+	return &clientRepository{db: db}
+}
+
+func (r *clientRepository) Create(ctx context.Context, client *domain.Client) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *clientRepository) FindByID(ctx context.Context, id string) (*domain.Client, error) {
+	var client domain.Client
+	err := r.db.WithContext(ctx).First(&client, "id = ?", id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrClientNotFound
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *clientRepository) List(ctx context.Context) ([]*domain.Client, error) {
+	var clients []*domain.Client
+	err := r.db.WithContext(ctx).Order("created_at").Find(&clients).Error
+	return clients, err
+}
+
+func (r *clientRepository) Update(ctx context.Context, client *domain.Client) error {
+	return r.db.WithContext(ctx).Save(client).Error
+}
+
+func (r *clientRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&domain.Client{}, "id = ?", id).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type consentRepository struct {
+	db *gorm.DB
+}
+
+// NewConsentRepository creates a new consent repository
+func NewConsentRepository(db *gorm.DB) ConsentRepository {
+	return &consentRepository{db: db}
+}
+
+func (r *consentRepository) Find(ctx context.Context, userID, clientID string) (*domain.Consent, error) {
+	var consent domain.Consent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", userID, clientID).
+		First(&consent).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrConsentNotFound
+		}
+		return nil, err
+	}
+	return &consent, nil
+}
+
+func (r *consentRepository) Save(ctx context.Context, consent *domain.Consent) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(consent).Error
+}
+
+func (r *consentRepository) ListByUserID(ctx context.Context, userID string) ([]*domain.Consent, error) {
+	var consents []*domain.Consent
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&consents).Error
+	return consents, err
+}
+
+func (r *consentRepository) Delete(ctx context.Context, userID, clientID string) error {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ?", userID, clientID).
+		Delete(&domain.Consent{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrConsentNotFound
+	}
+	return nil
+}
+
+func (r *consentRepository) DeleteAllByUserID(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Delete(&domain.Consent{}).Error
+}
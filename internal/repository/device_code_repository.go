@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type deviceCodeRepository struct {
+	db *gorm.DB
+}
+
+// NewDeviceCodeRepository creates a new device code repository
+func NewDeviceCodeRepository(db *gorm.DB) DeviceCodeRepository {
+	return &deviceCodeRepository{db: db}
+}
+
+func (r *deviceCodeRepository) Create(ctx context.Context, code *domain.DeviceCode) error {
+	return r.db.WithContext(ctx).Create(code).Error
+}
+
+func (r *deviceCodeRepository) FindByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceCode, error) {
+	var code domain.DeviceCode
+	if err := r.db.WithContext(ctx).Where("device_code_hash = ?", deviceCodeHash).First(&code).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrDeviceCodeNotFound
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *deviceCodeRepository) FindPendingByUserCode(ctx context.Context, userCode string) (*domain.DeviceCode, error) {
+	var code domain.DeviceCode
+	err := r.db.WithContext(ctx).
+		Where("user_code = ? AND status = ? AND expires_at > ?", userCode, domain.DeviceCodePending, time.Now()).
+		First(&code).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrDeviceCodeNotFound
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+func (r *deviceCodeRepository) Decide(ctx context.Context, id uint, status, userID string, authenticatedAt time.Time) error {
+	updates := map[string]interface{}{"status": status, "user_id": userID}
+	if status == domain.DeviceCodeApproved {
+		updates["authenticated_at"] = authenticatedAt
+	}
+
+	// Compare-and-swap, so a request can only be decided once
+	result := r.db.WithContext(ctx).Model(&domain.DeviceCode{}).
+		Where("id = ? AND status = ?", id, domain.DeviceCodePending).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDeviceCodeNotFound
+	}
+	return nil
+}
+
+func (r *deviceCodeRepository) RecordPoll(ctx context.Context, id uint, polledAt time.Time, interval int) error {
+	return r.db.WithContext(ctx).Model(&domain.DeviceCode{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_polled_at": polledAt,
+			"poll_interval":  interval,
+		}).Error
+}
+
+func (r *deviceCodeRepository) Consume(ctx context.Context, id uint) error {
+	// Compare-and-swap, so only one of several concurrent polls gets tokens
+	result := r.db.WithContext(ctx).Model(&domain.DeviceCode{}).
+		Where("id = ? AND status = ? AND used_at IS NULL", id, domain.DeviceCodeApproved).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDeviceCodeUsed
+	}
+	return nil
+}
+
+func (r *deviceCodeRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.DeviceCode{}).Error
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// logoutDeliveryRetention is how long finished deliveries are kept for
+// inspection by administrators
+const logoutDeliveryRetention = 7 * 24 * time.Hour
+
+type logoutDeliveryRepository struct {
+	db *gorm.DB
+}
+
+// NewLogoutDeliveryRepository creates a new logout delivery repository
+func NewLogoutDeliveryRepository(db *gorm.DB) LogoutDeliveryRepository {
+	return &logoutDeliveryRepository{db: db}
+}
+
+func (r *logoutDeliveryRepository) Create(ctx context.Context, delivery *domain.LogoutDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *logoutDeliveryRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.LogoutDelivery, error) {
+	var deliveries []*domain.LogoutDelivery
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Rows claimed by another instance are skipped rather than waited for
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", domain.LogoutDeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return tx.Model(&domain.LogoutDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *logoutDeliveryRepository) Update(ctx context.Context, delivery *domain.LogoutDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+func (r *logoutDeliveryRepository) ListByClientID(ctx context.Context, clientID string, limit int) ([]*domain.LogoutDelivery, error) {
+	var deliveries []*domain.LogoutDelivery
+	err := r.db.WithContext(ctx).
+		Where("client_id = ?", clientID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *logoutDeliveryRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("status <> ? AND updated_at < ?", domain.LogoutDeliveryPending, time.Now().Add(-logoutDeliveryRetention)).
+		Delete(&domain.LogoutDelivery{}).Error
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type pushedAuthorizationRequestRepository struct {
+	db *gorm.DB
+}
+
+// NewPushedAuthorizationRequestRepository creates a new pushed authorization request repository
+func NewPushedAuthorizationRequestRepository(db *gorm.DB) PushedAuthorizationRequestRepository {
+	return &pushedAuthorizationRequestRepository{db: db}
+}
+
+func (r *pushedAuthorizationRequestRepository) Create(ctx context.Context, request *domain.PushedAuthorizationRequest) error {
+	return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *pushedAuthorizationRequestRepository) FindActive(ctx context.Context, requestURIHash string) (*domain.PushedAuthorizationRequest, error) {
+	var request domain.PushedAuthorizationRequest
+	err := r.db.WithContext(ctx).
+		Where("request_uri_hash = ? AND used_at IS NULL AND expires_at > ?", requestURIHash, time.Now()).
+		First(&request).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrRequestURINotFound
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *pushedAuthorizationRequestRepository) Consume(ctx context.Context, id uint) error {
+	// Compare-and-swap, so a request URI completes only one authorization
+	result := r.db.WithContext(ctx).Model(&domain.PushedAuthorizationRequest{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrRequestURINotFound
+	}
+	return nil
+}
+
+func (r *pushedAuthorizationRequestRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.PushedAuthorizationRequest{}).Error
+}
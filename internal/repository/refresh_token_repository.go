@@ -21,9 +21,9 @@ func (r *refreshTokenRepository) Create(ctx context.Context, token *domain.Refre
 	return r.db.WithContext(ctx).Create(token).Error
 }
 
-func (r *refreshTokenRepository) FindByToken(ctx context.Context, token string) (*domain.RefreshToken, error) {
+func (r *refreshTokenRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error) {
 	var refreshToken domain.RefreshToken
-	err := r.db.WithContext(ctx).Where("token = ?", token).First(&refreshToken).Error
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&refreshToken).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, domain.ErrRefreshTokenNotFound
@@ -39,9 +39,36 @@ func (r *refreshTokenRepository) FindByUserID(ctx context.Context, userID string
 	return tokens, err
 }
 
-func (r *refreshTokenRepository) Revoke(ctx context.Context, tokenString string) error {
+func (r *refreshTokenRepository) Revoke(ctx context.Context, tokenHash string) error {
 	return r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
-		Where("token = ?", tokenString).
+		Where("token_hash = ?", tokenHash).
+		Update("is_revoked", true).Error
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, oldTokenID uint, newToken *domain.RefreshToken) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Compare-and-swap: the row lock taken by the UPDATE makes concurrent
+		// rotations of the same token wait, after which they match no rows
+		result := tx.Model(&domain.RefreshToken{}).
+			Where("id = ? AND is_revoked = ?", oldTokenID, false).
+			Updates(map[string]interface{}{
+				"is_revoked": true,
+				"rotated_at": time.Now(),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrRefreshTokenRotated
+		}
+
+		return tx.Create(newToken).Error
+	})
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("family_id = ?", familyID).
 		Update("is_revoked", true).Error
 }
 
@@ -51,8 +78,62 @@ func (r *refreshTokenRepository) RevokeAllByUserID(ctx context.Context, userID s
 		Update("is_revoked", true).Error
 }
 
+func (r *refreshTokenRepository) RevokeAllByClientID(ctx context.Context, clientID string) error {
+	return r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("client_id = ?", clientID).
+		Update("is_revoked", true).Error
+}
+
+func (r *refreshTokenRepository) RevokeAllByUserIDAndClientID(ctx context.Context, userID, clientID string) error {
+	return r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND client_id = ?", userID, clientID).
+		Update("is_revoked", true).Error
+}
+
+func (r *refreshTokenRepository) FindWithUnexpiredAccessTokens(ctx context.Context, userID, clientID string) ([]*domain.RefreshToken, error) {
+	var tokens []*domain.RefreshToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_id = ? AND access_token_expires_at > ?", userID, clientID, time.Now()).
+		Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *refreshTokenRepository) FindBySSOSessionID(ctx context.Context, ssoSessionID string) ([]*domain.RefreshToken, error) {
+	var tokens []*domain.RefreshToken
+	err := r.db.WithContext(ctx).Where("sso_session_id = ? AND is_revoked = ?", ssoSessionID, false).Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *refreshTokenRepository) RevokeAllBySSOSessionID(ctx context.Context, ssoSessionID string) error {
+	return r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("sso_session_id = ?", ssoSessionID).
+		Update("is_revoked", true).Error
+}
+
 func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
 	return r.db.WithContext(ctx).
 		Where("expires_at < ?", time.Now()).
 		Delete(&domain.RefreshToken{}).Error
 }
+
+func (r *refreshTokenRepository) BackfillTokenHashes(ctx context.Context, hash func(token string) string) (int, error) {
+	var tokens []*domain.RefreshToken
+	migrated := 0
+
+	result := r.db.WithContext(ctx).Where("token IS NOT NULL").
+		FindInBatches(&tokens, 100, func(tx *gorm.DB, batch int) error {
+			for _, token := range tokens {
+				err := tx.Model(token).Updates(map[string]interface{}{
+					"token_hash": hash(*token.LegacyToken),
+					"token":      nil,
+				}).Error
+				if err != nil {
+					return err
+				}
+				migrated++
+			}
+			return nil
+		})
+
+	return migrated, result.Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"auth-service/internal/domain"
 	"context"
+	"time"
 )
 
 // UserRepository defines the interface for user data access
@@ -11,15 +12,155 @@ type UserRepository interface {
 	FindByID(ctx context.Context, id string) (*domain.User, error)
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	Update(ctx context.Context, user *domain.User) error
+	IncrementTokenVersion(ctx context.Context, id string) error
 	Delete(ctx context.Context, id string) error
 }
 
 // RefreshTokenRepository defines the interface for refresh token data access
 type RefreshTokenRepository interface {
 	Create(ctx context.Context, token *domain.RefreshToken) error
-	FindByToken(ctx context.Context, tokenString string) (*domain.RefreshToken, error)
+	FindByTokenHash(ctx context.Context, tokenHash string) (*domain.RefreshToken, error)
 	FindByUserID(ctx context.Context, userID string) ([]*domain.RefreshToken, error)
-	Revoke(ctx context.Context, tokenString string) error
+	Revoke(ctx context.Context, tokenHash string) error
+	// Rotate atomically revokes oldTokenID and creates newToken, failing with
+	// domain.ErrRefreshTokenRotated if the old token is no longer unrevoked
+	Rotate(ctx context.Context, oldTokenID uint, newToken *domain.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllByUserID(ctx context.Context, userID string) error
+	RevokeAllByClientID(ctx context.Context, clientID string) error
+	RevokeAllByUserIDAndClientID(ctx context.Context, userID, clientID string) error
+	// FindWithUnexpiredAccessTokens finds the tokens of a user and client,
+	// revoked or not, whose access token has not yet expired
+	FindWithUnexpiredAccessTokens(ctx context.Context, userID, clientID string) ([]*domain.RefreshToken, error)
+	// FindBySSOSessionID finds the unrevoked tokens issued through a browser session
+	FindBySSOSessionID(ctx context.Context, ssoSessionID string) ([]*domain.RefreshToken, error)
+	RevokeAllBySSOSessionID(ctx context.Context, ssoSessionID string) error
+	DeleteExpired(ctx context.Context) error
+	// BackfillTokenHashes replaces plaintext tokens stored before hashing at
+	// rest with their hash and returns the number of migrated rows
+	BackfillTokenHashes(ctx context.Context, hash func(token string) string) (int, error)
+}
+
+// SecurityEventRepository defines the interface for security event data access
+type SecurityEventRepository interface {
+	Create(ctx context.Context, event *domain.SecurityEvent) error
+	// CountSince counts the events of a type recorded for a user since a time
+	CountSince(ctx context.Context, userID, eventType string, since time.Time) (int64, error)
+}
+
+// RevokedAccessTokenRepository defines the interface for revoked access token data access
+type RevokedAccessTokenRepository interface {
+	// Create stores a revocation and notifies all listening instances
+	Create(ctx context.Context, token *domain.RevokedAccessToken) error
+	FindActive(ctx context.Context) ([]*domain.RevokedAccessToken, error)
+	DeleteExpired(ctx context.Context) error
+	// Listen calls ready once subscribed, then handle for every revocation
+	// created by any instance. It blocks until ctx is done or the connection fails.
+	Listen(ctx context.Context, ready func() error, handle func(token *domain.RevokedAccessToken)) error
+}
+
+// ClientRepository defines the interface for OAuth client data access
+type ClientRepository interface {
+	Create(ctx context.Context, client *domain.Client) error
+	FindByID(ctx context.Context, id string) (*domain.Client, error)
+	List(ctx context.Context) ([]*domain.Client, error)
+	Update(ctx context.Context, client *domain.Client) error
+	Delete(ctx context.Context, id string) error
+}
+
+// AuthorizationCodeRepository defines the interface for authorization code data access
+type AuthorizationCodeRepository interface {
+	Create(ctx context.Context, code *domain.AuthorizationCode) error
+	// Consume atomically marks a code as used and returns it, failing with
+	// domain.ErrAuthCodeUsed if it was already used
+	Consume(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error)
+	// MarkReused flags a code that was presented again after being used and
+	// returns it with the tokens recorded for it so far
+	MarkReused(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error)
+	// RecordIssuedTokens stores the tokens issued for a code, to revoke them
+	// if the code is used again. It fails with domain.ErrAuthCodeUsed if the
+	// code was flagged as reused in the meantime
+	RecordIssuedTokens(ctx context.Context, id uint, sessionID, accessTokenID string, accessTokenExpiresAt time.Time) error
+	// FindPendingConsent finds an unused, unexpired code awaiting the user's consent
+	FindPendingConsent(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error)
+	// ApproveConsent makes a code awaiting consent exchangeable until
+	// expiresAt, failing with domain.ErrAuthCodeNotFound if it no longer awaits consent
+	ApproveConsent(ctx context.Context, id uint, expiresAt time.Time) error
+	DeleteExpired(ctx context.Context) error
+}
+
+// DeviceCodeRepository defines the interface for device authorization data access
+type DeviceCodeRepository interface {
+	Create(ctx context.Context, code *domain.DeviceCode) error
+	FindByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceCode, error)
+	// FindPendingByUserCode finds an unexpired request awaiting the user's decision
+	FindPendingByUserCode(ctx context.Context, userCode string) (*domain.DeviceCode, error)
+	// Decide approves or denies a pending request for a user, failing with
+	// domain.ErrDeviceCodeNotFound if it was already decided
+	Decide(ctx context.Context, id uint, status, userID string, authenticatedAt time.Time) error
+	RecordPoll(ctx context.Context, id uint, polledAt time.Time, interval int) error
+	// Consume atomically marks an approved request as used, failing with
+	// domain.ErrDeviceCodeUsed if it was already used
+	Consume(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context) error
+}
+
+// PushedAuthorizationRequestRepository defines the interface for pushed authorization request data access
+type PushedAuthorizationRequestRepository interface {
+	Create(ctx context.Context, request *domain.PushedAuthorizationRequest) error
+	// FindActive finds an unused, unexpired request by the hash of its request URI
+	FindActive(ctx context.Context, requestURIHash string) (*domain.PushedAuthorizationRequest, error)
+	// Consume atomically marks a request as used, failing with
+	// domain.ErrRequestURINotFound if it was already used
+	Consume(ctx context.Context, id uint) error
+	DeleteExpired(ctx context.Context) error
+}
+
+// ConsentRepository defines the interface for user consent data access
+type ConsentRepository interface {
+	Find(ctx context.Context, userID, clientID string) (*domain.Consent, error)
+	// Save creates a consent or replaces the scope of an existing one
+	Save(ctx context.Context, consent *domain.Consent) error
+	ListByUserID(ctx context.Context, userID string) ([]*domain.Consent, error)
+	Delete(ctx context.Context, userID, clientID string) error
+	DeleteAllByUserID(ctx context.Context, userID string) error
+}
+
+// UsedClientAssertionRepository defines the interface for client assertion replay protection
+type UsedClientAssertionRepository interface {
+	// Create records a used assertion, failing with
+	// domain.ErrClientAssertionReplayed if it was already used
+	Create(ctx context.Context, assertion *domain.UsedClientAssertion) error
+	DeleteExpired(ctx context.Context) error
+}
+
+// UsedDPoPProofRepository defines the interface for DPoP proof replay protection
+type UsedDPoPProofRepository interface {
+	// Create records a used proof, failing with domain.ErrDPoPProofReplayed
+	// if it was already used
+	Create(ctx context.Context, proof *domain.UsedDPoPProof) error
+	DeleteExpired(ctx context.Context) error
+}
+
+// LogoutDeliveryRepository defines the interface for back-channel logout delivery data access
+type LogoutDeliveryRepository interface {
+	Create(ctx context.Context, delivery *domain.LogoutDelivery) error
+	// ClaimDue returns up to limit pending deliveries that are due and
+	// postpones them by lease, so that other instances skip them meanwhile
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*domain.LogoutDelivery, error)
+	Update(ctx context.Context, delivery *domain.LogoutDelivery) error
+	ListByClientID(ctx context.Context, clientID string, limit int) ([]*domain.LogoutDelivery, error)
+	// DeleteExpired deletes finished deliveries past their retention
+	DeleteExpired(ctx context.Context) error
+}
+
+// SSOSessionRepository defines the interface for browser SSO session data access
+type SSOSessionRepository interface {
+	Create(ctx context.Context, session *domain.SSOSession) error
+	FindByTokenHash(ctx context.Context, tokenHash string) (*domain.SSOSession, error)
+	Update(ctx context.Context, session *domain.SSOSession) error
+	Touch(ctx context.Context, id string, lastActiveAt time.Time) error
+	Revoke(ctx context.Context, id string) error
 	RevokeAllByUserID(ctx context.Context, userID string) error
 	DeleteExpired(ctx context.Context) error
 }
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// accessTokenRevokedChannel is the Postgres NOTIFY channel revocations are published on
+const accessTokenRevokedChannel = "access_token_revoked"
+
+type revokedAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewRevokedAccessTokenRepository creates a new revoked access token repository
+func NewRevokedAccessTokenRepository(db *gorm.DB) RevokedAccessTokenRepository {
+	return &revokedAccessTokenRepository{db: db}
+}
+
+func (r *revokedAccessTokenRepository) Create(ctx context.Context, token *domain.RevokedAccessToken) error {
+	payload, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+
+	// The notification is delivered to listeners when the transaction commits
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(token).Error; err != nil {
+			return err
+		}
+		return tx.Exec("SELECT pg_notify(?, ?)", accessTokenRevokedChannel, string(payload)).Error
+	})
+}
+
+func (r *revokedAccessTokenRepository) FindActive(ctx context.Context) ([]*domain.RevokedAccessToken, error) {
+	var tokens []*domain.RevokedAccessToken
+	err := r.db.WithContext(ctx).Where("expires_at > ?", time.Now()).Find(&tokens).Error
+	return tokens, err
+}
+
+func (r *revokedAccessTokenRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.RevokedAccessToken{}).Error
+}
+
+func (r *revokedAccessTokenRepository) Listen(ctx context.Context, ready func() error, handle func(token *domain.RevokedAccessToken)) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var listenErr error
+	_ = conn.Raw(func(driverConn interface{}) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+		listenErr = func() error {
+			if _, err := pgxConn.Exec(ctx, "LISTEN "+accessTokenRevokedChannel); err != nil {
+				return err
+			}
+			if err := ready(); err != nil {
+				return err
+			}
+
+			for {
+				notification, err := pgxConn.WaitForNotification(ctx)
+				if err != nil {
+					return err
+				}
+
+				var token domain.RevokedAccessToken
+				if err := json.Unmarshal([]byte(notification.Payload), &token); err != nil {
+					log.Printf("invalid %s notification: %v", accessTokenRevokedChannel, err)
+					continue
+				}
+				handle(&token)
+			}
+		}()
+
+		// Discard the connection instead of returning a listening connection to the pool
+		return driver.ErrBadConn
+	})
+
+	return fmt.Errorf("listening on %s: %w", accessTokenRevokedChannel, listenErr)
+}
@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+// NewSecurityEventRepository creates a new security event repository
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepository {
+	return &securityEventRepository{db: db}
+}
+
+func (r *securityEventRepository) Create(ctx context.Context, event *domain.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *securityEventRepository) CountSince(ctx context.Context, userID, eventType string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.SecurityEvent{}).
+		Where("user_id = ? AND type = ? AND created_at >= ?", userID, eventType, since).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ssoSessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSSOSessionRepository creates a new SSO session repository
+func NewSSOSessionRepository(db *gorm.DB) SSOSessionRepository {
+	return &ssoSessionRepository{db: db}
+}
+
+func (r *ssoSessionRepository) Create(ctx context.Context, session *domain.SSOSession) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *ssoSessionRepository) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.SSOSession, error) {
+	var session domain.SSOSession
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&session).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *ssoSessionRepository) Update(ctx context.Context, session *domain.SSOSession) error {
+	return r.db.WithContext(ctx).Save(session).Error
+}
+
+func (r *ssoSessionRepository) Touch(ctx context.Context, id string, lastActiveAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&domain.SSOSession{}).
+		Where("id = ?", id).
+		Update("last_active_at", lastActiveAt).Error
+}
+
+func (r *ssoSessionRepository) Revoke(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&domain.SSOSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *ssoSessionRepository) RevokeAllByUserID(ctx context.Context, userID string) error {
+	return r.db.WithContext(ctx).Model(&domain.SSOSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *ssoSessionRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.SSOSession{}).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type usedClientAssertionRepository struct {
+	db *gorm.DB
+}
+
+// NewUsedClientAssertionRepository creates a new used client assertion repository
+func NewUsedClientAssertionRepository(db *gorm.DB) UsedClientAssertionRepository {
+	return &usedClientAssertionRepository{db: db}
+}
+
+func (r *usedClientAssertionRepository) Create(ctx context.Context, assertion *domain.UsedClientAssertion) error {
+	// The primary key makes concurrent uses of the same assertion fail
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(assertion)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrClientAssertionReplayed
+	}
+	return nil
+}
+
+func (r *usedClientAssertionRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.UsedClientAssertion{}).Error
+}
@@ -0,0 +1,37 @@
+package repository
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type usedDPoPProofRepository struct {
+	db *gorm.DB
+}
+
+// NewUsedDPoPProofRepository creates a new used DPoP proof repository
+func NewUsedDPoPProofRepository(db *gorm.DB) UsedDPoPProofRepository {
+	return &usedDPoPProofRepository{db: db}
+}
+
+func (r *usedDPoPProofRepository) Create(ctx context.Context, proof *domain.UsedDPoPProof) error {
+	// The primary key makes concurrent uses of the same proof fail
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(proof)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrDPoPProofReplayed
+	}
+	return nil
+}
+
+func (r *usedDPoPProofRepository) DeleteExpired(ctx context.Context) error {
+	return r.db.WithContext(ctx).
+		Where("expires_at < ?", time.Now()).
+		Delete(&domain.UsedDPoPProof{}).Error
+}
@@ -48,6 +48,12 @@ func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
 
+func (r *userRepository) IncrementTokenVersion(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ?", id).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+}
+
 func (r *userRepository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&domain.User{}, "id = ?", id).Error
 }
@@ -0,0 +1,130 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	denylistPruneInterval = time.Minute
+	denylistRetryDelay    = 5 * time.Second
+)
+
+// AccessTokenDenylist keeps an in-memory set of revoked access token IDs,
+// kept in sync across instances through Postgres LISTEN/NOTIFY
+type AccessTokenDenylist interface {
+	// Contains checks if the access token with the given jti was revoked
+	Contains(jti string) bool
+	// Revoke revokes an access token on all instances
+	Revoke(ctx context.Context, token *domain.RevokedAccessToken) error
+	// Run keeps the denylist in sync until ctx is done
+	Run(ctx context.Context)
+}
+
+type accessTokenDenylist struct {
+	repo    repository.RevokedAccessTokenRepository
+	mu      sync.RWMutex
+	entries map[string]time.Time // jti -> token expiry
+}
+
+// NewAccessTokenDenylist creates a new access token denylist
+func NewAccessTokenDenylist(repo repository.RevokedAccessTokenRepository) AccessTokenDenylist {
+	return &accessTokenDenylist{
+		repo:    repo,
+		entries: make(map[string]time.Time),
+	}
+}
+
+func (d *accessTokenDenylist) Contains(jti string) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	expiresAt, ok := d.entries[jti]
+	return ok && time.Now().Before(expiresAt)
+}
+
+func (d *accessTokenDenylist) Revoke(ctx context.Context, token *domain.RevokedAccessToken) error {
+	if err := d.repo.Create(ctx, token); err != nil {
+		return err
+	}
+
+	// Apply locally right away rather than waiting for our own notification
+	d.add(token)
+	return nil
+}
+
+func (d *accessTokenDenylist) Run(ctx context.Context) {
+	go d.prune(ctx)
+
+	for {
+		// Reload after every (re)subscribe, so that revocations published
+		// while we were not listening are not missed
+		err := d.repo.Listen(ctx, func() error { return d.load(ctx) }, d.add)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("access token denylist: %v, retrying in %s", err, denylistRetryDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(denylistRetryDelay):
+		}
+	}
+}
+
+func (d *accessTokenDenylist) add(token *domain.RevokedAccessToken) {
+	if token.IsExpired() {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.entries[token.JTI] = token.ExpiresAt
+}
+
+// load adds all unexpired revocations from the database
+func (d *accessTokenDenylist) load(ctx context.Context) error {
+	tokens, err := d.repo.FindActive(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		d.add(token)
+	}
+	return nil
+}
+
+// prune periodically drops entries whose tokens have expired, since expired
+// tokens are rejected by signature validation anyway
+func (d *accessTokenDenylist) prune(ctx context.Context) {
+	ticker := time.NewTicker(denylistPruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		d.mu.Lock()
+		for jti, expiresAt := range d.entries {
+			if now.After(expiresAt) {
+				delete(d.entries, jti)
+			}
+		}
+		d.mu.Unlock()
+
+		if err := d.repo.DeleteExpired(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("failed to delete expired access token revocations: %v", err)
+		}
+	}
+}
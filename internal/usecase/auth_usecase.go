@@ -3,9 +3,13 @@ package usecase
 import (
 	"auth-service/internal/domain"
 	"auth-service/internal/repository"
+	"auth-service/pkg/config"
 	"auth-service/pkg/jwt"
+	"auth-service/pkg/models"
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -14,29 +18,92 @@ import (
 type AuthUseCase interface {
 	Register(ctx context.Context, req RegisterRequest) (*AuthResponse, error)
 	Login(ctx context.Context, req LoginRequest) (*AuthResponse, error)
+	AuthenticateUser(ctx context.Context, email, password string) (*UserResponse, error)
+	IssueTokens(ctx context.Context, req IssueTokensRequest) (*AuthResponse, error)
 	RefreshToken(ctx context.Context, req RefreshTokenRequest) (*AuthResponse, error)
 	Logout(ctx context.Context, refreshToken string) error
 	LogoutAll(ctx context.Context, userID string) error
-	ValidateAccessToken(ctx context.Context, token string) (*jwt.Claims, error)
+	ListSessions(ctx context.Context, userID, currentSessionID string) ([]SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, sessionID string) error
+	ListConsents(ctx context.Context, userID string) ([]ConsentResponse, error)
+	// RevokeConsent withdraws a user's consent for a client and revokes the
+	// client's refresh tokens of the user
+	RevokeConsent(ctx context.Context, userID, clientID string) error
+	ChangePassword(ctx context.Context, userID string, req ChangePasswordRequest) error
+	SuspendUser(ctx context.Context, userID string) error
+	DeleteUser(ctx context.Context, userID string) error
+	RevokeAccessToken(ctx context.Context, userID, token string) error
+	// RevokeAuthorizationCodeTokens revokes the session and access token
+	// issued for an authorization code that was used again
+	RevokeAuthorizationCodeTokens(ctx context.Context, code *domain.AuthorizationCode) error
+	// ValidateAccessToken validates an access token, including revocation
+	// checks. The audience is only checked if required by an option
+	ValidateAccessToken(ctx context.Context, token string, opts ...jwt.ValidationOption) (*jwt.Claims, error)
+	// VerifyDPoPProof verifies a DPoP proof and returns the thumbprint of its
+	// key, returning domain.ErrDPoPNonceRequired if it lacks a fresh nonce
+	VerifyDPoPProof(ctx context.Context, req DPoPProofRequest) (string, error)
+	// DPoPNonce returns the nonce clients must include in DPoP proofs
+	DPoPNonce() string
+	// StartSSOSession starts a browser session for a user who signed in,
+	// replacing the browser's current session, and returns it with its new token
+	StartSSOSession(ctx context.Context, userID, currentToken string, metadata ClientMetadata) (*SSOSessionResponse, error)
+	// FindSSOSession returns the active session of a session token and
+	// records its use, returning domain.ErrSessionNotFound if there is none
+	FindSSOSession(ctx context.Context, token string) (*SSOSessionResponse, error)
+	// EndSSOSession ends a browser session and revokes the refresh tokens
+	// issued through it, returning the ended client sessions
+	EndSSOSession(ctx context.Context, sessionID string) ([]SessionResponse, error)
 	GetUserByID(ctx context.Context, userID string) (*UserResponse, error)
 }
 
+// refreshTokenReuseGracePeriod is how long after a rotation the old refresh
+// token is treated as a concurrent refresh rather than a replay
+const refreshTokenReuseGracePeriod = 10 * time.Second
+
 type authUseCase struct {
-	userRepo         repository.UserRepository
-	refreshTokenRepo repository.RefreshTokenRepository
-	jwtManager       *jwt.JWTManager
+	userRepo          repository.UserRepository
+	refreshTokenRepo  repository.RefreshTokenRepository
+	securityEventRepo repository.SecurityEventRepository
+	clientRepo        repository.ClientRepository
+	consentRepo       repository.ConsentRepository
+	usedDPoPProofRepo repository.UsedDPoPProofRepository
+	ssoSessionRepo    repository.SSOSessionRepository
+	jwtManager        *jwt.JWTManager
+	denylist          AccessTokenDenylist
+	logoutNotifier    LogoutNotifier
+	tokenVersions     *tokenVersionCache
+	machineClients    *machineClientCache
+	sessionCfg        *config.SessionConfig
 }
 
 // NewAuthUseCase creates a new auth use case
 func NewAuthUseCase(
 	userRepo repository.UserRepository,
 	refreshTokenRepo repository.RefreshTokenRepository,
+	securityEventRepo repository.SecurityEventRepository,
+	clientRepo repository.ClientRepository,
+	consentRepo repository.ConsentRepository,
+	usedDPoPProofRepo repository.UsedDPoPProofRepository,
+	ssoSessionRepo repository.SSOSessionRepository,
 	jwtManager *jwt.JWTManager,
+	denylist AccessTokenDenylist,
+	logoutNotifier LogoutNotifier,
+	sessionCfg *config.SessionConfig,
 ) AuthUseCase {
 	return &authUseCase{
-		userRepo:         userRepo,
-		refreshTokenRepo: refreshTokenRepo,
-		jwtManager:       jwtManager,
+		userRepo:          userRepo,
+		refreshTokenRepo:  refreshTokenRepo,
+		securityEventRepo: securityEventRepo,
+		clientRepo:        clientRepo,
+		consentRepo:       consentRepo,
+		usedDPoPProofRepo: usedDPoPProofRepo,
+		ssoSessionRepo:    ssoSessionRepo,
+		jwtManager:        jwtManager,
+		denylist:          denylist,
+		logoutNotifier:    logoutNotifier,
+		tokenVersions:     newTokenVersionCache(tokenVersionCacheTTL),
+		machineClients:    newMachineClientCache(machineClientCacheTTL),
+		sessionCfg:        sessionCfg,
 	}
 }
 
@@ -68,12 +135,59 @@ func (uc *authUseCase) Register(ctx context.Context, req RegisterRequest) (*Auth
 	}
 
 	// Generate tokens
-	return uc.generateTokens(ctx, user)
+	return uc.generateTokens(ctx, user, tokenParams{metadata: req.Client})
 }
 
 func (uc *authUseCase) Login(ctx context.Context, req LoginRequest) (*AuthResponse, error) {
+	scope, err := normalizeScope(req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var client *domain.Client
+	if req.ClientID != "" {
+		client, err = findClient(ctx, uc.clientRepo, req.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if !clientAllowsScope(client, scope) {
+			return nil, domain.ErrInvalidScope
+		}
+	} else if hasScope(scope, ScopeOpenID) {
+		return nil, domain.ErrClientIDRequired
+	}
+
+	user, err := uc.authenticate(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate tokens
+	return uc.generateTokens(ctx, user, tokenParams{
+		metadata: req.Client,
+		client:   client,
+		scope:    scope,
+		nonce:    req.Nonce,
+	})
+}
+
+func (uc *authUseCase) AuthenticateUser(ctx context.Context, email, password string) (*UserResponse, error) {
+	user, err := uc.authenticate(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserResponse{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.Name,
+	}, nil
+}
+
+// authenticate verifies the credentials of an active user
+func (uc *authUseCase) authenticate(ctx context.Context, email, password string) (*domain.User, error) {
 	// Find user by email
-	user, err := uc.userRepo.FindByEmail(ctx, req.Email)
+	user, err := uc.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		if err == domain.ErrUserNotFound {
 			return nil, domain.ErrInvalidCredentials
@@ -82,17 +196,48 @@ func (uc *authUseCase) Login(ctx context.Context, req LoginRequest) (*AuthRespon
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
 		return nil, domain.ErrInvalidCredentials
 	}
 
-	// Generate tokens
-	return uc.generateTokens(ctx, user)
+	if user.IsSuspended() {
+		return nil, domain.ErrUserSuspended
+	}
+
+	return user, nil
+}
+
+func (uc *authUseCase) IssueTokens(ctx context.Context, req IssueTokensRequest) (*AuthResponse, error) {
+	client, err := findClient(ctx, uc.clientRepo, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := uc.userRepo.FindByID(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user may have been suspended since authenticating
+	if user.IsSuspended() {
+		return nil, domain.ErrUserSuspended
+	}
+
+	return uc.generateTokens(ctx, user, tokenParams{
+		metadata:        req.Client,
+		client:          client,
+		scope:           req.Scope,
+		nonce:           req.Nonce,
+		authenticatedAt: req.AuthenticatedAt,
+		audience:        req.Audience,
+		cnf:             req.Confirmation,
+		ssoSessionID:    req.SSOSessionID,
+	})
 }
 
 func (uc *authUseCase) RefreshToken(ctx context.Context, req RefreshTokenRequest) (*AuthResponse, error) {
 	// Validate refresh token from database
-	refreshToken, err := uc.refreshTokenRepo.FindByToken(ctx, req.RefreshToken)
+	refreshToken, err := uc.refreshTokenRepo.FindByTokenHash(ctx, uc.jwtManager.HashRefreshToken(req.RefreshToken))
 	if err != nil {
 		if err == domain.ErrRefreshTokenNotFound {
 			return nil, domain.ErrInvalidToken
@@ -102,43 +247,444 @@ func (uc *authUseCase) RefreshToken(ctx context.Context, req RefreshTokenRequest
 
 	// Check if token is valid
 	if !refreshToken.IsValid() {
+		if refreshToken.IsRotated() {
+			return nil, uc.handleRefreshTokenReuse(ctx, refreshToken)
+		}
 		if refreshToken.IsRevoked {
 			return nil, domain.ErrRefreshTokenRevoked
 		}
 		return nil, domain.ErrRefreshTokenExpired
 	}
 
+	// Tokens can only be refreshed by the client they were issued to, with
+	// the keys they are bound to
+	if refreshToken.ClientID != req.ClientID {
+		return nil, domain.ErrInvalidToken
+	}
+	if refreshToken.DPoPJKT != "" && refreshToken.DPoPJKT != req.Confirmation.JKT {
+		return nil, domain.ErrInvalidDPoPProof
+	}
+	if refreshToken.CertificateThumbprint != "" && refreshToken.CertificateThumbprint != req.Confirmation.X5TS256 {
+		return nil, domain.ErrCertificateMismatch
+	}
+
+	var client *domain.Client
+	if refreshToken.ClientID != "" {
+		client, err = findClient(ctx, uc.clientRepo, refreshToken.ClientID)
+		if err != nil {
+			if err == domain.ErrInvalidClient {
+				return nil, domain.ErrInvalidToken
+			}
+			return nil, err
+		}
+	}
+
 	// Get user (refresh token already contains user ID)
 	user, err := uc.userRepo.FindByID(ctx, refreshToken.UserID)
 	if err != nil {
+		if err == domain.ErrUserNotFound {
+			return nil, domain.ErrInvalidToken
+		}
 		return nil, err
 	}
 
-	// Revoke old refresh token
-	if err := uc.refreshTokenRepo.Revoke(ctx, req.RefreshToken); err != nil {
-		return nil, fmt.Errorf("failed to revoke old refresh token: %w", err)
+	if user.IsSuspended() {
+		return nil, domain.ErrUserSuspended
 	}
 
 	// Generate new tokens
-	return uc.generateTokens(ctx, user)
+	resp, newRefreshToken, err := uc.newTokens(user, refreshToken, tokenParams{
+		metadata: req.Client,
+		client:   client,
+		audience: req.Audience,
+		cnf:      req.Confirmation,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Revoke old refresh token and save the new one atomically, so only one
+	// of several concurrent refreshes with the same token succeeds
+	if err := uc.refreshTokenRepo.Rotate(ctx, refreshToken.ID, newRefreshToken); err != nil {
+		if err == domain.ErrRefreshTokenRotated {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	return resp, nil
+}
+
+// handleRefreshTokenReuse handles presentation of an already rotated refresh
+// token. Outside the grace period for concurrent refreshes this means the
+// token was stolen or replayed, so the whole family is revoked.
+func (uc *authUseCase) handleRefreshTokenReuse(ctx context.Context, refreshToken *domain.RefreshToken) error {
+	if time.Since(*refreshToken.RotatedAt) < refreshTokenReuseGracePeriod {
+		return domain.ErrRefreshTokenRotated
+	}
+
+	if err := uc.refreshTokenRepo.RevokeFamily(ctx, refreshToken.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+
+	recordSecurityEvent(ctx, uc.securityEventRepo, refreshToken.UserID, domain.SecurityEventRefreshTokenReuse,
+		fmt.Sprintf("refresh token %d reused, revoked family %s", refreshToken.ID, refreshToken.FamilyID))
+	uc.notifyLogout(ctx, refreshToken)
+
+	return domain.ErrRefreshTokenReused
+}
+
+// recordSecurityEvent logs and stores a security event
+// Failing to store the event does not fail the request
+func recordSecurityEvent(ctx context.Context, securityEventRepo repository.SecurityEventRepository, userID, eventType, details string) {
+	log.Printf("security event %s for user %s: %s", eventType, userID, details)
+
+	event := &domain.SecurityEvent{
+		UserID:  userID,
+		Type:    eventType,
+		Details: details,
+	}
+	if err := securityEventRepo.Create(ctx, event); err != nil {
+		log.Printf("failed to store security event %s: %v", eventType, err)
+	}
 }
 
 func (uc *authUseCase) Logout(ctx context.Context, refreshToken string) error {
-	return uc.refreshTokenRepo.Revoke(ctx, refreshToken)
+	tokenHash := uc.jwtManager.HashRefreshToken(refreshToken)
+	token, err := uc.refreshTokenRepo.FindByTokenHash(ctx, tokenHash)
+	if err == domain.ErrRefreshTokenNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := uc.refreshTokenRepo.Revoke(ctx, tokenHash); err != nil {
+		return err
+	}
+	if token.IsValid() {
+		uc.notifyLogout(ctx, token)
+	}
+	return nil
 }
 
 func (uc *authUseCase) LogoutAll(ctx context.Context, userID string) error {
-	return uc.refreshTokenRepo.RevokeAllByUserID(ctx, userID)
+	return uc.invalidateAllTokens(ctx, userID)
 }
 
-func (uc *authUseCase) ValidateAccessToken(ctx context.Context, token string) (*jwt.Claims, error) {
+func (uc *authUseCase) ChangePassword(ctx context.Context, userID string, req ChangePasswordRequest) error {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return domain.ErrInvalidCredentials
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user.Password = string(hashedPassword)
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return uc.invalidateAllTokens(ctx, userID)
+}
+
+func (uc *authUseCase) SuspendUser(ctx context.Context, userID string) error {
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	user.SuspendedAt = &now
+	if err := uc.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to suspend user: %w", err)
+	}
+
+	return uc.invalidateAllTokens(ctx, userID)
+}
+
+func (uc *authUseCase) DeleteUser(ctx context.Context, userID string) error {
+	if err := uc.invalidateAllTokens(ctx, userID); err != nil {
+		return err
+	}
+
+	if err := uc.consentRepo.DeleteAllByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete consents: %w", err)
+	}
+
+	return uc.userRepo.Delete(ctx, userID)
+}
+
+func (uc *authUseCase) RevokeAccessToken(ctx context.Context, userID, token string) error {
 	claims, err := uc.jwtManager.ValidateToken(token)
+	if err != nil || claims.ID == "" {
+		return domain.ErrInvalidToken
+	}
+
+	// Users can only revoke their own tokens
+	if claims.UserID != userID {
+		return domain.ErrUnauthorized
+	}
+
+	return uc.denylist.Revoke(ctx, &domain.RevokedAccessToken{
+		JTI:       claims.ID,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+}
+
+func (uc *authUseCase) RevokeAuthorizationCodeTokens(ctx context.Context, code *domain.AuthorizationCode) error {
+	recordSecurityEvent(ctx, uc.securityEventRepo, code.UserID, domain.SecurityEventAuthCodeReuse,
+		fmt.Sprintf("authorization code %d reused, revoking session %s", code.ID, code.SessionID))
+
+	if code.SessionID != "" {
+		err := uc.RevokeSession(ctx, code.UserID, code.SessionID)
+		if err != nil && err != domain.ErrSessionNotFound {
+			return err
+		}
+	}
+
+	if code.AccessTokenID != "" && code.AccessTokenExpiresAt != nil {
+		return uc.denylist.Revoke(ctx, &domain.RevokedAccessToken{
+			JTI:       code.AccessTokenID,
+			UserID:    code.UserID,
+			ExpiresAt: *code.AccessTokenExpiresAt,
+		})
+	}
+	return nil
+}
+
+// invalidateAllTokens revokes all refresh tokens and SSO sessions of a user
+// and bumps the token version, which invalidates all outstanding access tokens
+func (uc *authUseCase) invalidateAllTokens(ctx context.Context, userID string) error {
+	sessions, err := uc.refreshTokenRepo.FindByUserID(ctx, userID)
 	if err != nil {
+		return err
+	}
+
+	if err := uc.refreshTokenRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	uc.notifyLogout(ctx, sessions...)
+
+	// Browsers must sign in again
+	if err := uc.ssoSessionRepo.RevokeAllByUserID(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke SSO sessions: %w", err)
+	}
+
+	if err := uc.userRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		return fmt.Errorf("failed to bump token version: %w", err)
+	}
+	uc.tokenVersions.invalidate(userID)
+
+	return nil
+}
+
+// notifyLogout notifies the clients of ended sessions through back-channel
+// logout. Failing to schedule notifications does not fail the logout
+func (uc *authUseCase) notifyLogout(ctx context.Context, sessions ...*domain.RefreshToken) {
+	if err := uc.logoutNotifier.Notify(ctx, sessions); err != nil {
+		log.Printf("failed to notify clients of logout: %v", err)
+	}
+}
+
+func (uc *authUseCase) ListSessions(ctx context.Context, userID, currentSessionID string) ([]SessionResponse, error) {
+	tokens, err := uc.refreshTokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each session is represented by the latest unrevoked token of its family
+	sessions := make([]SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		if token.IsExpired() {
+			continue
+		}
+		sessions = append(sessions, SessionResponse{
+			ID:         token.FamilyID,
+			ClientID:   token.ClientID,
+			CreatedAt:  token.AuthenticatedAt,
+			LastUsedAt: token.CreatedAt,
+			IPAddress:  token.IPAddress,
+			UserAgent:  token.UserAgent,
+			Current:    token.FamilyID == currentSessionID,
+		})
+	}
+
+	return sessions, nil
+}
+
+func (uc *authUseCase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	tokens, err := uc.refreshTokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, token := range tokens {
+		if token.FamilyID == sessionID && !token.IsExpired() {
+			if err := uc.refreshTokenRepo.RevokeFamily(ctx, sessionID); err != nil {
+				return err
+			}
+			uc.notifyLogout(ctx, token)
+			return nil
+		}
+	}
+
+	return domain.ErrSessionNotFound
+}
+
+func (uc *authUseCase) ListConsents(ctx context.Context, userID string) ([]ConsentResponse, error) {
+	consents, err := uc.consentRepo.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]ConsentResponse, 0, len(consents))
+	for _, consent := range consents {
+		client, err := uc.clientRepo.FindByID(ctx, consent.ClientID)
+		if err != nil {
+			// Consents outlive deleted clients, which can't use them anymore
+			if err == domain.ErrClientNotFound {
+				continue
+			}
+			return nil, err
+		}
+		responses = append(responses, ConsentResponse{
+			ClientID:   consent.ClientID,
+			ClientName: client.Name,
+			Scope:      consent.Scope,
+			Scopes:     describeScopes(consent.Scope),
+			CreatedAt:  consent.CreatedAt,
+			UpdatedAt:  consent.UpdatedAt,
+		})
+	}
+
+	return responses, nil
+}
+
+func (uc *authUseCase) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	if _, err := uc.consentRepo.Find(ctx, userID, clientID); err != nil {
+		return err
+	}
+
+	tokens, err := uc.refreshTokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	var sessions []*domain.RefreshToken
+	for _, token := range tokens {
+		if token.ClientID == clientID {
+			sessions = append(sessions, token)
+		}
+	}
+
+	if err := uc.refreshTokenRepo.RevokeAllByUserIDAndClientID(ctx, userID, clientID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	uc.notifyLogout(ctx, sessions...)
+
+	// The client loses access right away, not once its access tokens expire
+	issued, err := uc.refreshTokenRepo.FindWithUnexpiredAccessTokens(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	for _, token := range issued {
+		err := uc.denylist.Revoke(ctx, &domain.RevokedAccessToken{
+			JTI:       token.AccessTokenID,
+			UserID:    userID,
+			ExpiresAt: *token.AccessTokenExpiresAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to revoke access token: %w", err)
+		}
+	}
+
+	return uc.consentRepo.Delete(ctx, userID, clientID)
+}
+
+func (uc *authUseCase) ValidateAccessToken(ctx context.Context, token string, opts ...jwt.ValidationOption) (*jwt.Claims, error) {
+	claims, err := uc.jwtManager.ValidateToken(token, opts...)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+
+	if uc.denylist.Contains(claims.ID) {
+		return nil, domain.ErrInvalidToken
+	}
+
+	// Machine tokens are valid while their client may use the grant
+	if claims.IsMachine() {
+		active, err := uc.machineClientActive(ctx, claims.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, domain.ErrInvalidToken
+		}
+		return claims, nil
+	}
+
+	// Reject tokens issued before the user's token version was bumped
+	entry, err := uc.currentTokenVersion(ctx, claims.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.active || claims.TokenVersion != entry.version {
 		return nil, domain.ErrInvalidToken
 	}
+
 	return claims, nil
 }
 
+// currentTokenVersion returns the user's current token version, using the
+// cache when possible. Deleted and suspended users are reported as inactive.
+func (uc *authUseCase) currentTokenVersion(ctx context.Context, userID string) (tokenVersionEntry, error) {
+	if entry, ok := uc.tokenVersions.get(userID); ok {
+		return entry, nil
+	}
+
+	version, active := 0, false
+	user, err := uc.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err != domain.ErrUserNotFound {
+			return tokenVersionEntry{}, err
+		}
+	} else {
+		version, active = user.TokenVersion, !user.IsSuspended()
+	}
+
+	return uc.tokenVersions.set(userID, version, active), nil
+}
+
+// machineClientActive reports whether a client may still use the
+// client_credentials grant, using the cache when possible. Deleted clients
+// are reported as inactive.
+func (uc *authUseCase) machineClientActive(ctx context.Context, clientID string) (bool, error) {
+	if active, ok := uc.machineClients.get(clientID); ok {
+		return active, nil
+	}
+
+	active := false
+	client, err := uc.clientRepo.FindByID(ctx, clientID)
+	if err != nil {
+		if err != domain.ErrClientNotFound {
+			return false, err
+		}
+	} else {
+		active = client.AllowsGrantType(GrantTypeClientCredentials)
+	}
+
+	uc.machineClients.set(clientID, active)
+	return active, nil
+}
+
 func (uc *authUseCase) GetUserByID(ctx context.Context, userID string) (*UserResponse, error) {
 	user, err := uc.userRepo.FindByID(ctx, userID)
 	if err != nil {
@@ -152,41 +698,152 @@ func (uc *authUseCase) GetUserByID(ctx context.Context, userID string) (*UserRes
 	}, nil
 }
 
-// generateTokens generates access and refresh tokens for a user
-func (uc *authUseCase) generateTokens(ctx context.Context, user *domain.User) (*AuthResponse, error) {
-	// Generate access token
-	accessToken, err := uc.jwtManager.GenerateAccessToken(user.ID, user.Email)
+// tokenParams describes the tokens to issue for a session
+type tokenParams struct {
+	metadata        ClientMetadata
+	client          *domain.Client // nil for first-party apps
+	scope           string
+	nonce           string
+	authenticatedAt time.Time        // defaults to now
+	audience        []string         // resources the access token is for, defaults to the service's own API
+	cnf             jwt.Confirmation // keys to bind the tokens to
+	ssoSessionID    string           // browser session the user signed in with, if any
+}
+
+// generateTokens generates access and refresh tokens for a new session of a user
+func (uc *authUseCase) generateTokens(ctx context.Context, user *domain.User, params tokenParams) (*AuthResponse, error) {
+	resp, refreshToken, err := uc.newTokens(user, nil, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
+		return nil, err
 	}
 
+	// Save refresh token to database
+	if err := uc.refreshTokenRepo.Create(ctx, refreshToken); err != nil {
+		return nil, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	return resp, nil
+}
+
+// newTokens generates access and refresh tokens for a user without
+// persisting the refresh token. The refresh token continues the family of
+// parent, or starts a new family (session) if parent is nil. Rotated tokens
+// keep the client and scope of their parent. The access token is bound to
+// the keys of params.cnf, and so is the refresh token of public clients;
+// confidential clients already authenticate to refresh (RFC 9449 section 5,
+// RFC 8705 section 4)
+func (uc *authUseCase) newTokens(user *domain.User, parent *domain.RefreshToken, params tokenParams) (*AuthResponse, *domain.RefreshToken, error) {
 	// Generate refresh token
 	refreshTokenString, expiresAt, err := uc.jwtManager.GenerateRefreshToken(user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	accessTokenLifetime := uc.jwtManager.GetAccessTokenDuration()
+	var clientID string
+	if params.client != nil {
+		clientID = params.client.ID
+		if params.client.AccessTokenLifetime > 0 {
+			accessTokenLifetime = time.Duration(params.client.AccessTokenLifetime) * time.Second
+		}
+		if params.client.RefreshTokenLifetime > 0 {
+			expiresAt = time.Now().Add(time.Duration(params.client.RefreshTokenLifetime) * time.Second)
+		}
 	}
 
-	// Save refresh token to database
 	refreshToken := &domain.RefreshToken{
-		UserID:    user.ID,
-		Token:     refreshTokenString,
-		ExpiresAt: expiresAt,
-		IsRevoked: false,
+		UserID:          user.ID,
+		ClientID:        clientID,
+		TokenHash:       uc.jwtManager.HashRefreshToken(refreshTokenString),
+		FamilyID:        models.NewNanoID(),
+		ExpiresAt:       expiresAt,
+		IsRevoked:       false,
+		Scope:           params.scope,
+		AuthenticatedAt: params.authenticatedAt,
+		IPAddress:       params.metadata.IPAddress,
+		UserAgent:       params.metadata.UserAgent,
+		SSOSessionID:    params.ssoSessionID,
+	}
+	if refreshToken.AuthenticatedAt.IsZero() {
+		refreshToken.AuthenticatedAt = time.Now()
+	}
+	if parent != nil {
+		refreshToken.FamilyID = parent.FamilyID
+		refreshToken.ParentID = &parent.ID
+		refreshToken.Scope = parent.Scope
+		refreshToken.AuthenticatedAt = parent.AuthenticatedAt
+		refreshToken.SSOSessionID = parent.SSOSessionID
+		refreshToken.DPoPJKT = parent.DPoPJKT
+		refreshToken.CertificateThumbprint = parent.CertificateThumbprint
+	} else if params.client != nil && !params.client.IsConfidential() {
+		refreshToken.DPoPJKT = params.cnf.JKT
+		refreshToken.CertificateThumbprint = params.cnf.X5TS256
 	}
 
-	if err := uc.refreshTokenRepo.Create(ctx, refreshToken); err != nil {
-		return nil, fmt.Errorf("failed to save refresh token: %w", err)
+	// Generate access token
+	claims := jwt.Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		SessionID:    refreshToken.FamilyID,
+		ClientID:     clientID,
+		Scope:        refreshToken.Scope,
+		TokenVersion: user.TokenVersion,
+	}
+	claims.Audience = params.audience
+	claims.Cnf = confirmationOf(params.cnf)
+	accessToken, err := uc.jwtManager.GenerateAccessTokenWithLifetime(claims, accessTokenLifetime)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+	issued, err := uc.jwtManager.ValidateToken(accessToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read access token: %w", err)
+	}
+	refreshToken.AccessTokenID = issued.ID
+	refreshToken.AccessTokenExpiresAt = &issued.ExpiresAt.Time
+
+	// ID tokens are issued to clients, including on refresh (without nonce)
+	var idToken string
+	if clientID != "" && hasScope(refreshToken.Scope, ScopeOpenID) {
+		idToken, err = uc.newIDToken(user, refreshToken, accessToken, params)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	return &AuthResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshTokenString,
-		TokenType:    "Bearer",
-		ExpiresIn:    int(uc.jwtManager.GetAccessTokenDuration().Seconds()),
-		User: UserResponse{
+		TokenType:    tokenTypeOf(params.cnf),
+		ExpiresIn:    int(accessTokenLifetime.Seconds()),
+		Scope:        refreshToken.Scope,
+		IDToken:      idToken,
+		User: &UserResponse{
 			ID:    user.ID,
 			Email: user.Email,
 			Name:  user.Name,
 		},
-	}, nil
+	}, refreshToken, nil
+}
+
+// newIDToken generates an OpenID Connect ID token for the session of refreshToken
+func (uc *authUseCase) newIDToken(user *domain.User, refreshToken *domain.RefreshToken, accessToken string, params tokenParams) (string, error) {
+	claims := jwt.IDTokenClaims{
+		Nonce:     params.nonce,
+		AuthTime:  refreshToken.AuthenticatedAt.Unix(),
+		SessionID: refreshToken.FamilyID,
+	}
+	if hasScope(refreshToken.Scope, ScopeProfile) {
+		claims.Name = user.Name
+	}
+	if hasScope(refreshToken.Scope, ScopeEmail) {
+		claims.Email = user.Email
+	}
+
+	idToken, err := uc.jwtManager.GenerateIDToken(user.ID, params.client.ID, accessToken, claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID token: %w", err)
+	}
+
+	return idToken, nil
 }
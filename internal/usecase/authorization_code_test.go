@@ -0,0 +1,176 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const (
+	testCode         = "test-authorization-code"
+	testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	// testCodeChallenge is the S256 code challenge of testCodeVerifier
+	testCodeChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	testRedirectURI   = "https://app.example.com/callback"
+)
+
+// newCodeExchangeTest creates an oauthTest with an unused code of web-app
+func newCodeExchangeTest(t *testing.T) *oauthTest {
+	t.Helper()
+
+	test := newOAuthTest(t)
+	test.codes.codes[hashToken(testCode)] = &domain.AuthorizationCode{
+		ID:            1,
+		CodeHash:      hashToken(testCode),
+		ClientID:      "web-app",
+		UserID:        "user-1",
+		RedirectURI:   testRedirectURI,
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+	return test
+}
+
+func TestValidateAuthorizationRequest(t *testing.T) {
+	valid := AuthorizationRequest{
+		ResponseType:        ResponseTypeCode,
+		ClientID:            "web-app",
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid",
+		CodeChallenge:       "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		CodeChallengeMethod: CodeChallengeMethodS256,
+	}
+
+	tests := []struct {
+		name      string
+		modify    func(req *AuthorizationRequest)
+		wantErr   error
+		wantOAuth string
+	}{
+		{"valid request", func(req *AuthorizationRequest) {}, nil, ""},
+		{"unknown client", func(req *AuthorizationRequest) { req.ClientID = "other-app" }, domain.ErrInvalidClient, ""},
+		{"unregistered redirect URI", func(req *AuthorizationRequest) { req.RedirectURI = "https://app.example.com/other" }, domain.ErrInvalidRedirectURI, ""},
+		{"redirect URI prefix", func(req *AuthorizationRequest) { req.RedirectURI = testRedirectURI + "/evil" }, domain.ErrInvalidRedirectURI, ""},
+		{"token response type", func(req *AuthorizationRequest) { req.ResponseType = "token" }, nil, ErrorUnsupportedResponseType},
+		{"missing challenge", func(req *AuthorizationRequest) { req.CodeChallenge = "" }, nil, ErrorInvalidRequest},
+		{"plain method", func(req *AuthorizationRequest) { req.CodeChallengeMethod = "plain" }, nil, ErrorInvalidRequest},
+		{"unsupported scope", func(req *AuthorizationRequest) { req.Scope = "admin" }, nil, ErrorInvalidScope},
+		{"scope not allowed for the client", func(req *AuthorizationRequest) { req.Scope = "openid email" }, nil, ErrorInvalidScope},
+		{"client without the grant", func(req *AuthorizationRequest) { req.ClientID = "cli" }, nil, ErrorUnauthorizedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newOAuthTest(t)
+			test.clients.clients["cli"] = &domain.Client{
+				ID:           "cli",
+				RedirectURIs: []string{testRedirectURI},
+				GrantTypes:   []string{GrantTypeRefreshToken},
+				Scopes:       []string{ScopeOpenID},
+			}
+			req := valid
+			tt.modify(&req)
+
+			err := test.ValidateAuthorizationRequest(context.Background(), req)
+			var oauthErr *OAuthError
+			switch {
+			case tt.wantOAuth != "":
+				if !errors.As(err, &oauthErr) || oauthErr.Code != tt.wantOAuth {
+					t.Fatalf("expected %s, got %v", tt.wantOAuth, err)
+				}
+			case err != tt.wantErr:
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestExchangeAuthorizationCode(t *testing.T) {
+	client := &domain.Client{ID: "web-app"}
+	valid := TokenRequest{Code: testCode, CodeVerifier: testCodeVerifier, RedirectURI: testRedirectURI}
+
+	tests := []struct {
+		name    string
+		client  *domain.Client
+		modify  func(req *TokenRequest)
+		wantErr bool
+	}{
+		{"valid exchange", client, func(req *TokenRequest) {}, false},
+		{"unknown code", client, func(req *TokenRequest) { req.Code = "other" }, true},
+		{"wrong verifier", client, func(req *TokenRequest) { req.CodeVerifier = testCodeVerifier[1:] + "a" }, true},
+		{"missing verifier", client, func(req *TokenRequest) { req.CodeVerifier = "" }, true},
+		{"other redirect URI", client, func(req *TokenRequest) { req.RedirectURI = "https://evil.example.com/callback" }, true},
+		{"other client", &domain.Client{ID: "other-app"}, func(req *TokenRequest) {}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newCodeExchangeTest(t)
+			req := valid
+			tt.modify(&req)
+
+			resp, err := test.exchangeAuthorizationCode(context.Background(), tt.client, req)
+			if tt.wantErr {
+				var oauthErr *OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Code != ErrorInvalidGrant && oauthErr.Code != ErrorInvalidRequest {
+					t.Fatalf("expected invalid_grant or invalid_request, got %v", err)
+				}
+				return
+			}
+			if err != nil || resp.AccessToken == "" {
+				t.Fatalf("expected tokens, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExchangeAuthorizationCodeReuseRevokesTokens(t *testing.T) {
+	test := newCodeExchangeTest(t)
+	client := &domain.Client{ID: "web-app"}
+	req := TokenRequest{Code: testCode, CodeVerifier: testCodeVerifier, RedirectURI: testRedirectURI}
+
+	resp, err := test.exchangeAuthorizationCode(context.Background(), client, req)
+	if err != nil {
+		t.Fatalf("first exchange failed: %v", err)
+	}
+	claims, err := test.jwtManager.ValidateToken(resp.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := test.codes.codes[hashToken(testCode)]
+	if code.SessionID != "session-1" || code.AccessTokenID != claims.ID || code.AccessTokenExpiresAt == nil {
+		t.Fatalf("issued tokens not recorded on the code: %+v", code)
+	}
+
+	_, err = test.exchangeAuthorizationCode(context.Background(), client, req)
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != ErrorInvalidGrant {
+		t.Fatalf("expected invalid_grant on reuse, got %v", err)
+	}
+	if len(test.auth.revokedCodes) != 1 {
+		t.Fatalf("expected the tokens of the code to be revoked once, got %d", len(test.auth.revokedCodes))
+	}
+	if revoked := test.auth.revokedCodes[0]; revoked.SessionID != "session-1" || revoked.AccessTokenID != claims.ID {
+		t.Errorf("revoked the wrong tokens: %+v", revoked)
+	}
+}
+
+func TestExchangeAuthorizationCodeReusedWhileIssuing(t *testing.T) {
+	test := newCodeExchangeTest(t)
+	client := &domain.Client{ID: "web-app"}
+	req := TokenRequest{Code: testCode, CodeVerifier: testCodeVerifier, RedirectURI: testRedirectURI}
+
+	// A replay between consuming the code and recording its tokens finds
+	// nothing to revoke, so the exchange must not hand out its tokens
+	now := time.Now()
+	test.codes.codes[hashToken(testCode)].ReusedAt = &now
+
+	_, err := test.exchangeAuthorizationCode(context.Background(), client, req)
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != ErrorInvalidGrant {
+		t.Fatalf("expected invalid_grant, got %v", err)
+	}
+	if len(test.auth.revokedCodes) != 1 || test.auth.revokedCodes[0].SessionID != "session-1" {
+		t.Fatalf("expected the issued tokens to be revoked, got %+v", test.auth.revokedCodes)
+	}
+}
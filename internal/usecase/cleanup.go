@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// cleanupInterval is how often expired records are deleted
+const cleanupInterval = 5 * time.Minute
+
+// ExpiringRepository is a repository of records that expire
+type ExpiringRepository interface {
+	DeleteExpired(ctx context.Context) error
+}
+
+// RunCleanup periodically deletes expired records until ctx is done
+func RunCleanup(ctx context.Context, name string, repo ExpiringRepository) {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := repo.DeleteExpired(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("failed to delete expired %s: %v", name, err)
+		}
+	}
+}
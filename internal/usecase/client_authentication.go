@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"slices"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ClientAssertionTypeJWTBearer is the client_assertion_type of private_key_jwt (RFC 7523)
+const ClientAssertionTypeJWTBearer = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// clientIDOf returns the client the credentials claim to be
+// private_key_jwt clients may identify themselves only through the assertion
+func clientIDOf(credentials ClientCredentials) string {
+	if credentials.ClientID == "" && credentials.ClientAssertion != "" {
+		subject, _ := jwt.UnverifiedSubject(credentials.ClientAssertion)
+		return subject
+	}
+	return credentials.ClientID
+}
+
+// verifyClient authenticates a client with the method it was registered with
+// Returns domain.ErrInvalidClient if authentication fails
+func (uc *oauthUseCase) verifyClient(ctx context.Context, client *domain.Client, credentials ClientCredentials) error {
+	switch client.AuthMethod() {
+	case domain.ClientAuthSecretBasic, domain.ClientAuthSecretPost:
+		return verifyClientSecret(client, credentials)
+	case domain.ClientAuthPrivateKeyJWT:
+		return uc.verifyClientAssertion(ctx, client, credentials)
+	case domain.ClientAuthTLS:
+		// Only the CA vouches for the subject DN, so the chain must be verified
+		if credentials.Certificate == nil || !credentials.CertificateVerified || credentials.Certificate.Subject.String() != client.TLSClientAuthSubjectDN {
+			return domain.ErrInvalidClient
+		}
+		return nil
+	case domain.ClientAuthSelfSignedTLS:
+		return uc.verifySelfSignedCertificate(ctx, client, credentials)
+	default:
+		return domain.ErrInvalidClient
+	}
+}
+
+// verifyClientSecret checks the secret of a client, sent with HTTP Basic or
+// in the form body
+func verifyClientSecret(client *domain.Client, credentials ClientCredentials) error {
+	if credentials.ClientSecret == "" {
+		return domain.ErrInvalidClient
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(credentials.ClientSecret)); err != nil {
+		return domain.ErrInvalidClient
+	}
+	return nil
+}
+
+// verifyClientAssertion checks a JWT signed with one of the client's keys
+// (RFC 7523 section 3). Each assertion can only be used once
+func (uc *oauthUseCase) verifyClientAssertion(ctx context.Context, client *domain.Client, credentials ClientCredentials) error {
+	if credentials.ClientAssertionType != ClientAssertionTypeJWTBearer || credentials.ClientAssertion == "" {
+		return domain.ErrInvalidClient
+	}
+
+	keys, err := uc.clientKeys(ctx, client, false)
+	if err != nil {
+		return domain.ErrInvalidClient
+	}
+
+	claims, err := keys.VerifyAssertion(credentials.ClientAssertion)
+	if err != nil && client.JWKSURI != "" {
+		// The client may have rotated its keys since they were fetched
+		if keys, err = uc.clientKeys(ctx, client, true); err == nil {
+			claims, err = keys.VerifyAssertion(credentials.ClientAssertion)
+		}
+	}
+	if err != nil {
+		return domain.ErrInvalidClient
+	}
+	if claims.Issuer != client.ID || claims.Subject != client.ID || claims.ID == "" {
+		return domain.ErrInvalidClient
+	}
+	audiences := uc.clientAssertionAudiences()
+	if !slices.ContainsFunc(claims.Audience, func(audience string) bool { return slices.Contains(audiences, audience) }) {
+		return domain.ErrInvalidClient
+	}
+
+	err = uc.usedClientAssertionRepo.Create(ctx, &domain.UsedClientAssertion{
+		ClientID:  client.ID,
+		JTI:       claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err == domain.ErrClientAssertionReplayed {
+		return domain.ErrInvalidClient
+	}
+	return err
+}
+
+// clientAssertionAudiences lists the values a client assertion may identify
+// the server with in its aud claim: the issuer or the endpoint it is sent to
+func (uc *oauthUseCase) clientAssertionAudiences() []string {
+	metadata := uc.Discovery()
+	return []string{
+		metadata.Issuer,
+		metadata.TokenEndpoint,
+		metadata.IntrospectionEndpoint,
+		metadata.RevocationEndpoint,
+	}
+}
+
+// verifySelfSignedCertificate checks that the TLS client certificate has a
+// key of the client's JWKS (RFC 8705 section 2.2)
+func (uc *oauthUseCase) verifySelfSignedCertificate(ctx context.Context, client *domain.Client, credentials ClientCredentials) error {
+	if credentials.Certificate == nil {
+		return domain.ErrInvalidClient
+	}
+
+	keys, err := uc.clientKeys(ctx, client, false)
+	if err == nil && !keys.Contains(credentials.Certificate.PublicKey) && client.JWKSURI != "" {
+		keys, err = uc.clientKeys(ctx, client, true)
+	}
+	if err != nil || !keys.Contains(credentials.Certificate.PublicKey) {
+		return domain.ErrInvalidClient
+	}
+	return nil
+}
+
+// clientKeys returns the public keys of a client, registered inline or
+// published at its jwks_uri
+func (uc *oauthUseCase) clientKeys(ctx context.Context, client *domain.Client, refresh bool) (*jwt.KeySet, error) {
+	if client.JWKSURI != "" {
+		return uc.clientKeyCache.get(ctx, client.ID, client.JWKSURI, refresh)
+	}
+	return jwt.ParseKeySet([]byte(client.JWKS))
+}
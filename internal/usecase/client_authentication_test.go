@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// fakeUsedClientAssertionRepo records used assertions in memory
+type fakeUsedClientAssertionRepo struct {
+	repository.UsedClientAssertionRepository
+	used map[string]bool
+}
+
+func (r *fakeUsedClientAssertionRepo) Create(ctx context.Context, assertion *domain.UsedClientAssertion) error {
+	key := assertion.ClientID + " " + assertion.JTI
+	if r.used[key] {
+		return domain.ErrClientAssertionReplayed
+	}
+	r.used[key] = true
+	return nil
+}
+
+func signClientAssertion(t *testing.T, key ed25519.PrivateKey, claims gojwt.RegisteredClaims) string {
+	t.Helper()
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = "test"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyClientAssertion(t *testing.T) {
+	key, jwks := newTestClientKey(t)
+	otherKey, _ := newTestClientKey(t)
+	client := &domain.Client{
+		ID:                      "worker",
+		TokenEndpointAuthMethod: domain.ClientAuthPrivateKeyJWT,
+		JWKS:                    jwks,
+	}
+	validClaims := func() gojwt.RegisteredClaims {
+		return gojwt.RegisteredClaims{
+			Issuer:    "worker",
+			Subject:   "worker",
+			Audience:  gojwt.ClaimStrings{testIssuer + "/oauth/token"},
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Minute)),
+			ID:        "assertion-1",
+		}
+	}
+
+	tests := []struct {
+		name          string
+		key           ed25519.PrivateKey
+		modify        func(claims *gojwt.RegisteredClaims)
+		assertionType string
+		wantErr       bool
+	}{
+		{"valid assertion", key, func(claims *gojwt.RegisteredClaims) {}, ClientAssertionTypeJWTBearer, false},
+		{"issuer as audience", key, func(claims *gojwt.RegisteredClaims) { claims.Audience = gojwt.ClaimStrings{testIssuer} }, ClientAssertionTypeJWTBearer, false},
+		{"other audience", key, func(claims *gojwt.RegisteredClaims) {
+			claims.Audience = gojwt.ClaimStrings{"https://other.example.com"}
+		}, ClientAssertionTypeJWTBearer, true},
+		{"other issuer", key, func(claims *gojwt.RegisteredClaims) { claims.Issuer = "other" }, ClientAssertionTypeJWTBearer, true},
+		{"other subject", key, func(claims *gojwt.RegisteredClaims) { claims.Subject = "other" }, ClientAssertionTypeJWTBearer, true},
+		{"expired", key, func(claims *gojwt.RegisteredClaims) {
+			claims.ExpiresAt = gojwt.NewNumericDate(time.Now().Add(-time.Minute))
+		}, ClientAssertionTypeJWTBearer, true},
+		{"without expiry", key, func(claims *gojwt.RegisteredClaims) { claims.ExpiresAt = nil }, ClientAssertionTypeJWTBearer, true},
+		{"without jti", key, func(claims *gojwt.RegisteredClaims) { claims.ID = "" }, ClientAssertionTypeJWTBearer, true},
+		{"signed with another key", otherKey, func(claims *gojwt.RegisteredClaims) {}, ClientAssertionTypeJWTBearer, true},
+		{"other assertion type", key, func(claims *gojwt.RegisteredClaims) {}, "urn:example:other", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &oauthUseCase{
+				jwtManager:              newTestJWTManager(t),
+				usedClientAssertionRepo: &fakeUsedClientAssertionRepo{used: map[string]bool{}},
+			}
+			claims := validClaims()
+			tt.modify(&claims)
+			credentials := ClientCredentials{
+				ClientAssertionType: tt.assertionType,
+				ClientAssertion:     signClientAssertion(t, tt.key, claims),
+			}
+
+			err := uc.verifyClient(context.Background(), client, credentials)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyClientAssertionReplay(t *testing.T) {
+	key, jwks := newTestClientKey(t)
+	client := &domain.Client{ID: "worker", TokenEndpointAuthMethod: domain.ClientAuthPrivateKeyJWT, JWKS: jwks}
+	uc := &oauthUseCase{
+		jwtManager:              newTestJWTManager(t),
+		usedClientAssertionRepo: &fakeUsedClientAssertionRepo{used: map[string]bool{}},
+	}
+	credentials := ClientCredentials{
+		ClientAssertionType: ClientAssertionTypeJWTBearer,
+		ClientAssertion: signClientAssertion(t, key, gojwt.RegisteredClaims{
+			Issuer:    "worker",
+			Subject:   "worker",
+			Audience:  gojwt.ClaimStrings{testIssuer},
+			ExpiresAt: gojwt.NewNumericDate(time.Now().Add(time.Minute)),
+			ID:        "assertion-1",
+		}),
+	}
+
+	if err := uc.verifyClient(context.Background(), client, credentials); err != nil {
+		t.Fatalf("first use failed: %v", err)
+	}
+	if err := uc.verifyClient(context.Background(), client, credentials); err != domain.ErrInvalidClient {
+		t.Fatalf("expected a replayed assertion to be rejected, got %v", err)
+	}
+}
+
+// newTestCertificate creates a client certificate with the given subject,
+// signed by the parent (or self-signed if parent is nil)
+func newTestCertificate(t *testing.T, subject string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: subject, Organization: []string{"Example"}},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func TestVerifyTLSClientAuth(t *testing.T) {
+	ca, caKey := newTestCertificate(t, "Example CA", true, nil, nil)
+	cert, _ := newTestCertificate(t, "worker", false, ca, caKey)
+	client := &domain.Client{
+		ID:                      "worker",
+		TokenEndpointAuthMethod: domain.ClientAuthTLS,
+		TLSClientAuthSubjectDN:  cert.Subject.String(),
+	}
+	otherClient := &domain.Client{
+		ID:                      "other",
+		TokenEndpointAuthMethod: domain.ClientAuthTLS,
+		TLSClientAuthSubjectDN:  "CN=other,O=Example",
+	}
+
+	tests := []struct {
+		name        string
+		client      *domain.Client
+		credentials ClientCredentials
+		wantErr     bool
+	}{
+		{"verified certificate", client, ClientCredentials{Certificate: cert, CertificateVerified: true}, false},
+		{"unverified certificate", client, ClientCredentials{Certificate: cert}, true},
+		{"other subject", otherClient, ClientCredentials{Certificate: cert, CertificateVerified: true}, true},
+		{"no certificate", client, ClientCredentials{CertificateVerified: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &oauthUseCase{}
+			err := uc.verifyClient(context.Background(), tt.client, tt.credentials)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifySelfSignedTLSClientAuth(t *testing.T) {
+	cert, key := newTestCertificate(t, "worker", false, nil, nil)
+	other, _ := newTestCertificate(t, "worker", false, nil, nil)
+	jwk, err := publicJWKOf(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &domain.Client{
+		ID:                      "worker",
+		TokenEndpointAuthMethod: domain.ClientAuthSelfSignedTLS,
+		JWKS:                    `{"keys":[` + jwk + `]}`,
+	}
+
+	uc := &oauthUseCase{}
+	if err := uc.verifyClient(context.Background(), client, ClientCredentials{Certificate: cert}); err != nil {
+		t.Errorf("expected the registered certificate key to be accepted, got %v", err)
+	}
+	if err := uc.verifyClient(context.Background(), client, ClientCredentials{Certificate: other}); err == nil {
+		t.Error("expected a certificate with another key to be rejected")
+	}
+}
+
+// publicJWKOf returns the JWK of a P-256 public key
+func publicJWKOf(publicKey *ecdsa.PublicKey) (string, error) {
+	ecdhKey, err := publicKey.ECDH()
+	if err != nil {
+		return "", err
+	}
+	point := ecdhKey.Bytes() // 0x04 || x || y
+	encode := base64.RawURLEncoding.EncodeToString
+	return fmt.Sprintf(`{"kty":"EC","crv":"P-256","x":%q,"y":%q}`, encode(point[1:33]), encode(point[33:])), nil
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newWorkerClient registers a confidential client allowed the
+// client_credentials grant
+func newWorkerClient(t *testing.T, test *oauthTest) *domain.Client {
+	t.Helper()
+
+	secretHash, err := bcrypt.GenerateFromPassword([]byte("worker-secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &domain.Client{
+		ID:         "worker",
+		Name:       "Worker",
+		SecretHash: string(secretHash),
+		GrantTypes: []string{GrantTypeClientCredentials},
+		Scopes:     []string{"orders.read"},
+	}
+	test.clients.clients[client.ID] = client
+	return client
+}
+
+func TestExchangeClientCredentials(t *testing.T) {
+	tests := []struct {
+		name      string
+		clientID  string
+		secret    string
+		scope     string
+		wantOAuth string
+	}{
+		{"registered scope", "worker", "worker-secret", "orders.read", ""},
+		{"wrong secret", "worker", "other", "orders.read", ErrorInvalidClient},
+		{"unregistered scope", "worker", "worker-secret", "orders.write", ErrorInvalidScope},
+		{"user scope", "worker", "worker-secret", ScopeOpenID, ErrorInvalidScope},
+		{"client without the grant", "web-app", "", "", ErrorUnauthorizedClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newOAuthTest(t)
+			newWorkerClient(t, test)
+
+			resp, err := test.Token(context.Background(), TokenRequest{
+				GrantType:   GrantTypeClientCredentials,
+				Scope:       tt.scope,
+				Credentials: ClientCredentials{ClientID: tt.clientID, ClientSecret: tt.secret},
+			})
+			if tt.wantOAuth != "" {
+				var oauthErr *OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Code != tt.wantOAuth {
+					t.Fatalf("expected %s, got %v", tt.wantOAuth, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.RefreshToken != "" || resp.User != nil {
+				t.Error("expected an access token only")
+			}
+
+			claims, err := test.jwtManager.ValidateToken(resp.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !claims.IsMachine() || claims.Subject != "worker" || claims.ClientID != "worker" || claims.Scope != tt.scope {
+				t.Errorf("unexpected claims: %+v", claims)
+			}
+		})
+	}
+}
+
+func TestValidateMachineAccessToken(t *testing.T) {
+	test := newOAuthTest(t)
+	client := newWorkerClient(t, test)
+	resp, err := test.Token(context.Background(), TokenRequest{
+		GrantType:   GrantTypeClientCredentials,
+		Credentials: ClientCredentials{ClientID: "worker", ClientSecret: "worker-secret"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := &authUseCase{
+		clientRepo:     test.clients,
+		jwtManager:     test.jwtManager,
+		denylist:       &fakeDenylist{},
+		machineClients: newMachineClientCache(0),
+	}
+	if _, err := auth.ValidateAccessToken(context.Background(), resp.AccessToken); err != nil {
+		t.Fatalf("expected a valid machine token, got %v", err)
+	}
+
+	// Tokens stop being valid once the client loses the grant
+	client.GrantTypes = []string{GrantTypeRefreshToken}
+	if _, err := auth.ValidateAccessToken(context.Background(), resp.AccessToken); err != domain.ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken after losing the grant, got %v", err)
+	}
+
+	delete(test.clients.clients, "worker")
+	if _, err := auth.ValidateAccessToken(context.Background(), resp.AccessToken); err != domain.ErrInvalidToken {
+		t.Fatalf("expected ErrInvalidToken for a deleted client, got %v", err)
+	}
+}
+
+func TestClientCredentialsResource(t *testing.T) {
+	tests := []struct {
+		name         string
+		resource     []string
+		wantAudience []string
+		wantOAuth    string
+	}{
+		{"no resource", nil, []string{testIssuer}, ""},
+		{"registered resource", []string{"https://orders.example.com"}, []string{"https://orders.example.com"}, ""},
+		{"unregistered resource", []string{"https://billing.example.com"}, nil, ErrorInvalidTarget},
+		{"relative resource", []string{"/orders"}, nil, ErrorInvalidTarget},
+		{"resource with fragment", []string{"https://orders.example.com#api"}, nil, ErrorInvalidTarget},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newOAuthTest(t)
+			client := newWorkerClient(t, test)
+			client.Resources = []string{"https://orders.example.com", "/orders", "https://orders.example.com#api"}
+
+			resp, err := test.Token(context.Background(), TokenRequest{
+				GrantType:   GrantTypeClientCredentials,
+				Resource:    tt.resource,
+				Credentials: ClientCredentials{ClientID: "worker", ClientSecret: "worker-secret"},
+			})
+			if tt.wantOAuth != "" {
+				expectOAuthError(t, err, tt.wantOAuth)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			claims, err := test.jwtManager.ValidateToken(resp.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(claims.Audience, tt.wantAudience) {
+				t.Errorf("got audience %v, want %v", claims.Audience, tt.wantAudience)
+			}
+		})
+	}
+}
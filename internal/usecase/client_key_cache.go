@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"auth-service/pkg/jwt"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Settings for fetching the keys of clients registered with a jwks_uri
+const (
+	// clientKeyCacheTTL is how long fetched keys are used before fetching them again
+	clientKeyCacheTTL = 5 * time.Minute
+	// clientKeyRefreshInterval is the minimum time between fetches of a
+	// jwks_uri, so that unknown keys can't make the server fetch on every request
+	clientKeyRefreshInterval = 30 * time.Second
+	// maxClientJWKSSize limits the size of a fetched key set
+	maxClientJWKSSize = 64 << 10
+	// maxClientKeyCacheEntries limits the number of cached key sets
+	maxClientKeyCacheEntries = 1024
+)
+
+// clientKeyCache caches the key sets fetched from the jwks_uri of clients,
+// keyed by client ID so that each client has at most one entry
+type clientKeyCache struct {
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	entries map[string]clientKeyEntry
+}
+
+type clientKeyEntry struct {
+	uri       string
+	keys      *jwt.KeySet
+	fetchedAt time.Time
+}
+
+func newClientKeyCache() *clientKeyCache {
+	return &clientKeyCache{
+		httpClient: newOutboundHTTPClient(5 * time.Second),
+		entries:    make(map[string]clientKeyEntry),
+	}
+}
+
+// get returns the key set published at the jwks_uri of a client. With
+// refresh, keys are fetched again unless they were fetched just now, to pick
+// up rotated keys
+func (c *clientKeyCache) get(ctx context.Context, clientID, uri string, refresh bool) (*jwt.KeySet, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[clientID]
+	c.mu.Unlock()
+
+	age := time.Since(entry.fetchedAt)
+	if ok && entry.uri == uri && age < clientKeyCacheTTL && (!refresh || age < clientKeyRefreshInterval) {
+		return entry.keys, nil
+	}
+
+	keys, err := c.fetch(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if _, ok := c.entries[clientID]; !ok && len(c.entries) >= maxClientKeyCacheEntries {
+		c.evict()
+	}
+	c.entries[clientID] = clientKeyEntry{uri: uri, keys: keys, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return keys, nil
+}
+
+// evict makes room for a new entry by dropping expired entries, or the
+// oldest one if none expired. The caller must hold c.mu
+func (c *clientKeyCache) evict() {
+	var oldestID string
+	var oldest time.Time
+	for clientID, entry := range c.entries {
+		if time.Since(entry.fetchedAt) >= clientKeyCacheTTL {
+			delete(c.entries, clientID)
+		} else if oldestID == "" || entry.fetchedAt.Before(oldest) {
+			oldestID, oldest = clientID, entry.fetchedAt
+		}
+	}
+	if len(c.entries) >= maxClientKeyCacheEntries {
+		delete(c.entries, oldestID)
+	}
+}
+
+func (c *clientKeyCache) fetch(ctx context.Context, uri string) (*jwt.KeySet, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxClientJWKSSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	return jwt.ParseKeySet(data)
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/models"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// registrableGrantTypes lists the grant types clients may register
+// themselves for. Token exchange needs an exchange policy set by an
+// administrator
+var registrableGrantTypes = []string{
+	GrantTypeAuthorizationCode,
+	GrantTypeRefreshToken,
+	GrantTypeClientCredentials,
+	GrantTypeDeviceCode,
+}
+
+func (uc *clientUseCase) RegisterClient(ctx context.Context, req RegistrationRequest) (*RegistrationResponse, error) {
+	client := &domain.Client{
+		ID:                      models.NewNanoID(),
+		TokenEndpointAuthMethod: req.TokenEndpointAuthMethod,
+	}
+	// Clients register as confidential unless they ask otherwise (RFC 7591 section 2)
+	if client.TokenEndpointAuthMethod == "" {
+		client.TokenEndpointAuthMethod = domain.ClientAuthSecretBasic
+	}
+	if err := applyRegistrationRequest(client, req); err != nil {
+		return nil, err
+	}
+
+	var secret string
+	if usesClientSecret(client) {
+		var err error
+		secret, err = randomToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client secret: %w", err)
+		}
+
+		hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash client secret: %w", err)
+		}
+		client.SecretHash = string(hashedSecret)
+	}
+
+	registrationAccessToken, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate registration access token: %w", err)
+	}
+	client.RegistrationAccessTokenHash = hashToken(registrationAccessToken)
+
+	if err := uc.clientRepo.Create(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	resp := uc.newRegistrationResponse(client)
+	resp.ClientSecret = secret
+	resp.RegistrationAccessToken = registrationAccessToken
+	return resp, nil
+}
+
+func (uc *clientUseCase) GetRegistration(ctx context.Context, clientID, registrationAccessToken string) (*RegistrationResponse, error) {
+	client, err := uc.authenticateRegistration(ctx, clientID, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return uc.newRegistrationResponse(client), nil
+}
+
+func (uc *clientUseCase) UpdateRegistration(ctx context.Context, clientID, registrationAccessToken string, req RegistrationRequest) (*RegistrationResponse, error) {
+	client, err := uc.authenticateRegistration(ctx, clientID, registrationAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ClientID != client.ID {
+		return nil, fmt.Errorf("%w: client_id does not match", domain.ErrInvalidClientMetadata)
+	}
+	// Changing the authentication method would need new credentials
+	if req.TokenEndpointAuthMethod != "" && req.TokenEndpointAuthMethod != client.AuthMethod() {
+		return nil, fmt.Errorf("%w: token_endpoint_auth_method can't be changed", domain.ErrInvalidClientMetadata)
+	}
+
+	// Omitted metadata is removed (RFC 7592 section 2.2)
+	if err := applyRegistrationRequest(client, req); err != nil {
+		return nil, err
+	}
+
+	if err := uc.clientRepo.Update(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to update client: %w", err)
+	}
+
+	return uc.newRegistrationResponse(client), nil
+}
+
+func (uc *clientUseCase) DeleteRegistration(ctx context.Context, clientID, registrationAccessToken string) error {
+	if _, err := uc.authenticateRegistration(ctx, clientID, registrationAccessToken); err != nil {
+		return err
+	}
+
+	return uc.DeleteClient(ctx, clientID)
+}
+
+// authenticateRegistration finds a dynamically registered client by its
+// registration access token. Unknown clients fail like invalid tokens, so
+// client IDs can't be probed (RFC 7592 section 2)
+func (uc *clientUseCase) authenticateRegistration(ctx context.Context, clientID, registrationAccessToken string) (*domain.Client, error) {
+	client, err := uc.clientRepo.FindByID(ctx, clientID)
+	if err != nil {
+		if err == domain.ErrClientNotFound {
+			return nil, domain.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if !client.IsDynamicallyRegistered() || registrationAccessToken == "" {
+		return nil, domain.ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(hashToken(registrationAccessToken)), []byte(client.RegistrationAccessTokenHash)) != 1 {
+		return nil, domain.ErrInvalidToken
+	}
+
+	return client, nil
+}
+
+// applyRegistrationRequest copies registered metadata to a client and
+// validates it against the registration policy
+func applyRegistrationRequest(client *domain.Client, req RegistrationRequest) error {
+	for _, responseType := range req.ResponseTypes {
+		if responseType != ResponseTypeCode {
+			return fmt.Errorf("%w: unsupported response type %q", domain.ErrInvalidClientMetadata, responseType)
+		}
+	}
+
+	client.Name = req.ClientName
+	if client.Name == "" {
+		client.Name = client.ID
+	}
+	client.RedirectURIs = req.RedirectURIs
+	client.PostLogoutRedirectURIs = req.PostLogoutRedirectURIs
+	client.BackchannelLogoutURI = req.BackchannelLogoutURI
+	client.FrontchannelLogoutURI = req.FrontchannelLogoutURI
+	client.GrantTypes = req.GrantTypes
+	if len(client.GrantTypes) == 0 {
+		client.GrantTypes = []string{GrantTypeAuthorizationCode}
+	}
+	client.Scopes = strings.Fields(req.Scope)
+	client.JWKS = string(req.JWKS)
+	client.JWKSURI = req.JWKSURI
+	client.TLSClientAuthSubjectDN = req.TLSClientAuthSubjectDN
+	client.RequirePushedAuthorizationRequests = req.RequirePushedAuthorizationRequests
+
+	if err := validateClient(client); err != nil {
+		return err
+	}
+	return validateRegisteredClient(client)
+}
+
+// validateRegisteredClient checks the metadata of a dynamically registered
+// client against what clients may register without an administrator
+func validateRegisteredClient(client *domain.Client) error {
+	for _, grantType := range client.GrantTypes {
+		if !slices.Contains(registrableGrantTypes, grantType) {
+			return fmt.Errorf("%w: grant type %q can't be registered dynamically", domain.ErrInvalidClientMetadata, grantType)
+		}
+	}
+
+	// API scopes grant access to resource servers, which an administrator
+	// has to allow for the client
+	for _, scope := range client.Scopes {
+		if isAPIScope(scope) {
+			return fmt.Errorf("%w: scope %q can't be registered dynamically", domain.ErrInvalidClientMetadata, scope)
+		}
+	}
+
+	// Web apps must use https; native apps may use loopback addresses or
+	// private-use schemes based on a domain they own (RFC 8252 section 7)
+	for _, redirectURI := range append(client.RedirectURIs, client.PostLogoutRedirectURIs...) {
+		u, _ := url.Parse(redirectURI)
+		switch {
+		case u.Scheme == "https":
+		case u.Scheme == "http" && isLoopbackHost(u.Hostname()):
+		case u.Scheme != "http" && strings.Contains(u.Scheme, "."):
+		default:
+			return fmt.Errorf("%w %q: use https, a loopback address or a private-use scheme", domain.ErrInvalidRedirectURI, redirectURI)
+		}
+	}
+
+	return nil
+}
+
+// isLoopbackHost checks if a host name refers to the local machine
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func (uc *clientUseCase) newRegistrationResponse(client *domain.Client) *RegistrationResponse {
+	return &RegistrationResponse{
+		ClientID:                           client.ID,
+		ClientIDIssuedAt:                   client.CreatedAt.Unix(),
+		RegistrationClientURI:              uc.jwtManager.GetIssuer() + "/oauth/register/" + client.ID,
+		ClientName:                         client.Name,
+		RedirectURIs:                       client.RedirectURIs,
+		PostLogoutRedirectURIs:             client.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:               client.BackchannelLogoutURI,
+		FrontchannelLogoutURI:              client.FrontchannelLogoutURI,
+		GrantTypes:                         client.GrantTypes,
+		ResponseTypes:                      responseTypesOf(client),
+		Scope:                              strings.Join(client.Scopes, " "),
+		TokenEndpointAuthMethod:            client.AuthMethod(),
+		JWKSURI:                            client.JWKSURI,
+		JWKS:                               []byte(client.JWKS),
+		TLSClientAuthSubjectDN:             client.TLSClientAuthSubjectDN,
+		RequirePushedAuthorizationRequests: client.RequirePushedAuthorizationRequests,
+	}
+}
+
+// responseTypesOf returns the response types a client can use
+func responseTypesOf(client *domain.Client) []string {
+	if client.AllowsGrantType(GrantTypeAuthorizationCode) {
+		return []string{ResponseTypeCode}
+	}
+	return []string{}
+}
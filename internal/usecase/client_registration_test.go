@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateRegisteredClient(t *testing.T) {
+	valid := domain.Client{
+		Name:         "Photo Printer",
+		RedirectURIs: []string{"https://printer.example.com/cb", "http://127.0.0.1:8080/cb", "com.example.printer:/cb"},
+		GrantTypes:   []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		Scopes:       []string{ScopeOpenID, ScopeProfile},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(client *domain.Client)
+		wantErr error
+	}{
+		{"valid client", func(client *domain.Client) {}, nil},
+		{"token exchange", func(client *domain.Client) { client.GrantTypes = []string{GrantTypeTokenExchange} }, domain.ErrInvalidClientMetadata},
+		{"API scope", func(client *domain.Client) { client.Scopes = []string{"photos.read"} }, domain.ErrInvalidClientMetadata},
+		{"plain http redirect URI", func(client *domain.Client) { client.RedirectURIs = []string{"http://printer.example.com/cb"} }, domain.ErrInvalidRedirectURI},
+		{"scheme without domain", func(client *domain.Client) { client.RedirectURIs = []string{"printer:/cb"} }, domain.ErrInvalidRedirectURI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := valid
+			tt.modify(&client)
+
+			if err := validateRegisteredClient(&client); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validateRegisteredClient() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistrationLifecycle(t *testing.T) {
+	clients := &fakeClientRepo{clients: map[string]*domain.Client{}}
+	uc := NewClientUseCase(clients, &fakeRefreshTokenRepo{}, nil, newTestJWTManager(t))
+	ctx := context.Background()
+
+	registered, err := uc.RegisterClient(ctx, RegistrationRequest{
+		ClientName:   "Photo Printer",
+		RedirectURIs: []string{"https://printer.example.com/cb"},
+		Scope:        "openid profile",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registered.ClientSecret == "" || registered.RegistrationAccessToken == "" {
+		t.Fatalf("expected a client secret and registration access token, got %+v", registered)
+	}
+	if registered.RegistrationClientURI != testIssuer+"/oauth/register/"+registered.ClientID {
+		t.Errorf("unexpected registration client URI %q", registered.RegistrationClientURI)
+	}
+	if clients.clients[registered.ClientID].FirstParty {
+		t.Error("expected a registered client to be third-party")
+	}
+
+	if _, err := uc.GetRegistration(ctx, registered.ClientID, "wrong-token"); err != domain.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for a wrong token, got %v", err)
+	}
+	if _, err := uc.GetRegistration(ctx, "unknown", registered.RegistrationAccessToken); err != domain.ErrInvalidToken {
+		t.Errorf("expected ErrInvalidToken for an unknown client, got %v", err)
+	}
+
+	// Updates replace all metadata
+	updated, err := uc.UpdateRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken, RegistrationRequest{
+		ClientID:     registered.ClientID,
+		ClientName:   "Photo Printer 2",
+		RedirectURIs: []string{"https://printer.example.com/cb2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ClientName != "Photo Printer 2" || updated.Scope != "" || updated.ClientSecret != "" {
+		t.Errorf("unexpected update response %+v", updated)
+	}
+
+	_, err = uc.UpdateRegistration(ctx, registered.ClientID, registered.RegistrationAccessToken, RegistrationRequest{
+		ClientID:                registered.ClientID,
+		RedirectURIs:            []string{"https://printer.example.com/cb"},
+		TokenEndpointAuthMethod: domain.ClientAuthNone,
+	})
+	if !errors.Is(err, domain.ErrInvalidClientMetadata) {
+		t.Errorf("expected the auth method to be fixed, got %v", err)
+	}
+}
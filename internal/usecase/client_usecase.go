@@ -0,0 +1,441 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/pkg/jwt"
+	"auth-service/pkg/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ClientUseCase defines the interface for OAuth client management use cases
+type ClientUseCase interface {
+	CreateClient(ctx context.Context, req ClientRequest) (*ClientResponse, error)
+	ListClients(ctx context.Context) ([]ClientResponse, error)
+	GetClient(ctx context.Context, clientID string) (*ClientResponse, error)
+	UpdateClient(ctx context.Context, clientID string, req ClientRequest) (*ClientResponse, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	// ListLogoutDeliveries lists the recent back-channel logout notifications of a client
+	ListLogoutDeliveries(ctx context.Context, clientID string) ([]LogoutDeliveryResponse, error)
+	// RegisterClient registers a client requested through dynamic client
+	// registration (RFC 7591)
+	RegisterClient(ctx context.Context, req RegistrationRequest) (*RegistrationResponse, error)
+	// GetRegistration, UpdateRegistration and DeleteRegistration manage a
+	// dynamically registered client (RFC 7592), returning domain.ErrInvalidToken
+	// if the registration access token is invalid
+	GetRegistration(ctx context.Context, clientID, registrationAccessToken string) (*RegistrationResponse, error)
+	UpdateRegistration(ctx context.Context, clientID, registrationAccessToken string, req RegistrationRequest) (*RegistrationResponse, error)
+	DeleteRegistration(ctx context.Context, clientID, registrationAccessToken string) error
+	// IsOriginAllowed checks if any client allows browsers to call the
+	// service from an origin (CORS)
+	IsOriginAllowed(origin string) bool
+}
+
+// logoutDeliveryListLimit is how many logout deliveries are listed per client
+const logoutDeliveryListLimit = 100
+
+// allowedOriginsCacheTTL is how long the allowed CORS origins are cached
+const allowedOriginsCacheTTL = time.Minute
+
+type clientUseCase struct {
+	clientRepo       repository.ClientRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	jwtManager       *jwt.JWTManager
+
+	logoutDeliveryRepo repository.LogoutDeliveryRepository
+
+	mu              sync.Mutex
+	allowedOrigins  map[string]bool
+	originsLoadedAt time.Time
+}
+
+// NewClientUseCase creates a new client use case
+func NewClientUseCase(
+	clientRepo repository.ClientRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	logoutDeliveryRepo repository.LogoutDeliveryRepository,
+	jwtManager *jwt.JWTManager,
+) ClientUseCase {
+	return &clientUseCase{
+		clientRepo:         clientRepo,
+		refreshTokenRepo:   refreshTokenRepo,
+		jwtManager:         jwtManager,
+		logoutDeliveryRepo: logoutDeliveryRepo,
+	}
+}
+
+func (uc *clientUseCase) CreateClient(ctx context.Context, req ClientRequest) (*ClientResponse, error) {
+	client := &domain.Client{
+		ID:                      models.NewNanoID(),
+		TokenEndpointAuthMethod: req.TokenEndpointAuthMethod,
+	}
+	if client.TokenEndpointAuthMethod == "" {
+		client.TokenEndpointAuthMethod = domain.ClientAuthSecretBasic
+		if req.Public {
+			client.TokenEndpointAuthMethod = domain.ClientAuthNone
+		}
+	} else if req.Public && client.TokenEndpointAuthMethod != domain.ClientAuthNone {
+		return nil, fmt.Errorf("%w: public clients can't authenticate", domain.ErrInvalidClientMetadata)
+	}
+	applyClientRequest(client, req)
+
+	// Clients authenticating with a secret get one, which is only shown once
+	var secret string
+	if usesClientSecret(client) {
+		var err error
+		secret, err = randomToken()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client secret: %w", err)
+		}
+
+		hashedSecret, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash client secret: %w", err)
+		}
+		client.SecretHash = string(hashedSecret)
+	}
+
+	if err := validateClient(client); err != nil {
+		return nil, err
+	}
+
+	if err := uc.clientRepo.Create(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+	uc.invalidateAllowedOrigins()
+
+	resp := newClientResponse(client)
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+func (uc *clientUseCase) ListClients(ctx context.Context) ([]ClientResponse, error) {
+	clients, err := uc.clientRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]ClientResponse, 0, len(clients))
+	for _, client := range clients {
+		resp = append(resp, *newClientResponse(client))
+	}
+	return resp, nil
+}
+
+func (uc *clientUseCase) GetClient(ctx context.Context, clientID string) (*ClientResponse, error) {
+	client, err := uc.clientRepo.FindByID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClientResponse(client), nil
+}
+
+func (uc *clientUseCase) UpdateClient(ctx context.Context, clientID string, req ClientRequest) (*ClientResponse, error) {
+	client, err := uc.clientRepo.FindByID(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyClientRequest(client, req)
+	if err := validateClient(client); err != nil {
+		return nil, err
+	}
+
+	if err := uc.clientRepo.Update(ctx, client); err != nil {
+		return nil, fmt.Errorf("failed to update client: %w", err)
+	}
+	uc.invalidateAllowedOrigins()
+
+	return newClientResponse(client), nil
+}
+
+func (uc *clientUseCase) DeleteClient(ctx context.Context, clientID string) error {
+	if _, err := uc.clientRepo.FindByID(ctx, clientID); err != nil {
+		return err
+	}
+
+	// Sessions of the client end with it
+	if err := uc.refreshTokenRepo.RevokeAllByClientID(ctx, clientID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+
+	if err := uc.clientRepo.Delete(ctx, clientID); err != nil {
+		return fmt.Errorf("failed to delete client: %w", err)
+	}
+	uc.invalidateAllowedOrigins()
+
+	return nil
+}
+
+func (uc *clientUseCase) ListLogoutDeliveries(ctx context.Context, clientID string) ([]LogoutDeliveryResponse, error) {
+	if _, err := uc.clientRepo.FindByID(ctx, clientID); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := uc.logoutDeliveryRepo.ListByClientID(ctx, clientID, logoutDeliveryListLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]LogoutDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		response := LogoutDeliveryResponse{
+			ID:          delivery.ID,
+			UserID:      delivery.UserID,
+			SessionID:   delivery.SessionID,
+			Status:      delivery.Status,
+			Attempts:    delivery.Attempts,
+			LastError:   delivery.LastError,
+			DeliveredAt: delivery.DeliveredAt,
+			CreatedAt:   delivery.CreatedAt,
+		}
+		if delivery.Status == domain.LogoutDeliveryPending {
+			nextAttemptAt := delivery.NextAttemptAt
+			response.NextAttemptAt = &nextAttemptAt
+		}
+		responses = append(responses, response)
+	}
+
+	return responses, nil
+}
+
+func (uc *clientUseCase) IsOriginAllowed(origin string) bool {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if time.Since(uc.originsLoadedAt) > allowedOriginsCacheTTL {
+		if err := uc.loadAllowedOrigins(); err != nil {
+			// Keep serving the previous origins until the database is back
+			log.Printf("failed to load allowed origins: %v", err)
+		}
+	}
+
+	return uc.allowedOrigins[origin]
+}
+
+// loadAllowedOrigins reloads the allowed origins of all clients
+// The caller must hold uc.mu
+func (uc *clientUseCase) loadAllowedOrigins() error {
+	uc.originsLoadedAt = time.Now()
+
+	clients, err := uc.clientRepo.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	origins := make(map[string]bool)
+	for _, client := range clients {
+		for _, origin := range client.AllowedOrigins {
+			origins[origin] = true
+		}
+	}
+	uc.allowedOrigins = origins
+
+	return nil
+}
+
+// invalidateAllowedOrigins makes the next CORS check reload the origins
+// Other instances pick up changes after allowedOriginsCacheTTL
+func (uc *clientUseCase) invalidateAllowedOrigins() {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	uc.originsLoadedAt = time.Time{}
+}
+
+// findClient looks up a registered client, reporting unknown clients as
+// domain.ErrInvalidClient
+func findClient(ctx context.Context, clientRepo repository.ClientRepository, clientID string) (*domain.Client, error) {
+	if clientID == "" {
+		return nil, domain.ErrInvalidClient
+	}
+
+	client, err := clientRepo.FindByID(ctx, clientID)
+	if err != nil {
+		if err == domain.ErrClientNotFound {
+			return nil, domain.ErrInvalidClient
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// applyClientRequest copies the settings of a request to a client
+func applyClientRequest(client *domain.Client, req ClientRequest) {
+	client.Name = req.Name
+	client.FirstParty = req.FirstParty
+	client.RedirectURIs = req.RedirectURIs
+	client.PostLogoutRedirectURIs = req.PostLogoutRedirectURIs
+	client.BackchannelLogoutURI = req.BackchannelLogoutURI
+	client.FrontchannelLogoutURI = req.FrontchannelLogoutURI
+	client.GrantTypes = req.GrantTypes
+	client.Scopes = req.Scopes
+	client.AllowedOrigins = req.AllowedOrigins
+	client.Resources = req.Resources
+	client.TokenExchangeAudiences = req.TokenExchangeAudiences
+	client.TokenExchangeSubjectClients = req.TokenExchangeSubjectClients
+	client.TokenExchangeSubjectAudiences = req.TokenExchangeSubjectAudiences
+	client.RequirePushedAuthorizationRequests = req.RequirePushedAuthorizationRequests
+	client.AccessTokenLifetime = req.AccessTokenLifetime
+	client.RefreshTokenLifetime = req.RefreshTokenLifetime
+	client.JWKS = string(req.JWKS)
+	client.JWKSURI = req.JWKSURI
+	client.TLSClientAuthSubjectDN = req.TLSClientAuthSubjectDN
+}
+
+// usesClientSecret checks if a client authenticates with a secret
+func usesClientSecret(client *domain.Client) bool {
+	method := client.AuthMethod()
+	return method == domain.ClientAuthSecretBasic || method == domain.ClientAuthSecretPost
+}
+
+// validateClient checks client settings against what the server supports
+func validateClient(client *domain.Client) error {
+	if client.Name == "" {
+		return fmt.Errorf("%w: name is required", domain.ErrInvalidClientMetadata)
+	}
+
+	for _, grantType := range client.GrantTypes {
+		if !slices.Contains(supportedGrantTypes, grantType) {
+			return fmt.Errorf("%w: unsupported grant type %q", domain.ErrInvalidClientMetadata, grantType)
+		}
+	}
+	if client.AllowsGrantType(GrantTypeAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		return fmt.Errorf("%w: the authorization_code grant requires a redirect URI", domain.ErrInvalidClientMetadata)
+	}
+	if client.AllowsGrantType(GrantTypeClientCredentials) && !client.IsConfidential() {
+		return fmt.Errorf("%w: the client_credentials grant requires a confidential client", domain.ErrInvalidClientMetadata)
+	}
+	if client.AllowsGrantType(GrantTypeTokenExchange) {
+		if !client.IsConfidential() {
+			return fmt.Errorf("%w: the token exchange grant requires a confidential client", domain.ErrInvalidClientMetadata)
+		}
+		if len(client.TokenExchangeAudiences) == 0 {
+			return fmt.Errorf("%w: the token exchange grant requires token_exchange_audiences", domain.ErrInvalidClientMetadata)
+		}
+	}
+
+	method := client.AuthMethod()
+	if method != domain.ClientAuthNone && !slices.Contains(clientAuthMethods, method) {
+		return fmt.Errorf("%w: unsupported token endpoint auth method %q", domain.ErrInvalidClientMetadata, method)
+	}
+	if client.JWKS != "" && client.JWKSURI != "" {
+		return fmt.Errorf("%w: jwks and jwks_uri are mutually exclusive", domain.ErrInvalidClientMetadata)
+	}
+	if client.JWKS != "" {
+		if _, err := jwt.ParseKeySet([]byte(client.JWKS)); err != nil {
+			return fmt.Errorf("%w: %v", domain.ErrInvalidClientMetadata, err)
+		}
+	} else if client.JWKSURI != "" {
+		if err := validateOutboundURL(client.JWKSURI); err != nil {
+			return fmt.Errorf("%w: jwks_uri %v", domain.ErrInvalidClientMetadata, err)
+		}
+	} else if method == domain.ClientAuthPrivateKeyJWT || method == domain.ClientAuthSelfSignedTLS {
+		return fmt.Errorf("%w: %s requires jwks or jwks_uri", domain.ErrInvalidClientMetadata, method)
+	}
+	if method == domain.ClientAuthTLS && client.TLSClientAuthSubjectDN == "" {
+		return fmt.Errorf("%w: %s requires tls_client_auth_subject_dn", domain.ErrInvalidClientMetadata, method)
+	}
+
+	for _, scope := range client.Scopes {
+		if slices.Contains(supportedScopes, scope) {
+			continue
+		}
+		if !isAPIScope(scope) {
+			return fmt.Errorf("%w: invalid scope %q", domain.ErrInvalidClientMetadata, scope)
+		}
+		if !client.AllowsGrantType(GrantTypeClientCredentials) {
+			return fmt.Errorf("%w: scope %q is only granted through the client_credentials grant", domain.ErrInvalidClientMetadata, scope)
+		}
+	}
+	if client.AllowsScope(ScopeAccount) && !client.FirstParty {
+		return fmt.Errorf("%w: the %s scope is reserved for first-party clients", domain.ErrInvalidClientMetadata, ScopeAccount)
+	}
+
+	for _, redirectURI := range append(client.RedirectURIs, client.PostLogoutRedirectURIs...) {
+		// Absolute URIs, including custom schemes of mobile apps, without fragment
+		u, err := url.Parse(redirectURI)
+		if err != nil || u.Scheme == "" || u.Fragment != "" {
+			return fmt.Errorf("%w %q", domain.ErrInvalidRedirectURI, redirectURI)
+		}
+	}
+
+	// Logout URIs are called by the server or loaded in a frame, so they
+	// must be https URLs (Back-Channel Logout and Front-Channel Logout section 2.2)
+	for name, logoutURI := range map[string]string{
+		"backchannel_logout_uri":  client.BackchannelLogoutURI,
+		"frontchannel_logout_uri": client.FrontchannelLogoutURI,
+	} {
+		if logoutURI == "" {
+			continue
+		}
+		if u, err := url.Parse(logoutURI); err != nil || u.Scheme != "https" || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("%w: %s must be an https URL", domain.ErrInvalidClientMetadata, name)
+		}
+	}
+	// The server posts to the back-channel logout URI itself, browsers load the other
+	if client.BackchannelLogoutURI != "" {
+		if err := validateOutboundURL(client.BackchannelLogoutURI); err != nil {
+			return fmt.Errorf("%w: backchannel_logout_uri %v", domain.ErrInvalidClientMetadata, err)
+		}
+	}
+
+	for _, resource := range client.Resources {
+		if !isResourceIndicator(resource) {
+			return fmt.Errorf("%w: invalid resource %q", domain.ErrInvalidClientMetadata, resource)
+		}
+	}
+
+	for _, origin := range client.AllowedOrigins {
+		// Origins are scheme://host[:port] without path
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || origin != u.Scheme+"://"+u.Host {
+			return fmt.Errorf("%w: invalid origin %q", domain.ErrInvalidClientMetadata, origin)
+		}
+	}
+
+	if client.AccessTokenLifetime < 0 || client.RefreshTokenLifetime < 0 {
+		return fmt.Errorf("%w: token lifetimes must not be negative", domain.ErrInvalidClientMetadata)
+	}
+
+	return nil
+}
+
+func newClientResponse(client *domain.Client) *ClientResponse {
+	return &ClientResponse{
+		ClientID:                           client.ID,
+		Name:                               client.Name,
+		FirstParty:                         client.FirstParty,
+		Public:                             !client.IsConfidential(),
+		TokenEndpointAuthMethod:            client.AuthMethod(),
+		JWKS:                               json.RawMessage(client.JWKS),
+		JWKSURI:                            client.JWKSURI,
+		TLSClientAuthSubjectDN:             client.TLSClientAuthSubjectDN,
+		RedirectURIs:                       client.RedirectURIs,
+		PostLogoutRedirectURIs:             client.PostLogoutRedirectURIs,
+		BackchannelLogoutURI:               client.BackchannelLogoutURI,
+		FrontchannelLogoutURI:              client.FrontchannelLogoutURI,
+		GrantTypes:                         client.GrantTypes,
+		Scopes:                             client.Scopes,
+		AllowedOrigins:                     client.AllowedOrigins,
+		Resources:                          client.Resources,
+		TokenExchangeAudiences:             client.TokenExchangeAudiences,
+		TokenExchangeSubjectClients:        client.TokenExchangeSubjectClients,
+		TokenExchangeSubjectAudiences:      client.TokenExchangeSubjectAudiences,
+		RequirePushedAuthorizationRequests: client.RequirePushedAuthorizationRequests,
+		AccessTokenLifetime:                client.AccessTokenLifetime,
+		RefreshTokenLifetime:               client.RefreshTokenLifetime,
+		CreatedAt:                          client.CreatedAt,
+		UpdatedAt:                          client.UpdatedAt,
+	}
+}
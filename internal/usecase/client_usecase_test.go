@@ -0,0 +1,78 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestValidateClient(t *testing.T) {
+	valid := domain.Client{
+		Name:           "Web App",
+		RedirectURIs:   []string{"https://app.example.com/callback", "com.example.app:/callback"},
+		GrantTypes:     []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		Scopes:         []string{ScopeOpenID, ScopeEmail},
+		AllowedOrigins: []string{"https://app.example.com", "http://localhost:3000"},
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(client *domain.Client)
+		wantErr bool
+	}{
+		{"valid client", func(client *domain.Client) {}, false},
+		{"missing name", func(client *domain.Client) { client.Name = "" }, true},
+		{"unsupported grant type", func(client *domain.Client) { client.GrantTypes = []string{"password"} }, true},
+		{"code grant without redirect URI", func(client *domain.Client) { client.RedirectURIs = nil }, true},
+		{"unsupported scope", func(client *domain.Client) { client.Scopes = []string{"admin"} }, true},
+		{"relative redirect URI", func(client *domain.Client) { client.RedirectURIs = []string{"/callback"} }, true},
+		{"redirect URI with fragment", func(client *domain.Client) { client.RedirectURIs = []string{"https://app.example.com/#cb"} }, true},
+		{"origin with path", func(client *domain.Client) { client.AllowedOrigins = []string{"https://app.example.com/"} }, true},
+		{"wildcard origin", func(client *domain.Client) { client.AllowedOrigins = []string{"*"} }, true},
+		{"negative lifetime", func(client *domain.Client) { client.AccessTokenLifetime = -1 }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := valid
+			tt.modify(&client)
+
+			err := validateClient(&client)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("validateClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, domain.ErrInvalidClientMetadata) && !errors.Is(err, domain.ErrInvalidRedirectURI) {
+				t.Errorf("expected ErrInvalidClientMetadata or ErrInvalidRedirectURI, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIsOriginAllowed(t *testing.T) {
+	clientRepo := &fakeClientRepo{clients: map[string]*domain.Client{
+		"web-app": {ID: "web-app", Name: "Web App", AllowedOrigins: []string{"https://app.example.com"}},
+	}}
+	uc := NewClientUseCase(clientRepo, nil, nil, nil)
+
+	if !uc.IsOriginAllowed("https://app.example.com") {
+		t.Error("expected the registered origin to be allowed")
+	}
+	if uc.IsOriginAllowed("https://evil.example.com") {
+		t.Error("expected an unregistered origin to be rejected")
+	}
+
+	// Changing a client reloads the origins right away
+	_, err := uc.UpdateClient(context.Background(), "web-app", ClientRequest{
+		Name:           "Web App",
+		AllowedOrigins: []string{"https://new.example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uc.IsOriginAllowed("https://app.example.com") {
+		t.Error("expected the removed origin to be rejected")
+	}
+	if !uc.IsOriginAllowed("https://new.example.com") {
+		t.Error("expected the added origin to be allowed")
+	}
+}
@@ -0,0 +1,128 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"testing"
+	"time"
+)
+
+// newConsentTest creates an oauthTest with a code of web-app awaiting the
+// user's consent to profile, issued for testCode as consent challenge
+func newConsentTest(t *testing.T) *oauthTest {
+	t.Helper()
+
+	test := newCodeExchangeTest(t)
+	code := test.codes.codes[hashToken(testCode)]
+	code.Scope = ScopeProfile
+	code.State = "client-state"
+	code.ConsentPending = true
+	return test
+}
+
+func TestDecideConsent(t *testing.T) {
+	for _, approve := range []bool{true, false} {
+		test := newConsentTest(t)
+		ctx := context.Background()
+
+		resp, err := test.DecideConsent(ctx, ConsentDecisionRequest{ConsentChallenge: testCode, Approve: approve})
+		if err != nil {
+			t.Fatalf("approve=%v: %v", approve, err)
+		}
+		if resp.State != "client-state" || resp.RedirectURI != testRedirectURI {
+			t.Errorf("approve=%v: expected the state and redirect URI of the request, got %+v", approve, resp)
+		}
+		if (resp.Code == testCode) != approve {
+			t.Errorf("approve=%v: got code %q", approve, resp.Code)
+		}
+		if _, err := test.consents.Find(ctx, "user-1", "web-app"); (err == nil) != approve {
+			t.Errorf("approve=%v: got consent error %v", approve, err)
+		}
+
+		// The challenge can't be decided on twice
+		_, err = test.DecideConsent(ctx, ConsentDecisionRequest{ConsentChallenge: testCode, Approve: true})
+		if err != domain.ErrAuthCodeNotFound {
+			t.Errorf("approve=%v: expected the challenge to be used up, got %v", approve, err)
+		}
+	}
+}
+
+func TestExchangeAuthorizationCodeAwaitingConsent(t *testing.T) {
+	test := newConsentTest(t)
+	client := &domain.Client{ID: "web-app"}
+	req := TokenRequest{Code: testCode, CodeVerifier: testCodeVerifier, RedirectURI: testRedirectURI}
+
+	_, err := test.exchangeAuthorizationCode(context.Background(), client, req)
+	expectOAuthError(t, err, ErrorInvalidGrant)
+}
+
+func TestGrantConsentMergesScopes(t *testing.T) {
+	test := newOAuthTest(t)
+	ctx := context.Background()
+
+	for _, scope := range []string{"openid profile", "profile email"} {
+		if err := test.grantConsent(ctx, "user-1", "web-app", scope); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	client := test.clients.clients["web-app"]
+	for scope, want := range map[string]bool{"openid email": false, "openid profile email": false, "account": true} {
+		required, err := test.requiresConsent(ctx, client, "user-1", scope)
+		if err != nil || required != want {
+			t.Errorf("scope %q: got %v (%v), want %v", scope, required, err, want)
+		}
+	}
+
+	client.FirstParty = true
+	if required, _ := test.requiresConsent(ctx, client, "user-1", "account"); required {
+		t.Error("expected first-party clients to skip consent")
+	}
+}
+
+func TestRevokeConsentRevokesAccessTokens(t *testing.T) {
+	expiresAt := func(d time.Duration) *time.Time {
+		at := time.Now().Add(d)
+		return &at
+	}
+	tokens := &fakeRefreshTokenRepo{tokens: []*domain.RefreshToken{
+		// Rotated token whose access token is still valid
+		{UserID: "user-1", ClientID: "photo-printer", IsRevoked: true, AccessTokenID: "rotated", AccessTokenExpiresAt: expiresAt(time.Minute)},
+		{UserID: "user-1", ClientID: "photo-printer", AccessTokenID: "current", AccessTokenExpiresAt: expiresAt(10 * time.Minute)},
+		{UserID: "user-1", ClientID: "photo-printer", AccessTokenID: "expired", AccessTokenExpiresAt: expiresAt(-time.Minute)},
+		{UserID: "user-1", ClientID: "other-app", AccessTokenID: "other", AccessTokenExpiresAt: expiresAt(10 * time.Minute)},
+	}}
+	consents := &fakeConsentRepo{consents: map[string]*domain.Consent{
+		"user-1 photo-printer": {UserID: "user-1", ClientID: "photo-printer", Scope: ScopeProfile},
+	}}
+	denylist := &fakeDenylist{}
+	notifier := &fakeLogoutNotifier{}
+	uc := &authUseCase{refreshTokenRepo: tokens, consentRepo: consents, denylist: denylist, logoutNotifier: notifier}
+
+	if err := uc.RevokeConsent(context.Background(), "user-1", "photo-printer"); err != nil {
+		t.Fatal(err)
+	}
+	for jti, want := range map[string]bool{"rotated": true, "current": true, "expired": false, "other": false} {
+		if denylist.revoked[jti] != want {
+			t.Errorf("access token %s: revoked %v, want %v", jti, denylist.revoked[jti], want)
+		}
+	}
+	if !tokens.tokens[1].IsRevoked || tokens.tokens[3].IsRevoked {
+		t.Error("expected only the client's refresh tokens to be revoked")
+	}
+	if len(notifier.sessions) == 0 {
+		t.Error("expected the client to be notified of its ended sessions")
+	}
+	for _, session := range notifier.sessions {
+		if session.ClientID != "photo-printer" {
+			t.Errorf("expected only photo-printer to be notified, got %s", session.ClientID)
+		}
+	}
+	if len(consents.consents) != 0 {
+		t.Error("expected the consent to be deleted")
+	}
+
+	if err := uc.RevokeConsent(context.Background(), "user-1", "photo-printer"); err != domain.ErrConsentNotFound {
+		t.Errorf("expected ErrConsentNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Device authorization settings (RFC 8628)
+const (
+	deviceCodeTTL = 10 * time.Minute
+	// devicePollInterval is the initial minimum polling interval in seconds
+	devicePollInterval = 5
+	// deviceSlowDownIncrement is added to the interval of devices polling too fast
+	deviceSlowDownIncrement = 5
+)
+
+// userCodeAlphabet has no vowels, to avoid forming words, and no characters
+// that are easily confused (RFC 8628 section 6.1)
+const userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+// userCodeLength is the number of characters of a user code, shown as XXXX-XXXX
+const userCodeLength = 8
+
+// A user may enter userCodeMaxAttempts unknown user codes within
+// userCodeAttemptWindow before further codes are refused
+const (
+	userCodeMaxAttempts   = 5
+	userCodeAttemptWindow = 15 * time.Minute
+)
+
+func (uc *oauthUseCase) DeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequest) (*DeviceAuthorizationResponse, error) {
+	// Clients authenticate like at the token endpoint
+	client, err := uc.authenticateTokenClient(ctx, req.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	if !client.AllowsGrantType(GrantTypeDeviceCode) {
+		return nil, NewOAuthError(ErrorUnauthorizedClient, "client is not allowed to use the device flow")
+	}
+
+	scope, err := normalizeScope(req.Scope)
+	if err != nil || !clientAllowsScope(client, scope) {
+		return nil, NewOAuthError(ErrorInvalidScope, "scope not allowed for this client")
+	}
+
+	deviceCode, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate device code: %w", err)
+	}
+	userCode, err := randomUserCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate user code: %w", err)
+	}
+
+	code := &domain.DeviceCode{
+		DeviceCodeHash: hashToken(deviceCode),
+		UserCode:       userCode,
+		ClientID:       client.ID,
+		Scope:          scope,
+		Status:         domain.DeviceCodePending,
+		PollInterval:   devicePollInterval,
+		ExpiresAt:      time.Now().Add(deviceCodeTTL),
+	}
+	if err := uc.deviceCodeRepo.Create(ctx, code); err != nil {
+		return nil, fmt.Errorf("failed to save device code: %w", err)
+	}
+
+	displayCode := formatUserCode(userCode)
+	return &DeviceAuthorizationResponse{
+		DeviceCode:              deviceCode,
+		UserCode:                displayCode,
+		VerificationURI:         uc.deviceVerificationURI,
+		VerificationURIComplete: uc.deviceVerificationURI + "?user_code=" + url.QueryEscape(displayCode),
+		ExpiresIn:               int(deviceCodeTTL.Seconds()),
+		Interval:                devicePollInterval,
+	}, nil
+}
+
+func (uc *oauthUseCase) DeviceVerification(ctx context.Context, userID, userCode string) (*DeviceVerificationResponse, error) {
+	code, err := uc.findPendingDeviceCode(ctx, userID, userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := findClient(ctx, uc.clientRepo, code.ClientID)
+	if err != nil {
+		if err == domain.ErrInvalidClient {
+			return nil, domain.ErrDeviceCodeNotFound
+		}
+		return nil, err
+	}
+
+	return &DeviceVerificationResponse{
+		UserCode:   formatUserCode(code.UserCode),
+		ClientName: client.Name,
+		Scope:      code.Scope,
+	}, nil
+}
+
+func (uc *oauthUseCase) DecideDevice(ctx context.Context, req DeviceDecisionRequest) error {
+	code, err := uc.findPendingDeviceCode(ctx, req.UserID, req.UserCode)
+	if err != nil {
+		return err
+	}
+
+	// The device acts on behalf of the session's user, so its tokens carry
+	// the time that user signed in
+	sessions, err := uc.authUseCase.ListSessions(ctx, req.UserID, req.SessionID)
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(sessions, func(session SessionResponse) bool { return session.Current })
+	if i < 0 {
+		return domain.ErrSessionNotFound
+	}
+
+	if !req.Approve {
+		return uc.deviceCodeRepo.Decide(ctx, code.ID, domain.DeviceCodeDenied, req.UserID, sessions[i].CreatedAt)
+	}
+
+	// The user was shown the requested scopes, so approving consents to them
+	client, err := findClient(ctx, uc.clientRepo, code.ClientID)
+	if err != nil {
+		return err
+	}
+	if !client.FirstParty {
+		if err := uc.grantConsent(ctx, req.UserID, client.ID, code.Scope); err != nil {
+			return err
+		}
+	}
+
+	return uc.deviceCodeRepo.Decide(ctx, code.ID, domain.DeviceCodeApproved, req.UserID, sessions[i].CreatedAt)
+}
+
+// findPendingDeviceCode looks up a pending device authorization for a user
+// entering its user code. Unknown codes count as failed attempts, which are
+// limited per user so codes can't be guessed (RFC 8628 section 5.1)
+func (uc *oauthUseCase) findPendingDeviceCode(ctx context.Context, userID, userCode string) (*domain.DeviceCode, error) {
+	misses, err := uc.securityEventRepo.CountSince(ctx, userID, domain.SecurityEventUserCodeMiss, time.Now().Add(-userCodeAttemptWindow))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count user code attempts: %w", err)
+	}
+	if misses >= userCodeMaxAttempts {
+		return nil, domain.ErrTooManyAttempts
+	}
+
+	code, err := uc.deviceCodeRepo.FindPendingByUserCode(ctx, normalizeUserCode(userCode))
+	if err == domain.ErrDeviceCodeNotFound {
+		recordSecurityEvent(ctx, uc.securityEventRepo, userID, domain.SecurityEventUserCodeMiss,
+			fmt.Sprintf("unknown user code entered (%d of %d attempts)", misses+1, userCodeMaxAttempts))
+	}
+	return code, err
+}
+
+// exchangeDeviceCode handles the device_code grant, polled by the device
+// until the user decided (RFC 8628 section 3.4)
+func (uc *oauthUseCase) exchangeDeviceCode(ctx context.Context, client *domain.Client, req TokenRequest) (*AuthResponse, error) {
+	if req.DeviceCode == "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "device_code is required")
+	}
+
+	code, err := uc.deviceCodeRepo.FindByDeviceCodeHash(ctx, hashToken(req.DeviceCode))
+	if err != nil {
+		if err == domain.ErrDeviceCodeNotFound {
+			return nil, NewOAuthError(ErrorInvalidGrant, "invalid device code")
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ID {
+		return nil, NewOAuthError(ErrorInvalidGrant, "device code was issued to another client")
+	}
+	if code.UsedAt != nil {
+		return nil, NewOAuthError(ErrorInvalidGrant, "device code already used")
+	}
+	if code.IsExpired() {
+		return nil, NewOAuthError(ErrorExpiredToken, "device code expired")
+	}
+
+	// Devices polling faster than the interval must slow down for this and
+	// all subsequent requests
+	now := time.Now()
+	interval := code.PollInterval
+	tooFast := code.LastPolledAt != nil && now.Sub(*code.LastPolledAt) < time.Duration(code.PollInterval)*time.Second
+	if tooFast {
+		interval += deviceSlowDownIncrement
+	}
+	if err := uc.deviceCodeRepo.RecordPoll(ctx, code.ID, now, interval); err != nil {
+		return nil, fmt.Errorf("failed to record poll: %w", err)
+	}
+	if tooFast {
+		return nil, NewOAuthError(ErrorSlowDown, fmt.Sprintf("poll at most every %d seconds", interval))
+	}
+
+	switch code.Status {
+	case domain.DeviceCodePending:
+		return nil, NewOAuthError(ErrorAuthorizationPending, "the user has not yet approved the request")
+	case domain.DeviceCodeDenied:
+		return nil, NewOAuthError(ErrorAccessDenied, "the user denied the request")
+	}
+
+	if err := uc.deviceCodeRepo.Consume(ctx, code.ID); err != nil {
+		if err == domain.ErrDeviceCodeUsed {
+			return nil, NewOAuthError(ErrorInvalidGrant, "device code already used")
+		}
+		return nil, err
+	}
+
+	resp, err := uc.authUseCase.IssueTokens(ctx, IssueTokensRequest{
+		UserID:          code.UserID,
+		ClientID:        code.ClientID,
+		Scope:           code.Scope,
+		AuthenticatedAt: *code.AuthenticatedAt,
+		Audience:        req.Resource,
+		Confirmation:    req.cnf,
+		Client:          req.Client,
+	})
+	if err != nil {
+		if err == domain.ErrUserNotFound || err == domain.ErrUserSuspended || err == domain.ErrInvalidClient {
+			return nil, NewOAuthError(ErrorInvalidGrant, err.Error())
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// randomUserCode returns a random user code of userCodeAlphabet characters
+func randomUserCode() (string, error) {
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	code := make([]byte, userCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// formatUserCode splits a user code in two halves for readability
+func formatUserCode(userCode string) string {
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+// normalizeUserCode undoes formatting and case changes of an entered user code
+func normalizeUserCode(userCode string) string {
+	userCode = strings.ToUpper(userCode)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, userCode)
+}
@@ -0,0 +1,231 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newDeviceFlowTest creates an oauthTest with a public client tv allowed to
+// use the device flow, and a user signed in to session-1
+func newDeviceFlowTest(t *testing.T, signedInAt time.Time) *oauthTest {
+	t.Helper()
+
+	test := newOAuthTest(t)
+	test.clients.clients["tv"] = &domain.Client{
+		ID:         "tv",
+		Name:       "Living Room TV",
+		GrantTypes: []string{GrantTypeDeviceCode},
+		Scopes:     []string{ScopeOpenID, ScopeProfile},
+	}
+	test.auth.sessions = []SessionResponse{{ID: "session-1", CreatedAt: signedInAt}}
+	return test
+}
+
+// startDeviceAuthorization starts the device flow for tv
+func startDeviceAuthorization(t *testing.T, test *oauthTest) *DeviceAuthorizationResponse {
+	t.Helper()
+
+	resp, err := test.DeviceAuthorization(context.Background(), DeviceAuthorizationRequest{
+		Scope:       "openid",
+		Credentials: ClientCredentials{ClientID: "tv"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+// pollDeviceCode polls the token endpoint as tv, after the poll interval
+func pollDeviceCode(test *oauthTest, deviceCode string) (*AuthResponse, error) {
+	for _, code := range test.devices.codes {
+		code.LastPolledAt = nil
+	}
+	return test.Token(context.Background(), TokenRequest{
+		GrantType:   GrantTypeDeviceCode,
+		DeviceCode:  deviceCode,
+		Credentials: ClientCredentials{ClientID: "tv"},
+	})
+}
+
+func expectOAuthError(t *testing.T, err error, code string) {
+	t.Helper()
+
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != code {
+		t.Fatalf("expected %s, got %v", code, err)
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	signedInAt := time.Now().Add(-time.Hour)
+	test := newDeviceFlowTest(t, signedInAt)
+	ctx := context.Background()
+
+	resp := startDeviceAuthorization(t, test)
+	if resp.VerificationURI != testIssuer+"/device" || resp.VerificationURIComplete != testIssuer+"/device?user_code="+resp.UserCode {
+		t.Errorf("unexpected verification URIs %q and %q", resp.VerificationURI, resp.VerificationURIComplete)
+	}
+
+	_, err := pollDeviceCode(test, resp.DeviceCode)
+	expectOAuthError(t, err, ErrorAuthorizationPending)
+
+	verification, err := test.DeviceVerification(ctx, "user-1", resp.UserCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verification.ClientName != "Living Room TV" || verification.Scope != "openid" {
+		t.Errorf("unexpected verification %+v", verification)
+	}
+
+	err = test.DecideDevice(ctx, DeviceDecisionRequest{UserCode: resp.UserCode, Approve: true, UserID: "user-1", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if authenticatedAt := test.devices.codes[0].AuthenticatedAt; authenticatedAt == nil || !authenticatedAt.Equal(signedInAt) {
+		t.Errorf("expected the time the session signed in, got %v", authenticatedAt)
+	}
+	if consent, err := test.consents.Find(ctx, "user-1", "tv"); err != nil || consent.Scope != "openid" {
+		t.Errorf("expected approving to consent to the requested scope, got %+v (%v)", consent, err)
+	}
+
+	tokens, err := pollDeviceCode(test, resp.DeviceCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := test.jwtManager.ValidateToken(tokens.AccessToken)
+	if err != nil || claims.UserID != "user-1" || claims.ClientID != "tv" {
+		t.Fatalf("expected a token of user-1 for tv, got %+v (%v)", claims, err)
+	}
+
+	_, err = pollDeviceCode(test, resp.DeviceCode)
+	expectOAuthError(t, err, ErrorInvalidGrant)
+}
+
+func TestDecideDevice(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        DeviceDecisionRequest
+		wantStatus string
+		wantErr    error
+	}{
+		{
+			name:       "approve",
+			req:        DeviceDecisionRequest{Approve: true, UserID: "user-1", SessionID: "session-1"},
+			wantStatus: domain.DeviceCodeApproved,
+		},
+		{
+			name:       "deny",
+			req:        DeviceDecisionRequest{UserID: "user-1", SessionID: "session-1"},
+			wantStatus: domain.DeviceCodeDenied,
+		},
+		{
+			name:       "approve from an ended session",
+			req:        DeviceDecisionRequest{Approve: true, UserID: "user-1", SessionID: "session-2"},
+			wantStatus: domain.DeviceCodePending,
+			wantErr:    domain.ErrSessionNotFound,
+		},
+		{
+			name:       "deny from an ended session",
+			req:        DeviceDecisionRequest{UserID: "user-1", SessionID: "session-2"},
+			wantStatus: domain.DeviceCodePending,
+			wantErr:    domain.ErrSessionNotFound,
+		},
+		{
+			name:       "unknown user code",
+			req:        DeviceDecisionRequest{UserCode: "BBBB-BBBB", Approve: true, UserID: "user-1", SessionID: "session-1"},
+			wantStatus: domain.DeviceCodePending,
+			wantErr:    domain.ErrDeviceCodeNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newDeviceFlowTest(t, time.Now())
+			resp := startDeviceAuthorization(t, test)
+			req := tt.req
+			if req.UserCode == "" {
+				req.UserCode = resp.UserCode
+			}
+
+			err := test.DecideDevice(context.Background(), req)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if status := test.devices.codes[0].Status; status != tt.wantStatus {
+				t.Errorf("got status %q, want %q", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDeviceUserCodeAttemptLimit(t *testing.T) {
+	test := newDeviceFlowTest(t, time.Now())
+	resp := startDeviceAuthorization(t, test)
+	ctx := context.Background()
+
+	for i := 0; i < userCodeMaxAttempts; i++ {
+		if _, err := test.DeviceVerification(ctx, "user-1", "BBBB-BBBB"); err != domain.ErrDeviceCodeNotFound {
+			t.Fatalf("attempt %d: expected an unknown code, got %v", i+1, err)
+		}
+	}
+
+	// Once limited, even the right code is refused
+	if _, err := test.DeviceVerification(ctx, "user-1", resp.UserCode); err != domain.ErrTooManyAttempts {
+		t.Fatalf("expected too many attempts, got %v", err)
+	}
+	err := test.DecideDevice(ctx, DeviceDecisionRequest{UserCode: resp.UserCode, UserID: "user-1", SessionID: "session-1"})
+	if err != domain.ErrTooManyAttempts {
+		t.Fatalf("expected too many attempts on decision, got %v", err)
+	}
+
+	// Other users are not limited
+	if _, err := test.DeviceVerification(ctx, "user-2", resp.UserCode); err != nil {
+		t.Fatalf("expected another user to look up the code, got %v", err)
+	}
+
+	// Attempts expire after the window
+	for _, event := range test.events.events {
+		event.CreatedAt = event.CreatedAt.Add(-userCodeAttemptWindow)
+	}
+	if _, err := test.DeviceVerification(ctx, "user-1", resp.UserCode); err != nil {
+		t.Fatalf("expected the limit to expire, got %v", err)
+	}
+}
+
+func TestExchangeDeviceCodePolling(t *testing.T) {
+	test := newDeviceFlowTest(t, time.Now())
+	resp := startDeviceAuthorization(t, test)
+	req := TokenRequest{
+		GrantType:   GrantTypeDeviceCode,
+		DeviceCode:  resp.DeviceCode,
+		Credentials: ClientCredentials{ClientID: "tv"},
+	}
+
+	_, err := test.Token(context.Background(), req)
+	expectOAuthError(t, err, ErrorAuthorizationPending)
+
+	// Polling again right away must slow down, for all later polls too
+	_, err = test.Token(context.Background(), req)
+	expectOAuthError(t, err, ErrorSlowDown)
+	if interval := test.devices.codes[0].PollInterval; interval != devicePollInterval+deviceSlowDownIncrement {
+		t.Errorf("expected the interval to increase, got %d", interval)
+	}
+
+	err = test.DecideDevice(context.Background(), DeviceDecisionRequest{UserCode: resp.UserCode, UserID: "user-1", SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = pollDeviceCode(test, resp.DeviceCode)
+	expectOAuthError(t, err, ErrorAccessDenied)
+
+	test.devices.codes[0].ExpiresAt = time.Now().Add(-time.Second)
+	_, err = pollDeviceCode(test, resp.DeviceCode)
+	expectOAuthError(t, err, ErrorExpiredToken)
+
+	test.clients.clients["other"] = &domain.Client{ID: "other", GrantTypes: []string{GrantTypeDeviceCode}}
+	req.Credentials.ClientID = "other"
+	_, err = test.Token(context.Background(), req)
+	expectOAuthError(t, err, ErrorInvalidGrant)
+}
@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TokenTypeDPoP is the token type of access tokens bound to a DPoP key (RFC 9449 section 5)
+const TokenTypeDPoP = "DPoP"
+
+// dpopProofMaxAge is how far the issuance time of a DPoP proof may lie in
+// the past or, to allow for clock skew, in the future
+const dpopProofMaxAge = time.Minute
+
+// maxDPoPProofIDLength limits the jti of DPoP proofs to what is recorded
+// against replays
+const maxDPoPProofIDLength = 255
+
+// dpopNonceWindow is how often the server nonce changes. Nonces of the
+// previous window are still accepted
+const dpopNonceWindow = 5 * time.Minute
+
+// VerifyDPoPProof verifies a DPoP proof against the request it was sent
+// with and returns the thumbprint of its key (RFC 9449 section 4.3). The
+// request URL is the issuer followed by the request path, as the endpoints
+// are published in the server metadata, so that the Host header of a request
+// can't change which URL proofs must name
+func (uc *authUseCase) VerifyDPoPProof(ctx context.Context, req DPoPProofRequest) (string, error) {
+	proof, err := jwt.ParseDPoPProof(req.Proof)
+	if err != nil || len(proof.ID) > maxDPoPProofIDLength {
+		return "", domain.ErrInvalidDPoPProof
+	}
+
+	requestURL := strings.TrimSuffix(uc.jwtManager.GetIssuer(), "/") + req.Path
+	if proof.Method != req.Method || !dpopURLMatches(proof.URL, requestURL) {
+		return "", domain.ErrInvalidDPoPProof
+	}
+	if age := time.Since(proof.IssuedAt); age > dpopProofMaxAge || age < -dpopProofMaxAge {
+		return "", domain.ErrInvalidDPoPProof
+	}
+	if req.AccessToken != "" && proof.AccessTokenHash != jwt.DPoPAccessTokenHash(req.AccessToken) {
+		return "", domain.ErrInvalidDPoPProof
+	}
+	if !uc.validDPoPNonce(proof.Nonce) {
+		return "", domain.ErrDPoPNonceRequired
+	}
+
+	// Proofs are only accepted once, until they are too old anyway
+	err = uc.usedDPoPProofRepo.Create(ctx, &domain.UsedDPoPProof{
+		JKT:       proof.JKT,
+		JTI:       proof.ID,
+		ExpiresAt: proof.IssuedAt.Add(dpopProofMaxAge),
+	})
+	if err == domain.ErrDPoPProofReplayed {
+		return "", domain.ErrInvalidDPoPProof
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to record DPoP proof: %w", err)
+	}
+
+	return proof.JKT, nil
+}
+
+// DPoPNonce returns the current server nonce for DPoP proofs
+func (uc *authUseCase) DPoPNonce() string {
+	return uc.jwtManager.DPoPNonce(dpopNonceWindowOf(time.Now()))
+}
+
+// DPoPNonce returns the current server nonce for DPoP proofs
+func (uc *oauthUseCase) DPoPNonce() string {
+	return uc.authUseCase.DPoPNonce()
+}
+
+// dpopError converts an error of a DPoP proof verification to the error
+// returned to OAuth clients
+func dpopError(err error) error {
+	switch err {
+	case domain.ErrInvalidDPoPProof:
+		return NewOAuthError(ErrorInvalidDPoPProof, "invalid DPoP proof")
+	case domain.ErrDPoPNonceRequired:
+		return NewOAuthError(ErrorUseDPoPNonce, "DPoP proof must include the server nonce")
+	}
+	return err
+}
+
+// validDPoPNonce checks if a nonce is the nonce of the current or previous window
+func (uc *authUseCase) validDPoPNonce(nonce string) bool {
+	if nonce == "" {
+		return false
+	}
+	window := dpopNonceWindowOf(time.Now())
+	for _, w := range []int64{window, window - 1} {
+		if subtle.ConstantTimeCompare([]byte(nonce), []byte(uc.jwtManager.DPoPNonce(w))) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func dpopNonceWindowOf(t time.Time) int64 {
+	return t.Unix() / int64(dpopNonceWindow/time.Second)
+}
+
+// dpopURLMatches checks if the htu claim of a proof names the request URL,
+// ignoring its query and fragment and the case of scheme and host
+func dpopURLMatches(htu, requestURL string) bool {
+	proofURL, err := url.Parse(htu)
+	if err != nil {
+		return false
+	}
+	reqURL, err := url.Parse(requestURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(proofURL.Scheme, reqURL.Scheme) &&
+		strings.EqualFold(proofURL.Host, reqURL.Host) &&
+		proofURL.EscapedPath() == reqURL.EscapedPath()
+}
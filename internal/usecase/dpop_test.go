@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// signDPoPProof signs a DPoP proof with the public key in its jwk header
+func signDPoPProof(t *testing.T, key *ecdsa.PrivateKey, typ string, claims gojwt.MapClaims) string {
+	t.Helper()
+
+	publicJWK, err := publicJWKOf(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var jwk map[string]interface{}
+	if err := json.Unmarshal([]byte(publicJWK), &jwk); err != nil {
+		t.Fatal(err)
+	}
+
+	token := gojwt.NewWithClaims(gojwt.SigningMethodES256, claims)
+	token.Header["typ"] = typ
+	token.Header["jwk"] = jwk
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestVerifyDPoPProof(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtManager := newTestJWTManager(t)
+	now := time.Now()
+	nonce := jwtManager.DPoPNonce(dpopNonceWindowOf(now))
+
+	validClaims := func() gojwt.MapClaims {
+		return gojwt.MapClaims{
+			"jti":   "proof-1",
+			"htm":   "GET",
+			"htu":   testIssuer + "/userinfo",
+			"iat":   now.Unix(),
+			"ath":   jwt.DPoPAccessTokenHash("access-token"),
+			"nonce": nonce,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		typ     string
+		modify  func(gojwt.MapClaims)
+		wantErr error
+	}{
+		{"valid", "dpop+jwt", func(gojwt.MapClaims) {}, nil},
+		{"htu with query and upper case host", "dpop+jwt", func(c gojwt.MapClaims) { c["htu"] = "HTTPS://AUTH.EXAMPLE.COM/userinfo?x=1" }, nil},
+		{"nonce of the previous window", "dpop+jwt", func(c gojwt.MapClaims) {
+			c["nonce"] = jwtManager.DPoPNonce(dpopNonceWindowOf(now) - 1)
+		}, nil},
+		{"wrong type", "JWT", func(gojwt.MapClaims) {}, domain.ErrInvalidDPoPProof},
+		{"missing jti", "dpop+jwt", func(c gojwt.MapClaims) { delete(c, "jti") }, domain.ErrInvalidDPoPProof},
+		{"jti too long", "dpop+jwt", func(c gojwt.MapClaims) { c["jti"] = strings.Repeat("x", maxDPoPProofIDLength+1) }, domain.ErrInvalidDPoPProof},
+		{"wrong htm", "dpop+jwt", func(c gojwt.MapClaims) { c["htm"] = "POST" }, domain.ErrInvalidDPoPProof},
+		{"htu of the Host header", "dpop+jwt", func(c gojwt.MapClaims) { c["htu"] = "https://attacker.example.com/userinfo" }, domain.ErrInvalidDPoPProof},
+		{"htu over http", "dpop+jwt", func(c gojwt.MapClaims) { c["htu"] = "http://auth.example.com/userinfo" }, domain.ErrInvalidDPoPProof},
+		{"htu of another path", "dpop+jwt", func(c gojwt.MapClaims) { c["htu"] = testIssuer + "/oauth/token" }, domain.ErrInvalidDPoPProof},
+		{"stale iat", "dpop+jwt", func(c gojwt.MapClaims) { c["iat"] = now.Add(-2 * dpopProofMaxAge).Unix() }, domain.ErrInvalidDPoPProof},
+		{"future iat", "dpop+jwt", func(c gojwt.MapClaims) { c["iat"] = now.Add(2 * dpopProofMaxAge).Unix() }, domain.ErrInvalidDPoPProof},
+		{"missing ath", "dpop+jwt", func(c gojwt.MapClaims) { delete(c, "ath") }, domain.ErrInvalidDPoPProof},
+		{"ath of another token", "dpop+jwt", func(c gojwt.MapClaims) { c["ath"] = jwt.DPoPAccessTokenHash("other-token") }, domain.ErrInvalidDPoPProof},
+		{"missing nonce", "dpop+jwt", func(c gojwt.MapClaims) { delete(c, "nonce") }, domain.ErrDPoPNonceRequired},
+		{"expired nonce", "dpop+jwt", func(c gojwt.MapClaims) {
+			c["nonce"] = jwtManager.DPoPNonce(dpopNonceWindowOf(now) - 2)
+		}, domain.ErrDPoPNonceRequired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &authUseCase{
+				jwtManager:        jwtManager,
+				usedDPoPProofRepo: &fakeUsedDPoPProofRepo{used: map[string]bool{}},
+			}
+			claims := validClaims()
+			tt.modify(claims)
+			req := DPoPProofRequest{
+				Proof:       signDPoPProof(t, key, tt.typ, claims),
+				Method:      "GET",
+				Path:        "/userinfo",
+				AccessToken: "access-token",
+			}
+
+			jkt, err := uc.VerifyDPoPProof(context.Background(), req)
+			if err != tt.wantErr {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && jkt == "" {
+				t.Error("expected the thumbprint of the proof key")
+			}
+		})
+	}
+}
+
+func TestVerifyDPoPProofReplay(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uc := &authUseCase{
+		jwtManager:        newTestJWTManager(t),
+		usedDPoPProofRepo: &fakeUsedDPoPProofRepo{used: map[string]bool{}},
+	}
+	proof := func(jti string) DPoPProofRequest {
+		return DPoPProofRequest{
+			Proof: signDPoPProof(t, key, "dpop+jwt", gojwt.MapClaims{
+				"jti":   jti,
+				"htm":   "POST",
+				"htu":   testIssuer + "/oauth/token",
+				"iat":   time.Now().Unix(),
+				"nonce": uc.DPoPNonce(),
+			}),
+			Method: "POST",
+			Path:   "/oauth/token",
+		}
+	}
+
+	ctx := context.Background()
+	first := proof("proof-1")
+	if _, err := uc.VerifyDPoPProof(ctx, first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.VerifyDPoPProof(ctx, first); err != domain.ErrInvalidDPoPProof {
+		t.Errorf("replayed proof: got %v, want %v", err, domain.ErrInvalidDPoPProof)
+	}
+	if _, err := uc.VerifyDPoPProof(ctx, proof("proof-2")); err != nil {
+		t.Errorf("new proof of the same key: %v", err)
+	}
+}
@@ -1,30 +1,89 @@
 package usecase
 
+import (
+	"auth-service/pkg/jwt"
+	"crypto/x509"
+	"encoding/json"
+	"time"
+)
+
 // RegisterRequest represents a registration request
 type RegisterRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=8"`
 	Name     string `json:"name" validate:"required,min=2"`
+
+	Client ClientMetadata `json:"-"`
 }
 
 // LoginRequest represents a login request
+// ClientID, Scope and Nonce are optional; requesting the openid scope
+// returns an ID token issued to ClientID
 type LoginRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required"`
+	// ClientID optionally names the registered OAuth client the tokens are issued to
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	Nonce    string `json:"nonce"`
+
+	Client ClientMetadata `json:"-"`
 }
 
 // RefreshTokenRequest represents a refresh token request
+// ClientID, Audience and Confirmation are set for tokens refreshed at the OAuth token endpoint
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
+
+	ClientID     string           `json:"-"`
+	Audience     []string         `json:"-"`
+	Confirmation jwt.Confirmation `json:"-"` // keys the client proved possession of
+	Client       ClientMetadata   `json:"-"`
+}
+
+// ChangePasswordRequest represents a password change request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
+// RevokeAccessTokenRequest represents an access token revocation request
+type RevokeAccessTokenRequest struct {
+	AccessToken string `json:"access_token"`
+}
+
+// IssueTokensRequest represents the issuance of tokens for a new session of
+// a user who authenticated through an OAuth flow
+type IssueTokensRequest struct {
+	UserID          string
+	ClientID        string
+	Scope           string
+	Nonce           string
+	AuthenticatedAt time.Time
+	Audience        []string         // requested resources, empty for the service's own API
+	Confirmation    jwt.Confirmation // keys to bind the tokens to
+	Client          ClientMetadata
+	SSOSessionID    string // browser session the user signed in with, if any
+}
+
+// ClientMetadata describes the client a request was made from
+type ClientMetadata struct {
+	IPAddress string
+	UserAgent string
 }
 
 // AuthResponse represents the authentication response
+// RefreshToken and User are omitted for tokens issued to machine principals
 type AuthResponse struct {
-	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
-	TokenType    string       `json:"token_type"`
-	ExpiresIn    int          `json:"expires_in"` // in seconds
-	User         UserResponse `json:"user"`
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token,omitempty"`
+	TokenType    string        `json:"token_type"`
+	ExpiresIn    int           `json:"expires_in"` // in seconds
+	Scope        string        `json:"scope,omitempty"`
+	IDToken      string        `json:"id_token,omitempty"`
+	User         *UserResponse `json:"user,omitempty"`
+	// IssuedTokenType is only set for token exchange (RFC 8693)
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
 }
 
 // UserResponse represents a user response
@@ -33,3 +92,390 @@ type UserResponse struct {
 	Email string `json:"email"`
 	Name  string `json:"name"`
 }
+
+// SessionResponse represents an active session (device) of a user
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	ClientID   string    `json:"client_id,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+}
+
+// ClientCredentials represents the credentials an OAuth client authenticates with
+// Depending on the client's authentication method, these are a secret, a
+// signed assertion (RFC 7523) or a TLS client certificate (RFC 8705)
+type ClientCredentials struct {
+	ClientID            string
+	ClientSecret        string
+	ClientAssertionType string
+	ClientAssertion     string
+	Certificate         *x509.Certificate
+	// CertificateVerified is set if the certificate chain was verified by the
+	// TLS listener or against the client CAs, as required for tls_client_auth
+	CertificateVerified bool
+}
+
+// IntrospectionRequest represents a token introspection request (RFC 7662)
+type IntrospectionRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// IntrospectionResponse represents a token introspection response (RFC 7662)
+type IntrospectionResponse struct {
+	Active    bool              `json:"active"`
+	Scope     string            `json:"scope,omitempty"`
+	ClientID  string            `json:"client_id,omitempty"`
+	Username  string            `json:"username,omitempty"`
+	TokenType string            `json:"token_type,omitempty"`
+	Exp       int64             `json:"exp,omitempty"`
+	Iat       int64             `json:"iat,omitempty"`
+	Sub       string            `json:"sub,omitempty"`
+	Iss       string            `json:"iss,omitempty"`
+	Jti       string            `json:"jti,omitempty"`
+	Aud       []string          `json:"aud,omitempty"`
+	Act       *jwt.Actor        `json:"act,omitempty"`
+	Cnf       *jwt.Confirmation `json:"cnf,omitempty"`
+}
+
+// ClientRequest represents the settings of an OAuth client, used to create
+// and update clients. Public and TokenEndpointAuthMethod are only read on
+// creation; confidential clients default to client_secret_basic
+type ClientRequest struct {
+	Name                               string          `json:"name"`
+	Public                             bool            `json:"public"`
+	FirstParty                         bool            `json:"first_party"`
+	TokenEndpointAuthMethod            string          `json:"token_endpoint_auth_method"`
+	JWKS                               json.RawMessage `json:"jwks,omitempty"`
+	JWKSURI                            string          `json:"jwks_uri,omitempty"`
+	TLSClientAuthSubjectDN             string          `json:"tls_client_auth_subject_dn,omitempty"`
+	RedirectURIs                       []string        `json:"redirect_uris"`
+	PostLogoutRedirectURIs             []string        `json:"post_logout_redirect_uris,omitempty"`
+	BackchannelLogoutURI               string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI              string          `json:"frontchannel_logout_uri,omitempty"`
+	GrantTypes                         []string        `json:"grant_types"`
+	Scopes                             []string        `json:"scopes"`
+	AllowedOrigins                     []string        `json:"allowed_origins"`
+	Resources                          []string        `json:"resources,omitempty"`
+	TokenExchangeAudiences             []string        `json:"token_exchange_audiences,omitempty"`
+	TokenExchangeSubjectClients        []string        `json:"token_exchange_subject_clients,omitempty"`
+	TokenExchangeSubjectAudiences      []string        `json:"token_exchange_subject_audiences,omitempty"`
+	RequirePushedAuthorizationRequests bool            `json:"require_pushed_authorization_requests"`
+	AccessTokenLifetime                int             `json:"access_token_lifetime"`  // in seconds, 0 for the default
+	RefreshTokenLifetime               int             `json:"refresh_token_lifetime"` // in seconds, 0 for the default
+}
+
+// ClientResponse represents an OAuth client
+// ClientSecret is only returned when the client is created
+type ClientResponse struct {
+	ClientID                           string          `json:"client_id"`
+	ClientSecret                       string          `json:"client_secret,omitempty"`
+	Name                               string          `json:"name"`
+	FirstParty                         bool            `json:"first_party"`
+	Public                             bool            `json:"public"`
+	TokenEndpointAuthMethod            string          `json:"token_endpoint_auth_method"`
+	JWKS                               json.RawMessage `json:"jwks,omitempty"`
+	JWKSURI                            string          `json:"jwks_uri,omitempty"`
+	TLSClientAuthSubjectDN             string          `json:"tls_client_auth_subject_dn,omitempty"`
+	RedirectURIs                       []string        `json:"redirect_uris"`
+	PostLogoutRedirectURIs             []string        `json:"post_logout_redirect_uris,omitempty"`
+	BackchannelLogoutURI               string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI              string          `json:"frontchannel_logout_uri,omitempty"`
+	GrantTypes                         []string        `json:"grant_types"`
+	Scopes                             []string        `json:"scopes"`
+	AllowedOrigins                     []string        `json:"allowed_origins"`
+	Resources                          []string        `json:"resources,omitempty"`
+	TokenExchangeAudiences             []string        `json:"token_exchange_audiences,omitempty"`
+	TokenExchangeSubjectClients        []string        `json:"token_exchange_subject_clients,omitempty"`
+	TokenExchangeSubjectAudiences      []string        `json:"token_exchange_subject_audiences,omitempty"`
+	RequirePushedAuthorizationRequests bool            `json:"require_pushed_authorization_requests"`
+	AccessTokenLifetime                int             `json:"access_token_lifetime"`
+	RefreshTokenLifetime               int             `json:"refresh_token_lifetime"`
+	CreatedAt                          time.Time       `json:"created_at"`
+	UpdatedAt                          time.Time       `json:"updated_at"`
+}
+
+// RegistrationRequest represents the metadata a client registers itself
+// with (RFC 7591 section 2). ClientID is only sent on update (RFC 7592)
+type RegistrationRequest struct {
+	ClientID                           string          `json:"client_id,omitempty"`
+	ClientName                         string          `json:"client_name"`
+	RedirectURIs                       []string        `json:"redirect_uris"`
+	PostLogoutRedirectURIs             []string        `json:"post_logout_redirect_uris,omitempty"`
+	BackchannelLogoutURI               string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI              string          `json:"frontchannel_logout_uri,omitempty"`
+	GrantTypes                         []string        `json:"grant_types"`
+	ResponseTypes                      []string        `json:"response_types"`
+	Scope                              string          `json:"scope"`
+	TokenEndpointAuthMethod            string          `json:"token_endpoint_auth_method"`
+	JWKSURI                            string          `json:"jwks_uri,omitempty"`
+	JWKS                               json.RawMessage `json:"jwks,omitempty"`
+	TLSClientAuthSubjectDN             string          `json:"tls_client_auth_subject_dn,omitempty"`
+	RequirePushedAuthorizationRequests bool            `json:"require_pushed_authorization_requests,omitempty"`
+}
+
+// RegistrationResponse represents a dynamically registered client (RFC 7591
+// section 3.2.1). The client secret and registration access token are only
+// returned on registration
+type RegistrationResponse struct {
+	ClientID                           string          `json:"client_id"`
+	ClientSecret                       string          `json:"client_secret,omitempty"`
+	ClientIDIssuedAt                   int64           `json:"client_id_issued_at"`
+	ClientSecretExpiresAt              int64           `json:"client_secret_expires_at"` // 0, secrets don't expire
+	RegistrationAccessToken            string          `json:"registration_access_token,omitempty"`
+	RegistrationClientURI              string          `json:"registration_client_uri"`
+	ClientName                         string          `json:"client_name"`
+	RedirectURIs                       []string        `json:"redirect_uris"`
+	PostLogoutRedirectURIs             []string        `json:"post_logout_redirect_uris,omitempty"`
+	BackchannelLogoutURI               string          `json:"backchannel_logout_uri,omitempty"`
+	FrontchannelLogoutURI              string          `json:"frontchannel_logout_uri,omitempty"`
+	GrantTypes                         []string        `json:"grant_types"`
+	ResponseTypes                      []string        `json:"response_types"`
+	Scope                              string          `json:"scope,omitempty"`
+	TokenEndpointAuthMethod            string          `json:"token_endpoint_auth_method"`
+	JWKSURI                            string          `json:"jwks_uri,omitempty"`
+	JWKS                               json.RawMessage `json:"jwks,omitempty"`
+	TLSClientAuthSubjectDN             string          `json:"tls_client_auth_subject_dn,omitempty"`
+	RequirePushedAuthorizationRequests bool            `json:"require_pushed_authorization_requests"`
+}
+
+// UserInfoResponse represents the OpenID Connect userinfo response
+// Claims are filtered by the scopes of the access token
+type UserInfoResponse struct {
+	Sub   string `json:"sub"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// ProviderMetadata represents the OpenID Connect discovery document
+type ProviderMetadata struct {
+	Issuer                                     string   `json:"issuer"`
+	AuthorizationEndpoint                      string   `json:"authorization_endpoint"`
+	TokenEndpoint                              string   `json:"token_endpoint"`
+	JWKSURI                                    string   `json:"jwks_uri"`
+	UserInfoEndpoint                           string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint                      string   `json:"introspection_endpoint"`
+	RevocationEndpoint                         string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint                string   `json:"device_authorization_endpoint"`
+	PushedAuthorizationRequestEndpoint         string   `json:"pushed_authorization_request_endpoint"`
+	EndSessionEndpoint                         string   `json:"end_session_endpoint"`
+	RegistrationEndpoint                       string   `json:"registration_endpoint,omitempty"`
+	ScopesSupported                            []string `json:"scopes_supported"`
+	ResponseTypesSupported                     []string `json:"response_types_supported"`
+	GrantTypesSupported                        []string `json:"grant_types_supported"`
+	CodeChallengeMethodsSupported              []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                            []string `json:"claims_supported"`
+	SubjectTypesSupported                      []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported"`
+	IntrospectionEndpointAuthMethodsSupported  []string `json:"introspection_endpoint_auth_methods_supported"`
+	RevocationEndpointAuthMethodsSupported     []string `json:"revocation_endpoint_auth_methods_supported"`
+	DPoPSigningAlgValuesSupported              []string `json:"dpop_signing_alg_values_supported"`
+	TLSClientCertificateBoundAccessTokens      bool     `json:"tls_client_certificate_bound_access_tokens"`
+	FrontchannelLogoutSupported                bool     `json:"frontchannel_logout_supported"`
+	FrontchannelLogoutSessionSupported         bool     `json:"frontchannel_logout_session_supported"`
+	BackchannelLogoutSupported                 bool     `json:"backchannel_logout_supported"`
+	BackchannelLogoutSessionSupported          bool     `json:"backchannel_logout_session_supported"`
+}
+
+// EndSessionRequest represents an RP-initiated logout request (OpenID
+// Connect RP-Initiated Logout 1.0 section 2)
+type EndSessionRequest struct {
+	IDTokenHint           string `query:"id_token_hint" form:"id_token_hint"`
+	ClientID              string `query:"client_id" form:"client_id"`
+	PostLogoutRedirectURI string `query:"post_logout_redirect_uri" form:"post_logout_redirect_uri"`
+	State                 string `query:"state" form:"state"`
+
+	// SessionToken is the SSO session cookie of the browser, if any
+	SessionToken string `query:"-" form:"-"`
+}
+
+// EndSessionResponse tells the browser where to go after logging out
+// RedirectURI is the post-logout redirect URI with the state, empty to show
+// the logged out page. FrontchannelLogoutURIs are loaded in iframes to log
+// the user out of the clients of the ended sessions. SessionEnded is set
+// when the browser's SSO session ended, so that its cookie is cleared
+type EndSessionResponse struct {
+	RedirectURI            string
+	FrontchannelLogoutURIs []string
+	SessionEnded           bool
+}
+
+// LogoutDeliveryResponse represents a back-channel logout notification of a client
+type LogoutDeliveryResponse struct {
+	ID            uint       `json:"id"`
+	UserID        string     `json:"user_id"`
+	SessionID     string     `json:"session_id"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"` // only set on pending deliveries
+	LastError     string     `json:"last_error,omitempty"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// RevocationRequest represents a token revocation request (RFC 7009)
+// ClientID is set by the handler to the authenticated client
+type RevocationRequest struct {
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"-"`
+}
+
+// AuthorizationRequest represents an OAuth 2.0 authorization request
+// PKCE with the S256 method is mandatory
+type AuthorizationRequest struct {
+	ResponseType        string `query:"response_type" form:"response_type"`
+	ClientID            string `query:"client_id" form:"client_id"`
+	RedirectURI         string `query:"redirect_uri" form:"redirect_uri"`
+	Scope               string `query:"scope" form:"scope"`
+	State               string `query:"state" form:"state"`
+	Nonce               string `query:"nonce" form:"nonce"`
+	CodeChallenge       string `query:"code_challenge" form:"code_challenge"`
+	CodeChallengeMethod string `query:"code_challenge_method" form:"code_challenge_method"`
+
+	// Prompt and MaxAge control whether a user with an SSO session must
+	// sign in again (OpenID Connect Core 1.0 section 3.1.2.1)
+	Prompt string `query:"prompt" form:"prompt"`
+	MaxAge string `query:"max_age" form:"max_age"`
+
+	// RequestURI references a pushed authorization request (RFC 9126)
+	RequestURI string `query:"request_uri" form:"request_uri"`
+
+	Client ClientMetadata `query:"-" form:"-"`
+	// SessionToken is the SSO session cookie of the browser, if any
+	SessionToken string `query:"-" form:"-"`
+}
+
+// PushedAuthorizationResponse represents a pushed authorization response (RFC 9126 section 2.2)
+type PushedAuthorizationResponse struct {
+	RequestURI string `json:"request_uri"`
+	ExpiresIn  int    `json:"expires_in"` // in seconds
+}
+
+// AuthorizationResponse represents a successful authorization, to be
+// returned to the client's redirect URI
+// If the user must consent first, ConsentChallenge and Consent are set
+// instead of Code. Without either, the user denied the request
+type AuthorizationResponse struct {
+	RedirectURI      string
+	Code             string
+	State            string
+	ConsentChallenge string
+	Consent          *ConsentPrompt
+
+	// Session is set when the user signed in, to store its cookie in the browser
+	Session *SSOSessionResponse
+}
+
+// SSOSessionResponse represents a browser SSO session
+// Token, the value of the session cookie, is only set when it changed
+type SSOSessionResponse struct {
+	ID              string
+	UserID          string
+	Token           string
+	AuthenticatedAt time.Time
+	ExpiresAt       time.Time
+}
+
+// ConsentPrompt describes what a client asks the user to consent to
+type ConsentPrompt struct {
+	ClientName string
+	Scopes     []ScopeDefinition
+}
+
+// ConsentDecisionRequest represents the user's decision on a consent prompt
+type ConsentDecisionRequest struct {
+	ConsentChallenge string `form:"consent_challenge"`
+	Approve          bool   `form:"-"`
+}
+
+// ConsentResponse represents the scopes a user granted to a third-party client
+type ConsentResponse struct {
+	ClientID   string            `json:"client_id"`
+	ClientName string            `json:"client_name"`
+	Scope      string            `json:"scope"`
+	Scopes     []ScopeDefinition `json:"scopes"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// TokenRequest represents a token endpoint request (RFC 6749 section 3.2)
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	DeviceCode   string `form:"device_code"`
+
+	// Resource lists the resource servers the access token is for (RFC 8707)
+	Resource []string `form:"resource"`
+
+	// Token exchange parameters (RFC 8693 section 2.1)
+	SubjectToken       string `form:"subject_token"`
+	SubjectTokenType   string `form:"subject_token_type"`
+	ActorToken         string `form:"actor_token"`
+	ActorTokenType     string `form:"actor_token_type"`
+	RequestedTokenType string `form:"requested_token_type"`
+	Audience           string `form:"audience"`
+
+	// DPoP is the DPoP proof sent with the request, if any (RFC 9449)
+	DPoP *DPoPProofRequest `form:"-"`
+
+	Credentials ClientCredentials `form:"-"`
+	Client      ClientMetadata    `form:"-"`
+
+	// cnf holds the keys the client proved possession of with the request
+	cnf jwt.Confirmation
+}
+
+// DPoPProofRequest represents a DPoP proof sent with a request (RFC 9449)
+type DPoPProofRequest struct {
+	Proof  string
+	Method string
+	Path   string // request path, which follows the issuer in the request URL
+	// AccessToken is the token the proof was presented with, empty at the token endpoint
+	AccessToken string
+}
+
+// DeviceAuthorizationRequest represents a device authorization request (RFC 8628 section 3.1)
+type DeviceAuthorizationRequest struct {
+	Scope string `form:"scope"`
+
+	Credentials ClientCredentials `form:"-"`
+}
+
+// DeviceAuthorizationResponse represents a device authorization response (RFC 8628 section 3.2)
+type DeviceAuthorizationResponse struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"` // in seconds
+	Interval                int    `json:"interval"`   // in seconds
+}
+
+// DeviceVerificationResponse describes a pending device authorization to
+// the user asked to approve it
+type DeviceVerificationResponse struct {
+	UserCode   string `json:"user_code"`
+	ClientName string `json:"client_name"`
+	Scope      string `json:"scope"`
+}
+
+// DeviceDecisionRequest represents a signed-in user's decision on a device
+// authorization. UserID and SessionID are set by the handler from the
+// user's access token
+type DeviceDecisionRequest struct {
+	UserCode string `json:"user_code" validate:"required"`
+	Approve  bool   `json:"approve"`
+
+	UserID    string `json:"-"`
+	SessionID string `json:"-"`
+}
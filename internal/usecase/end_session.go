@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// EndSession logs the user out of the browser's SSO session and of the
+// session an ID token was issued for (OpenID Connect RP-Initiated Logout
+// 1.0). The clients of the sessions are notified through back-channel
+// logout, and the front-channel logout URIs to load in the browser are
+// returned
+func (uc *oauthUseCase) EndSession(ctx context.Context, req EndSessionRequest) (*EndSessionResponse, error) {
+	var claims *jwt.IDTokenClaims
+	if req.IDTokenHint != "" {
+		hint, err := uc.jwtManager.ParseIDTokenHint(req.IDTokenHint)
+		if err != nil {
+			return nil, domain.ErrInvalidIDTokenHint
+		}
+		if req.ClientID != "" && req.ClientID != hint.AuthorizedParty {
+			return nil, domain.ErrInvalidIDTokenHint
+		}
+		claims = hint
+		req.ClientID = hint.AuthorizedParty
+	}
+
+	// Redirecting back needs a client to check the redirect URI against
+	if req.PostLogoutRedirectURI != "" {
+		client, err := findClient(ctx, uc.clientRepo, req.ClientID)
+		if err != nil {
+			return nil, err
+		}
+		if !client.AllowsPostLogoutRedirectURI(req.PostLogoutRedirectURI) {
+			return nil, domain.ErrInvalidRedirectURI
+		}
+	}
+
+	session, err := uc.authUseCase.FindSSOSession(ctx, req.SessionToken)
+	if err != nil && err != domain.ErrSessionNotFound {
+		return nil, err
+	}
+
+	// A hint for another user than the one signed in to the browser only
+	// ends the hinted session
+	resp := &EndSessionResponse{}
+	var ended []SessionResponse
+	if session != nil && (claims == nil || claims.Subject == session.UserID) {
+		ended, err = uc.authUseCase.EndSSOSession(ctx, session.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to end session: %w", err)
+		}
+		resp.SessionEnded = true
+	}
+
+	// The session may have ended already, in which case there is nothing to
+	// revoke but the client is still notified in the browser
+	if claims != nil && claims.SessionID != "" {
+		err := uc.authUseCase.RevokeSession(ctx, claims.Subject, claims.SessionID)
+		if err != nil && err != domain.ErrSessionNotFound {
+			return nil, fmt.Errorf("failed to end session: %w", err)
+		}
+		ended = append(ended, SessionResponse{ID: claims.SessionID, ClientID: claims.AuthorizedParty})
+	}
+
+	notified := make(map[string]bool)
+	for _, endedSession := range ended {
+		if endedSession.ClientID == "" || notified[endedSession.ID] {
+			continue
+		}
+		notified[endedSession.ID] = true
+
+		client, err := uc.clientRepo.FindByID(ctx, endedSession.ClientID)
+		if err != nil {
+			if err == domain.ErrClientNotFound {
+				continue
+			}
+			return nil, err
+		}
+		if client.FrontchannelLogoutURI == "" {
+			continue
+		}
+		logoutURI, err := uc.frontchannelLogoutURI(client, endedSession.ID)
+		if err != nil {
+			return nil, err
+		}
+		resp.FrontchannelLogoutURIs = append(resp.FrontchannelLogoutURIs, logoutURI)
+	}
+
+	if req.PostLogoutRedirectURI != "" {
+		redirectURI, err := url.Parse(req.PostLogoutRedirectURI)
+		if err != nil {
+			return nil, domain.ErrInvalidRedirectURI
+		}
+		if req.State != "" {
+			query := redirectURI.Query()
+			query.Set("state", req.State)
+			redirectURI.RawQuery = query.Encode()
+		}
+		resp.RedirectURI = redirectURI.String()
+	}
+
+	return resp, nil
+}
+
+// frontchannelLogoutURI returns the front-channel logout URI of a client
+// with the issuer and session ID (Front-Channel Logout 1.0 section 2)
+func (uc *oauthUseCase) frontchannelLogoutURI(client *domain.Client, sessionID string) (string, error) {
+	logoutURI, err := url.Parse(client.FrontchannelLogoutURI)
+	if err != nil {
+		return "", fmt.Errorf("invalid front-channel logout URI of client %s: %w", client.ID, err)
+	}
+
+	query := logoutURI.Query()
+	query.Set("iss", uc.jwtManager.GetIssuer())
+	if sessionID != "" {
+		query.Set("sid", sessionID)
+	}
+	logoutURI.RawQuery = query.Encode()
+	return logoutURI.String(), nil
+}
@@ -0,0 +1,132 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"net/url"
+	"testing"
+)
+
+// newEndSessionTest creates an oauthTest with a browser SSO session of
+// user-1 through which web-app logged in, and returns an ID token of that
+// client session
+func newEndSessionTest(t *testing.T) (*oauthTest, string) {
+	t.Helper()
+
+	test := newOAuthTest(t)
+	client := test.clients.clients["web-app"]
+	client.PostLogoutRedirectURIs = []string{"https://app.example.com/logged-out"}
+	client.FrontchannelLogoutURI = "https://app.example.com/frontchannel-logout"
+	test.auth.sessions = []SessionResponse{{ID: "session-1", ClientID: "web-app"}}
+	test.auth.ssoSession = &SSOSessionResponse{ID: "sso-1", UserID: "user-1", Token: "sso-token"}
+
+	idToken, err := test.jwtManager.GenerateIDToken("user-1", "web-app", "access-token", jwt.IDTokenClaims{SessionID: "session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return test, idToken
+}
+
+func TestEndSession(t *testing.T) {
+	test, idToken := newEndSessionTest(t)
+
+	resp, err := test.EndSession(context.Background(), EndSessionRequest{
+		IDTokenHint:           idToken,
+		PostLogoutRedirectURI: "https://app.example.com/logged-out",
+		State:                 "client-state",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(test.auth.revokedSessions) != 1 || test.auth.revokedSessions[0] != "session-1" {
+		t.Errorf("expected session-1 to be revoked, got %v", test.auth.revokedSessions)
+	}
+	if resp.RedirectURI != "https://app.example.com/logged-out?state=client-state" {
+		t.Errorf("unexpected redirect URI %q", resp.RedirectURI)
+	}
+	want := "https://app.example.com/frontchannel-logout?iss=" + url.QueryEscape(testIssuer) + "&sid=session-1"
+	if len(resp.FrontchannelLogoutURIs) != 1 || resp.FrontchannelLogoutURIs[0] != want {
+		t.Errorf("unexpected front-channel logout URIs %v", resp.FrontchannelLogoutURIs)
+	}
+	if resp.SessionEnded || len(test.auth.endedSSOSessions) != 0 {
+		t.Error("expected the SSO session to be kept without its cookie")
+	}
+
+	// Logging out of an ended session still returns to the client
+	if _, err := test.EndSession(context.Background(), EndSessionRequest{IDTokenHint: idToken}); err != nil {
+		t.Errorf("ended session: %v", err)
+	}
+}
+
+func TestEndSessionRejectsUntrustedRequests(t *testing.T) {
+	test, idToken := newEndSessionTest(t)
+
+	tests := []struct {
+		name    string
+		req     EndSessionRequest
+		wantErr error
+	}{
+		{"redirect without client_id", EndSessionRequest{PostLogoutRedirectURI: "https://app.example.com/logged-out"}, domain.ErrInvalidClient},
+		{"invalid ID token hint", EndSessionRequest{IDTokenHint: "not-a-token"}, domain.ErrInvalidIDTokenHint},
+		{"other client_id", EndSessionRequest{IDTokenHint: idToken, ClientID: "mobile-app"}, domain.ErrInvalidIDTokenHint},
+		{"unregistered redirect URI", EndSessionRequest{IDTokenHint: idToken, PostLogoutRedirectURI: "https://evil.example.com/"}, domain.ErrInvalidRedirectURI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := test.EndSession(context.Background(), tt.req); err != tt.wantErr {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if len(test.auth.revokedSessions) != 0 {
+		t.Error("expected no session to be revoked")
+	}
+}
+
+func TestEndSessionEndsSSOSession(t *testing.T) {
+	test, _ := newEndSessionTest(t)
+
+	resp, err := test.EndSession(context.Background(), EndSessionRequest{
+		ClientID:              "web-app",
+		PostLogoutRedirectURI: "https://app.example.com/logged-out",
+		SessionToken:          "sso-token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.SessionEnded || len(test.auth.endedSSOSessions) != 1 || test.auth.endedSSOSessions[0] != "sso-1" {
+		t.Errorf("expected sso-1 to end, got %v", test.auth.endedSSOSessions)
+	}
+	if len(resp.FrontchannelLogoutURIs) != 1 {
+		t.Errorf("expected web-app to be logged out in the browser, got %v", resp.FrontchannelLogoutURIs)
+	}
+	if resp.RedirectURI != "https://app.example.com/logged-out" {
+		t.Errorf("unexpected redirect URI %q", resp.RedirectURI)
+	}
+
+	// A session that ended already is not reported as ended again
+	resp, err = test.EndSession(context.Background(), EndSessionRequest{SessionToken: "sso-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.SessionEnded {
+		t.Error("expected no session to end twice")
+	}
+}
+
+func TestEndSessionOfAnotherUser(t *testing.T) {
+	test, idToken := newEndSessionTest(t)
+	test.auth.ssoSession.UserID = "user-2"
+
+	resp, err := test.EndSession(context.Background(), EndSessionRequest{IDTokenHint: idToken, SessionToken: "sso-token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.SessionEnded || len(test.auth.endedSSOSessions) != 0 {
+		t.Error("expected the browser's session of another user to be kept")
+	}
+	if len(test.auth.revokedSessions) != 1 || test.auth.revokedSessions[0] != "session-1" {
+		t.Errorf("expected session-1 to be revoked, got %v", test.auth.revokedSessions)
+	}
+}
@@ -0,0 +1,594 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/pkg/config"
+	"auth-service/pkg/jwt"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const testIssuer = "https://auth.example.com"
+
+// newTestJWTManager creates a JWT manager with a fresh Ed25519 key
+func newTestJWTManager(t *testing.T) *jwt.JWTManager {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private_key.pem")
+	publicPath := filepath.Join(dir, "public_key.pem")
+	writePEM(t, privatePath, "PRIVATE KEY", privateDER)
+	writePEM(t, publicPath, "PUBLIC KEY", publicDER)
+
+	manager, err := jwt.NewJWTManager(&config.JWTConfig{
+		Issuer:               testIssuer,
+		PrivateKeyPath:       privatePath,
+		PublicKeyPath:        publicPath,
+		AccessTokenDuration:  15 * time.Minute,
+		RefreshTokenDuration: time.Hour,
+		RefreshTokenSecret:   "test-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newTestClientKey creates an Ed25519 key of a client and its JWKS
+func newTestClientKey(t *testing.T) (ed25519.PrivateKey, string) {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"test","x":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(publicKey))
+	return privateKey, jwks
+}
+
+// fakeAuthUseCase issues access tokens of a fixed session, validates the
+// access tokens it was given claims for and records revocations. The client
+// sessions all belong to the browser's SSO session, if any. Methods not
+// needed by a test panic through the nil interface
+type fakeAuthUseCase struct {
+	AuthUseCase
+	jwtManager       *jwt.JWTManager
+	sessions         []SessionResponse
+	ssoSession       *SSOSessionResponse
+	tokens           map[string]*jwt.Claims
+	revokedCodes     []*domain.AuthorizationCode
+	revokedSessions  []string
+	endedSSOSessions []string
+}
+
+func (uc *fakeAuthUseCase) IssueTokens(ctx context.Context, req IssueTokensRequest) (*AuthResponse, error) {
+	accessToken, err := uc.jwtManager.GenerateAccessToken(jwt.Claims{
+		UserID:    req.UserID,
+		ClientID:  req.ClientID,
+		SessionID: "session-1",
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResponse{AccessToken: accessToken, RefreshToken: "refresh-token"}, nil
+}
+
+func (uc *fakeAuthUseCase) ValidateAccessToken(ctx context.Context, token string, opts ...jwt.ValidationOption) (*jwt.Claims, error) {
+	claims, ok := uc.tokens[token]
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (uc *fakeAuthUseCase) ListSessions(ctx context.Context, userID, currentSessionID string) ([]SessionResponse, error) {
+	sessions := make([]SessionResponse, len(uc.sessions))
+	for i, session := range uc.sessions {
+		session.Current = session.ID == currentSessionID
+		sessions[i] = session
+	}
+	return sessions, nil
+}
+
+func (uc *fakeAuthUseCase) RevokeAuthorizationCodeTokens(ctx context.Context, code *domain.AuthorizationCode) error {
+	revoked := *code
+	uc.revokedCodes = append(uc.revokedCodes, &revoked)
+	return nil
+}
+
+func (uc *fakeAuthUseCase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	for i, session := range uc.sessions {
+		if session.ID == sessionID {
+			uc.sessions = append(uc.sessions[:i], uc.sessions[i+1:]...)
+			uc.revokedSessions = append(uc.revokedSessions, sessionID)
+			return nil
+		}
+	}
+	return domain.ErrSessionNotFound
+}
+
+func (uc *fakeAuthUseCase) FindSSOSession(ctx context.Context, token string) (*SSOSessionResponse, error) {
+	if uc.ssoSession == nil || token != uc.ssoSession.Token {
+		return nil, domain.ErrSessionNotFound
+	}
+	return uc.ssoSession, nil
+}
+
+func (uc *fakeAuthUseCase) EndSSOSession(ctx context.Context, sessionID string) ([]SessionResponse, error) {
+	uc.endedSSOSessions = append(uc.endedSSOSessions, sessionID)
+	uc.ssoSession = nil
+	ended := uc.sessions
+	uc.sessions = nil
+	return ended, nil
+}
+
+// fakeDenylist keeps revoked access tokens in memory
+type fakeDenylist struct {
+	AccessTokenDenylist
+	revoked map[string]bool
+}
+
+func (d *fakeDenylist) Contains(jti string) bool {
+	return d.revoked[jti]
+}
+
+func (d *fakeDenylist) Revoke(ctx context.Context, token *domain.RevokedAccessToken) error {
+	if d.revoked == nil {
+		d.revoked = make(map[string]bool)
+	}
+	d.revoked[token.JTI] = true
+	return nil
+}
+
+// fakeLogoutNotifier records the sessions whose clients would be notified
+type fakeLogoutNotifier struct {
+	LogoutNotifier
+	sessions []*domain.RefreshToken
+}
+
+func (n *fakeLogoutNotifier) Notify(ctx context.Context, sessions []*domain.RefreshToken) error {
+	n.sessions = append(n.sessions, sessions...)
+	return nil
+}
+
+// fakeClientRepo keeps clients in memory
+type fakeClientRepo struct {
+	repository.ClientRepository
+	clients map[string]*domain.Client
+}
+
+func (r *fakeClientRepo) FindByID(ctx context.Context, id string) (*domain.Client, error) {
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, domain.ErrClientNotFound
+	}
+	return client, nil
+}
+
+func (r *fakeClientRepo) List(ctx context.Context) ([]*domain.Client, error) {
+	clients := make([]*domain.Client, 0, len(r.clients))
+	for _, client := range r.clients {
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+func (r *fakeClientRepo) Create(ctx context.Context, client *domain.Client) error {
+	r.clients[client.ID] = client
+	return nil
+}
+
+func (r *fakeClientRepo) Update(ctx context.Context, client *domain.Client) error {
+	r.clients[client.ID] = client
+	return nil
+}
+
+// fakeAuthorizationCodeRepo keeps codes in memory, keyed by code hash
+type fakeAuthorizationCodeRepo struct {
+	repository.AuthorizationCodeRepository
+	codes map[string]*domain.AuthorizationCode
+}
+
+func (r *fakeAuthorizationCodeRepo) Create(ctx context.Context, code *domain.AuthorizationCode) error {
+	stored := *code
+	r.codes[code.CodeHash] = &stored
+	return nil
+}
+
+func (r *fakeAuthorizationCodeRepo) Consume(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, domain.ErrAuthCodeNotFound
+	}
+	if code.UsedAt != nil {
+		return nil, domain.ErrAuthCodeUsed
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	consumed := *code
+	return &consumed, nil
+}
+
+func (r *fakeAuthorizationCodeRepo) MarkReused(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, domain.ErrAuthCodeNotFound
+	}
+	if code.ReusedAt == nil {
+		now := time.Now()
+		code.ReusedAt = &now
+	}
+	found := *code
+	return &found, nil
+}
+
+func (r *fakeAuthorizationCodeRepo) RecordIssuedTokens(ctx context.Context, id uint, sessionID, accessTokenID string, accessTokenExpiresAt time.Time) error {
+	for _, code := range r.codes {
+		if code.ID != id {
+			continue
+		}
+		if code.ReusedAt != nil {
+			return domain.ErrAuthCodeUsed
+		}
+		code.SessionID = sessionID
+		code.AccessTokenID = accessTokenID
+		code.AccessTokenExpiresAt = &accessTokenExpiresAt
+		return nil
+	}
+	return domain.ErrAuthCodeNotFound
+}
+
+func (r *fakeAuthorizationCodeRepo) FindPendingConsent(ctx context.Context, codeHash string) (*domain.AuthorizationCode, error) {
+	code, ok := r.codes[codeHash]
+	if !ok || !code.ConsentPending || code.UsedAt != nil || time.Now().After(code.ExpiresAt) {
+		return nil, domain.ErrAuthCodeNotFound
+	}
+	found := *code
+	return &found, nil
+}
+
+func (r *fakeAuthorizationCodeRepo) ApproveConsent(ctx context.Context, id uint, expiresAt time.Time) error {
+	for _, code := range r.codes {
+		if code.ID == id && code.ConsentPending && code.UsedAt == nil {
+			code.ConsentPending = false
+			code.ExpiresAt = expiresAt
+			return nil
+		}
+	}
+	return domain.ErrAuthCodeNotFound
+}
+
+// fakeRefreshTokenRepo keeps refresh tokens in memory
+type fakeRefreshTokenRepo struct {
+	repository.RefreshTokenRepository
+	tokens []*domain.RefreshToken
+}
+
+func (r *fakeRefreshTokenRepo) FindByUserID(ctx context.Context, userID string) ([]*domain.RefreshToken, error) {
+	var tokens []*domain.RefreshToken
+	for _, token := range r.tokens {
+		if token.UserID == userID && !token.IsRevoked {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakeRefreshTokenRepo) RevokeAllByUserIDAndClientID(ctx context.Context, userID, clientID string) error {
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.ClientID == clientID {
+			token.IsRevoked = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) FindBySSOSessionID(ctx context.Context, ssoSessionID string) ([]*domain.RefreshToken, error) {
+	var tokens []*domain.RefreshToken
+	for _, token := range r.tokens {
+		if token.SSOSessionID == ssoSessionID && !token.IsRevoked {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *fakeRefreshTokenRepo) RevokeAllBySSOSessionID(ctx context.Context, ssoSessionID string) error {
+	for _, token := range r.tokens {
+		if token.SSOSessionID == ssoSessionID {
+			token.IsRevoked = true
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshTokenRepo) FindWithUnexpiredAccessTokens(ctx context.Context, userID, clientID string) ([]*domain.RefreshToken, error) {
+	var tokens []*domain.RefreshToken
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.ClientID == clientID && token.AccessTokenExpiresAt.After(time.Now()) {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+// fakeConsentRepo keeps consents in memory, keyed by user and client ID
+type fakeConsentRepo struct {
+	repository.ConsentRepository
+	consents map[string]*domain.Consent
+}
+
+func (r *fakeConsentRepo) Find(ctx context.Context, userID, clientID string) (*domain.Consent, error) {
+	consent, ok := r.consents[userID+" "+clientID]
+	if !ok {
+		return nil, domain.ErrConsentNotFound
+	}
+	return consent, nil
+}
+
+func (r *fakeConsentRepo) Save(ctx context.Context, consent *domain.Consent) error {
+	r.consents[consent.UserID+" "+consent.ClientID] = consent
+	return nil
+}
+
+func (r *fakeConsentRepo) Delete(ctx context.Context, userID, clientID string) error {
+	delete(r.consents, userID+" "+clientID)
+	return nil
+}
+
+// fakeUsedDPoPProofRepo keeps used proofs in memory
+type fakeUsedDPoPProofRepo struct {
+	repository.UsedDPoPProofRepository
+	used map[string]bool
+}
+
+func (r *fakeUsedDPoPProofRepo) Create(ctx context.Context, proof *domain.UsedDPoPProof) error {
+	key := proof.JKT + ":" + proof.JTI
+	if r.used[key] {
+		return domain.ErrDPoPProofReplayed
+	}
+	r.used[key] = true
+	return nil
+}
+
+// fakeDeviceCodeRepo keeps device authorizations in memory
+type fakeDeviceCodeRepo struct {
+	repository.DeviceCodeRepository
+	codes []*domain.DeviceCode
+}
+
+func (r *fakeDeviceCodeRepo) Create(ctx context.Context, code *domain.DeviceCode) error {
+	code.ID = uint(len(r.codes) + 1)
+	r.codes = append(r.codes, code)
+	return nil
+}
+
+func (r *fakeDeviceCodeRepo) FindByDeviceCodeHash(ctx context.Context, deviceCodeHash string) (*domain.DeviceCode, error) {
+	for _, code := range r.codes {
+		if code.DeviceCodeHash == deviceCodeHash {
+			found := *code
+			return &found, nil
+		}
+	}
+	return nil, domain.ErrDeviceCodeNotFound
+}
+
+func (r *fakeDeviceCodeRepo) FindPendingByUserCode(ctx context.Context, userCode string) (*domain.DeviceCode, error) {
+	for _, code := range r.codes {
+		if code.UserCode == userCode && code.Status == domain.DeviceCodePending && !code.IsExpired() {
+			found := *code
+			return &found, nil
+		}
+	}
+	return nil, domain.ErrDeviceCodeNotFound
+}
+
+func (r *fakeDeviceCodeRepo) Decide(ctx context.Context, id uint, status, userID string, authenticatedAt time.Time) error {
+	code := r.codes[id-1]
+	if code.Status != domain.DeviceCodePending {
+		return domain.ErrDeviceCodeNotFound
+	}
+	code.Status = status
+	code.UserID = userID
+	if status == domain.DeviceCodeApproved {
+		code.AuthenticatedAt = &authenticatedAt
+	}
+	return nil
+}
+
+func (r *fakeDeviceCodeRepo) RecordPoll(ctx context.Context, id uint, polledAt time.Time, interval int) error {
+	r.codes[id-1].LastPolledAt = &polledAt
+	r.codes[id-1].PollInterval = interval
+	return nil
+}
+
+func (r *fakeDeviceCodeRepo) Consume(ctx context.Context, id uint) error {
+	code := r.codes[id-1]
+	if code.Status != domain.DeviceCodeApproved || code.UsedAt != nil {
+		return domain.ErrDeviceCodeUsed
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	return nil
+}
+
+// fakePushedAuthorizationRequestRepo keeps pushed requests in memory, keyed
+// by request URI hash
+type fakePushedAuthorizationRequestRepo struct {
+	repository.PushedAuthorizationRequestRepository
+	requests map[string]*domain.PushedAuthorizationRequest
+}
+
+func (r *fakePushedAuthorizationRequestRepo) Create(ctx context.Context, request *domain.PushedAuthorizationRequest) error {
+	request.ID = uint(len(r.requests) + 1)
+	r.requests[request.RequestURIHash] = request
+	return nil
+}
+
+func (r *fakePushedAuthorizationRequestRepo) FindActive(ctx context.Context, requestURIHash string) (*domain.PushedAuthorizationRequest, error) {
+	request, ok := r.requests[requestURIHash]
+	if !ok || request.UsedAt != nil || time.Now().After(request.ExpiresAt) {
+		return nil, domain.ErrRequestURINotFound
+	}
+	return request, nil
+}
+
+func (r *fakePushedAuthorizationRequestRepo) Consume(ctx context.Context, id uint) error {
+	for _, request := range r.requests {
+		if request.ID == id {
+			if request.UsedAt != nil {
+				return domain.ErrRequestURINotFound
+			}
+			now := time.Now()
+			request.UsedAt = &now
+			return nil
+		}
+	}
+	return domain.ErrRequestURINotFound
+}
+
+// fakeSSOSessionRepo keeps SSO sessions in memory, keyed by ID
+type fakeSSOSessionRepo struct {
+	repository.SSOSessionRepository
+	sessions map[string]*domain.SSOSession
+}
+
+func (r *fakeSSOSessionRepo) Create(ctx context.Context, session *domain.SSOSession) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *fakeSSOSessionRepo) FindByTokenHash(ctx context.Context, tokenHash string) (*domain.SSOSession, error) {
+	for _, session := range r.sessions {
+		if session.TokenHash == tokenHash {
+			found := *session
+			return &found, nil
+		}
+	}
+	return nil, domain.ErrSessionNotFound
+}
+
+func (r *fakeSSOSessionRepo) Update(ctx context.Context, session *domain.SSOSession) error {
+	stored := *session
+	r.sessions[session.ID] = &stored
+	return nil
+}
+
+func (r *fakeSSOSessionRepo) Touch(ctx context.Context, id string, lastActiveAt time.Time) error {
+	if session, ok := r.sessions[id]; ok {
+		session.LastActiveAt = lastActiveAt
+	}
+	return nil
+}
+
+func (r *fakeSSOSessionRepo) Revoke(ctx context.Context, id string) error {
+	if session, ok := r.sessions[id]; ok {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+// fakeSecurityEventRepo keeps security events in memory
+type fakeSecurityEventRepo struct {
+	repository.SecurityEventRepository
+	events []*domain.SecurityEvent
+}
+
+func (r *fakeSecurityEventRepo) Create(ctx context.Context, event *domain.SecurityEvent) error {
+	event.CreatedAt = time.Now()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeSecurityEventRepo) CountSince(ctx context.Context, userID, eventType string, since time.Time) (int64, error) {
+	var count int64
+	for _, event := range r.events {
+		if event.UserID == userID && event.Type == eventType && !event.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// oauthTest holds an oauthUseCase wired to in-memory fakes
+type oauthTest struct {
+	*oauthUseCase
+	auth     *fakeAuthUseCase
+	clients  *fakeClientRepo
+	codes    *fakeAuthorizationCodeRepo
+	devices  *fakeDeviceCodeRepo
+	consents *fakeConsentRepo
+	pushed   *fakePushedAuthorizationRequestRepo
+	events   *fakeSecurityEventRepo
+}
+
+// newOAuthTest creates an oauthUseCase with a public client web-app
+func newOAuthTest(t *testing.T) *oauthTest {
+	t.Helper()
+
+	jwtManager := newTestJWTManager(t)
+	test := &oauthTest{
+		auth: &fakeAuthUseCase{jwtManager: jwtManager},
+		clients: &fakeClientRepo{clients: map[string]*domain.Client{
+			"web-app": {
+				ID:           "web-app",
+				Name:         "Web App",
+				RedirectURIs: []string{"https://app.example.com/callback"},
+				GrantTypes:   []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+				Scopes:       []string{ScopeOpenID, ScopeProfile},
+			},
+		}},
+		codes:    &fakeAuthorizationCodeRepo{codes: map[string]*domain.AuthorizationCode{}},
+		devices:  &fakeDeviceCodeRepo{},
+		consents: &fakeConsentRepo{consents: map[string]*domain.Consent{}},
+		pushed:   &fakePushedAuthorizationRequestRepo{requests: map[string]*domain.PushedAuthorizationRequest{}},
+		events:   &fakeSecurityEventRepo{},
+	}
+	test.oauthUseCase = &oauthUseCase{
+		authUseCase:           test.auth,
+		clientRepo:            test.clients,
+		authorizationCodeRepo: test.codes,
+		deviceCodeRepo:        test.devices,
+		consentRepo:           test.consents,
+		securityEventRepo:     test.events,
+		jwtManager:            jwtManager,
+		deviceVerificationURI: testIssuer + "/device",
+
+		pushedAuthorizationRequestRepo: test.pushed,
+	}
+	return test
+}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/pkg/jwt"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Settings for delivering back-channel logout notifications
+const (
+	// logoutPollInterval is how often due deliveries are looked for, in case
+	// they were scheduled by another instance or are due for a retry
+	logoutPollInterval = 15 * time.Second
+	// logoutDeliveryBatchSize is how many deliveries are claimed at once
+	logoutDeliveryBatchSize = 20
+	// logoutDeliveryLease is how long a claimed delivery is skipped by other
+	// instances. It exceeds the time needed to attempt a batch
+	logoutDeliveryLease = 5 * time.Minute
+)
+
+// logoutRetryDelays are the delays before retrying a failed delivery,
+// after which it is given up
+var logoutRetryDelays = []time.Duration{
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+// errLogoutUndeliverable is returned for notifications that can't ever be
+// delivered, because the client was deleted or no longer wants them
+var errLogoutUndeliverable = errors.New("client is no longer registered for back-channel logout")
+
+// LogoutNotifier notifies clients registered for back-channel logout when
+// a session of theirs ends (OpenID Connect Back-Channel Logout 1.0)
+type LogoutNotifier interface {
+	// Notify schedules notifications of the clients of ended sessions
+	Notify(ctx context.Context, sessions []*domain.RefreshToken) error
+	// Run delivers scheduled notifications until ctx is done
+	Run(ctx context.Context)
+}
+
+type logoutNotifier struct {
+	repo       repository.LogoutDeliveryRepository
+	clientRepo repository.ClientRepository
+	jwtManager *jwt.JWTManager
+	httpClient *http.Client
+	wake       chan struct{}
+}
+
+// NewLogoutNotifier creates a new logout notifier
+func NewLogoutNotifier(repo repository.LogoutDeliveryRepository, clientRepo repository.ClientRepository, jwtManager *jwt.JWTManager) LogoutNotifier {
+	return &logoutNotifier{
+		repo:       repo,
+		clientRepo: clientRepo,
+		jwtManager: jwtManager,
+		// Clients must not redirect the notification (section 2.5), and
+		// the URI must not point at the server's own network
+		httpClient: newOutboundHTTPClient(5 * time.Second),
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+func (n *logoutNotifier) Notify(ctx context.Context, sessions []*domain.RefreshToken) error {
+	// A session may be represented by several tokens of its family
+	notified := make(map[string]bool)
+	scheduled := false
+	for _, session := range sessions {
+		if session.ClientID == "" || session.IsExpired() || notified[session.FamilyID] {
+			continue
+		}
+		notified[session.FamilyID] = true
+
+		client, err := n.clientRepo.FindByID(ctx, session.ClientID)
+		if err == domain.ErrClientNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if client.BackchannelLogoutURI == "" {
+			continue
+		}
+
+		err = n.repo.Create(ctx, &domain.LogoutDelivery{
+			ClientID:      client.ID,
+			UserID:        session.UserID,
+			SessionID:     session.FamilyID,
+			Status:        domain.LogoutDeliveryPending,
+			NextAttemptAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to schedule back-channel logout: %w", err)
+		}
+		scheduled = true
+	}
+
+	if scheduled {
+		select {
+		case n.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (n *logoutNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(logoutPollInterval)
+	defer ticker.Stop()
+
+	for {
+		n.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-n.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue attempts all due deliveries, batch by batch
+func (n *logoutNotifier) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := n.repo.ClaimDue(ctx, logoutDeliveryBatchSize, logoutDeliveryLease)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to claim logout deliveries: %v", err)
+			}
+			return
+		}
+
+		for _, delivery := range deliveries {
+			n.attempt(ctx, delivery)
+		}
+		if len(deliveries) < logoutDeliveryBatchSize {
+			return
+		}
+	}
+}
+
+// attempt delivers a notification once and records the outcome
+func (n *logoutNotifier) attempt(ctx context.Context, delivery *domain.LogoutDelivery) {
+	err := n.deliver(ctx, delivery)
+
+	delivery.Attempts++
+	switch {
+	case err == nil:
+		now := time.Now()
+		delivery.Status = domain.LogoutDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case err == errLogoutUndeliverable || delivery.Attempts > len(logoutRetryDelays):
+		delivery.Status = domain.LogoutDeliveryFailed
+		delivery.LastError = err.Error()
+		log.Printf("giving up back-channel logout %d of client %s: %v", delivery.ID, delivery.ClientID, err)
+	default:
+		delivery.NextAttemptAt = time.Now().Add(logoutRetryDelays[delivery.Attempts-1])
+		delivery.LastError = err.Error()
+	}
+
+	if err := n.repo.Update(ctx, delivery); err != nil && ctx.Err() == nil {
+		log.Printf("failed to update logout delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// deliver POSTs a fresh logout token to the client's back-channel logout URI
+// Clients respond with 200 or 204 on success (section 2.8)
+func (n *logoutNotifier) deliver(ctx context.Context, delivery *domain.LogoutDelivery) error {
+	client, err := n.clientRepo.FindByID(ctx, delivery.ClientID)
+	if err == domain.ErrClientNotFound {
+		return errLogoutUndeliverable
+	}
+	if err != nil {
+		return err
+	}
+	if client.BackchannelLogoutURI == "" {
+		return errLogoutUndeliverable
+	}
+
+	logoutToken, err := n.jwtManager.GenerateLogoutToken(delivery.UserID, client.ID, delivery.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to generate logout token: %w", err)
+	}
+
+	form := url.Values{"logout_token": {logoutToken}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.BackchannelLogoutURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("back-channel logout URI responded with %s", resp.Status)
+	}
+	return nil
+}
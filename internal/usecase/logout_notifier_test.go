@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogoutNotifierRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("logout token was posted to a loopback address")
+	}))
+	defer server.Close()
+
+	clients := &fakeClientRepo{clients: map[string]*domain.Client{
+		"web-app": {ID: "web-app", BackchannelLogoutURI: server.URL + "/backchannel-logout"},
+	}}
+	notifier := NewLogoutNotifier(nil, clients, newTestJWTManager(t)).(*logoutNotifier)
+
+	err := notifier.deliver(context.Background(), &domain.LogoutDelivery{ClientID: "web-app", UserID: "user-1", SessionID: "sid"})
+	if !errors.Is(err, errInternalAddress) {
+		t.Errorf("got %v, want %v", err, errInternalAddress)
+	}
+}
+
+func TestValidateClientLogoutURIs(t *testing.T) {
+	tests := []struct {
+		name         string
+		backchannel  string
+		frontchannel string
+		wantErr      bool
+	}{
+		{"public URIs", "https://app.example.com/backchannel-logout", "https://app.example.com/frontchannel-logout", false},
+		{"internal back-channel URI", "https://10.0.0.5/backchannel-logout", "", true},
+		{"back-channel URI on localhost", "https://localhost/backchannel-logout", "", true},
+		// Browsers load front-channel URIs, the server never connects to them
+		{"internal front-channel URI", "", "https://localhost:8443/frontchannel-logout", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &domain.Client{
+				Name:                  "Web App",
+				RedirectURIs:          []string{testRedirectURI},
+				GrantTypes:            []string{GrantTypeAuthorizationCode},
+				BackchannelLogoutURI:  tt.backchannel,
+				FrontchannelLogoutURI: tt.frontchannel,
+			}
+			err := validateClient(client)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+)
+
+// machineClientCacheTTL bounds how long another replica may keep accepting
+// machine tokens after their client was deleted or lost the
+// client_credentials grant
+const machineClientCacheTTL = 30 * time.Second
+
+// machineClientCache caches whether clients may use the client_credentials
+// grant, so that validating a machine token does not query the database on
+// every request
+type machineClientCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]machineClientEntry
+}
+
+type machineClientEntry struct {
+	active    bool // false for deleted clients and clients without the grant
+	expiresAt time.Time
+}
+
+func newMachineClientCache(ttl time.Duration) *machineClientCache {
+	return &machineClientCache{
+		ttl:     ttl,
+		entries: make(map[string]machineClientEntry),
+	}
+}
+
+// get returns whether a client is active, if cached and not expired
+func (c *machineClientCache) get(clientID string) (active, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[clientID]
+	if !ok {
+		return false, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, clientID)
+		return false, false
+	}
+	return entry.active, true
+}
+
+// set caches whether a client is active
+func (c *machineClientCache) set(clientID string, active bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[clientID] = machineClientEntry{
+		active:    active,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
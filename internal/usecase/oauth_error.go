@@ -0,0 +1,37 @@
+package usecase
+
+// OAuth 2.0 error codes (RFC 6749 sections 4.1.2.1 and 5.2, RFC 8628 section 3.5,
+// RFC 8707 section 2, RFC 9449 sections 5 and 8, OpenID Connect Core 1.0 section 3.1.2.6)
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorAccessDenied            = "access_denied"
+	ErrorAuthorizationPending    = "authorization_pending"
+	ErrorSlowDown                = "slow_down"
+	ErrorExpiredToken            = "expired_token"
+	ErrorInvalidTarget           = "invalid_target"
+	ErrorInvalidDPoPProof        = "invalid_dpop_proof"
+	ErrorUseDPoPNonce            = "use_dpop_nonce"
+	ErrorLoginRequired           = "login_required"
+	ErrorConsentRequired         = "consent_required"
+)
+
+// OAuthError is an error returned to OAuth clients, with its error code
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+// NewOAuthError creates a new OAuth error
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
@@ -0,0 +1,862 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/internal/repository"
+	"auth-service/pkg/jwt"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Token type hints (RFC 7009, RFC 7662)
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// Supported grant and response types
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeDeviceCode        = "urn:ietf:params:oauth:grant-type:device_code"
+	GrantTypeTokenExchange     = "urn:ietf:params:oauth:grant-type:token-exchange"
+	ResponseTypeCode           = "code"
+)
+
+// authorizationCodeTTL is how long an authorization code can be exchanged
+const authorizationCodeTTL = time.Minute
+
+// consentTTL is how long the user has to decide on a consent prompt
+const consentTTL = 10 * time.Minute
+
+// OAuthUseCase defines the interface for OAuth 2.0 protocol use cases
+type OAuthUseCase interface {
+	// AuthenticateClient authenticates a confidential client and returns its ID
+	AuthenticateClient(ctx context.Context, credentials ClientCredentials) (string, error)
+	Introspect(ctx context.Context, req IntrospectionRequest) (*IntrospectionResponse, error)
+	Revoke(ctx context.Context, req RevocationRequest) error
+	UserInfo(ctx context.Context, userID, scope string) (*UserInfoResponse, error)
+	Discovery() *ProviderMetadata
+	// ValidateAuthorizationRequest returns domain.ErrInvalidClient or
+	// domain.ErrInvalidRedirectURI if the error must not be redirected to
+	// the client, or an *OAuthError otherwise
+	ValidateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) error
+	// PushAuthorizationRequest validates and stores an authorization request
+	// of an authenticated client (RFC 9126)
+	PushAuthorizationRequest(ctx context.Context, req AuthorizationRequest, credentials ClientCredentials) (*PushedAuthorizationResponse, error)
+	// ResolveAuthorizationRequest replaces a request referencing a pushed
+	// request by its request_uri with the pushed request, returning
+	// domain.ErrRequestURINotFound if it is unknown, used or expired
+	ResolveAuthorizationRequest(ctx context.Context, req AuthorizationRequest) (AuthorizationRequest, error)
+	// Authorize authenticates the user and issues an authorization code, or
+	// a consent challenge if a third-party client needs the user's consent.
+	// The user's SSO session is started and returned with the response
+	Authorize(ctx context.Context, req AuthorizationRequest, email, password string) (*AuthorizationResponse, error)
+	// AuthorizeWithSession authorizes the user of the browser's SSO session
+	// like Authorize, returning domain.ErrLoginRequired if the user has to
+	// sign in, or an *OAuthError if the request doesn't allow that (prompt=none)
+	AuthorizeWithSession(ctx context.Context, req AuthorizationRequest) (*AuthorizationResponse, error)
+	// DecideConsent issues the authorization code of an approved consent
+	// challenge, returning domain.ErrAuthCodeNotFound if it is unknown or expired
+	DecideConsent(ctx context.Context, req ConsentDecisionRequest) (*AuthorizationResponse, error)
+	// Token issues tokens for a grant, bound to the key of the request's
+	// DPoP proof if it has one
+	Token(ctx context.Context, req TokenRequest) (*AuthResponse, error)
+	// DPoPNonce returns the nonce clients must include in DPoP proofs
+	DPoPNonce() string
+	DeviceAuthorization(ctx context.Context, req DeviceAuthorizationRequest) (*DeviceAuthorizationResponse, error)
+	// DeviceVerification looks up a pending device authorization by user code
+	// for a signed-in user, returning domain.ErrDeviceCodeNotFound if there is
+	// none or domain.ErrTooManyAttempts if the user entered too many unknown codes
+	DeviceVerification(ctx context.Context, userID, userCode string) (*DeviceVerificationResponse, error)
+	// DecideDevice approves or denies a pending device authorization for the
+	// user of a session, with the same errors as DeviceVerification
+	DecideDevice(ctx context.Context, req DeviceDecisionRequest) error
+	// EndSession logs the user out of the browser's SSO session and the
+	// session of the ID token hint, returning domain.ErrInvalidIDTokenHint,
+	// domain.ErrInvalidClient or
+	// domain.ErrInvalidRedirectURI if the request can't be trusted
+	EndSession(ctx context.Context, req EndSessionRequest) (*EndSessionResponse, error)
+}
+
+// clientAuthMethods lists the supported authentication methods of confidential clients
+var clientAuthMethods = []string{
+	domain.ClientAuthSecretBasic,
+	domain.ClientAuthSecretPost,
+	domain.ClientAuthPrivateKeyJWT,
+	domain.ClientAuthTLS,
+	domain.ClientAuthSelfSignedTLS,
+}
+
+// supportedGrantTypes lists the grant types clients can be registered for
+var supportedGrantTypes = []string{
+	GrantTypeAuthorizationCode,
+	GrantTypeRefreshToken,
+	GrantTypeClientCredentials,
+	GrantTypeDeviceCode,
+	GrantTypeTokenExchange,
+}
+
+type oauthUseCase struct {
+	authUseCase             AuthUseCase
+	clientRepo              repository.ClientRepository
+	refreshTokenRepo        repository.RefreshTokenRepository
+	authorizationCodeRepo   repository.AuthorizationCodeRepository
+	usedClientAssertionRepo repository.UsedClientAssertionRepository
+	deviceCodeRepo          repository.DeviceCodeRepository
+	consentRepo             repository.ConsentRepository
+	securityEventRepo       repository.SecurityEventRepository
+	jwtManager              *jwt.JWTManager
+	clientKeyCache          *clientKeyCache
+	deviceVerificationURI   string
+
+	pushedAuthorizationRequestRepo repository.PushedAuthorizationRequestRepository
+}
+
+// NewOAuthUseCase creates a new OAuth use case
+func NewOAuthUseCase(
+	authUseCase AuthUseCase,
+	clientRepo repository.ClientRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	authorizationCodeRepo repository.AuthorizationCodeRepository,
+	usedClientAssertionRepo repository.UsedClientAssertionRepository,
+	deviceCodeRepo repository.DeviceCodeRepository,
+	consentRepo repository.ConsentRepository,
+	pushedAuthorizationRequestRepo repository.PushedAuthorizationRequestRepository,
+	securityEventRepo repository.SecurityEventRepository,
+	jwtManager *jwt.JWTManager,
+	deviceVerificationURI string,
+) OAuthUseCase {
+	return &oauthUseCase{
+		authUseCase:             authUseCase,
+		clientRepo:              clientRepo,
+		refreshTokenRepo:        refreshTokenRepo,
+		authorizationCodeRepo:   authorizationCodeRepo,
+		usedClientAssertionRepo: usedClientAssertionRepo,
+		deviceCodeRepo:          deviceCodeRepo,
+		consentRepo:             consentRepo,
+		securityEventRepo:       securityEventRepo,
+		jwtManager:              jwtManager,
+		clientKeyCache:          newClientKeyCache(),
+		deviceVerificationURI:   deviceVerificationURI,
+
+		pushedAuthorizationRequestRepo: pushedAuthorizationRequestRepo,
+	}
+}
+
+func (uc *oauthUseCase) AuthenticateClient(ctx context.Context, credentials ClientCredentials) (string, error) {
+	client, err := findClient(ctx, uc.clientRepo, clientIDOf(credentials))
+	if err != nil {
+		return "", err
+	}
+
+	if err := uc.verifyClient(ctx, client, credentials); err != nil {
+		return "", err
+	}
+
+	return client.ID, nil
+}
+
+func (uc *oauthUseCase) Introspect(ctx context.Context, req IntrospectionRequest) (*IntrospectionResponse, error) {
+	// The hint only decides which lookup is tried first
+	lookups := []func(context.Context, string) (*IntrospectionResponse, error){
+		uc.introspectAccessToken,
+		uc.introspectRefreshToken,
+	}
+	if req.TokenTypeHint == TokenTypeHintRefreshToken {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+
+	for _, lookup := range lookups {
+		resp, err := lookup(ctx, req.Token)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Active {
+			return resp, nil
+		}
+	}
+
+	return &IntrospectionResponse{Active: false}, nil
+}
+
+// introspectAccessToken validates an access token, including revocation checks
+func (uc *oauthUseCase) introspectAccessToken(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	claims, err := uc.authUseCase.ValidateAccessToken(ctx, token)
+	if err != nil {
+		if err == domain.ErrInvalidToken {
+			return &IntrospectionResponse{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     claims.Scope,
+		TokenType: TokenTypeHintAccessToken,
+		Sub:       claims.Subject,
+		Username:  claims.Email,
+		ClientID:  claims.ClientID,
+		Exp:       claims.ExpiresAt.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+		Aud:       claims.Audience,
+		Act:       claims.Act,
+		Cnf:       claims.Cnf,
+	}, nil
+}
+
+// introspectRefreshToken looks up a refresh token in the database
+func (uc *oauthUseCase) introspectRefreshToken(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	refreshToken, err := uc.refreshTokenRepo.FindByTokenHash(ctx, uc.jwtManager.HashRefreshToken(token))
+	if err != nil {
+		if err == domain.ErrRefreshTokenNotFound {
+			return &IntrospectionResponse{Active: false}, nil
+		}
+		return nil, err
+	}
+
+	if !refreshToken.IsValid() {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	return &IntrospectionResponse{
+		Active:    true,
+		Scope:     refreshToken.Scope,
+		TokenType: TokenTypeHintRefreshToken,
+		Sub:       refreshToken.UserID,
+		ClientID:  refreshToken.ClientID,
+		Exp:       refreshToken.ExpiresAt.Unix(),
+		Iat:       refreshToken.CreatedAt.Unix(),
+	}, nil
+}
+
+func (uc *oauthUseCase) Revoke(ctx context.Context, req RevocationRequest) error {
+	// The hint only decides which lookup is tried first
+	revocations := []func(context.Context, string, string) (bool, error){
+		uc.revokeAccessToken,
+		uc.revokeRefreshToken,
+	}
+	if req.TokenTypeHint == TokenTypeHintRefreshToken {
+		revocations[0], revocations[1] = revocations[1], revocations[0]
+	}
+
+	for _, revoke := range revocations {
+		revoked, err := revoke(ctx, req.ClientID, req.Token)
+		if err != nil || revoked {
+			return err
+		}
+	}
+
+	// Invalid tokens do not cause an error (RFC 7009 section 2.2)
+	return nil
+}
+
+// revokeAccessToken revokes a valid access token through the jti denylist
+// Tokens not issued to the client are left alone, but reported as handled
+func (uc *oauthUseCase) revokeAccessToken(ctx context.Context, clientID, token string) (bool, error) {
+	claims, err := uc.jwtManager.ValidateToken(token)
+	if err != nil || claims.ID == "" {
+		return false, nil
+	}
+	if claims.ClientID != clientID {
+		return true, nil
+	}
+
+	return true, uc.authUseCase.RevokeAccessToken(ctx, claims.UserID, token)
+}
+
+// revokeRefreshToken revokes a known refresh token
+// Tokens not issued to the client are left alone, but reported as handled
+func (uc *oauthUseCase) revokeRefreshToken(ctx context.Context, clientID, token string) (bool, error) {
+	refreshToken, err := uc.refreshTokenRepo.FindByTokenHash(ctx, uc.jwtManager.HashRefreshToken(token))
+	if err != nil {
+		if err == domain.ErrRefreshTokenNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if refreshToken.ClientID != clientID {
+		return true, nil
+	}
+
+	return true, uc.authUseCase.Logout(ctx, token)
+}
+
+func (uc *oauthUseCase) UserInfo(ctx context.Context, userID, scope string) (*UserInfoResponse, error) {
+	if !hasScope(scope, ScopeOpenID) {
+		return nil, domain.ErrInsufficientScope
+	}
+
+	user, err := uc.authUseCase.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UserInfoResponse{Sub: user.ID}
+	if hasScope(scope, ScopeProfile) {
+		resp.Name = user.Name
+	}
+	if hasScope(scope, ScopeEmail) {
+		resp.Email = user.Email
+	}
+
+	return resp, nil
+}
+
+func (uc *oauthUseCase) Discovery() *ProviderMetadata {
+	issuer := uc.jwtManager.GetIssuer()
+
+	return &ProviderMetadata{
+		Issuer:                                     issuer,
+		AuthorizationEndpoint:                      issuer + "/oauth/authorize",
+		TokenEndpoint:                              issuer + "/oauth/token",
+		JWKSURI:                                    issuer + "/.well-known/jwks.json",
+		UserInfoEndpoint:                           issuer + "/userinfo",
+		IntrospectionEndpoint:                      issuer + "/oauth/introspect",
+		RevocationEndpoint:                         issuer + "/oauth/revoke",
+		DeviceAuthorizationEndpoint:                issuer + "/oauth/device_authorization",
+		PushedAuthorizationRequestEndpoint:         issuer + "/oauth/par",
+		EndSessionEndpoint:                         issuer + "/oauth/logout",
+		ScopesSupported:                            supportedScopes,
+		ResponseTypesSupported:                     []string{ResponseTypeCode},
+		GrantTypesSupported:                        supportedGrantTypes,
+		CodeChallengeMethodsSupported:              []string{CodeChallengeMethodS256},
+		ClaimsSupported:                            []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "at_hash", "sid", "name", "email"},
+		SubjectTypesSupported:                      []string{"public"},
+		IDTokenSigningAlgValuesSupported:           []string{uc.jwtManager.GetSigningAlgorithm()},
+		TokenEndpointAuthMethodsSupported:          append([]string{domain.ClientAuthNone}, clientAuthMethods...),
+		TokenEndpointAuthSigningAlgValuesSupported: jwt.SupportedAlgorithms,
+		IntrospectionEndpointAuthMethodsSupported:  clientAuthMethods,
+		RevocationEndpointAuthMethodsSupported:     clientAuthMethods,
+		DPoPSigningAlgValuesSupported:              jwt.SupportedAlgorithms,
+		TLSClientCertificateBoundAccessTokens:      true,
+		FrontchannelLogoutSupported:                true,
+		FrontchannelLogoutSessionSupported:         true,
+		BackchannelLogoutSupported:                 true,
+		BackchannelLogoutSessionSupported:          true,
+	}
+}
+
+func (uc *oauthUseCase) ValidateAuthorizationRequest(ctx context.Context, req AuthorizationRequest) error {
+	// Without a known client and exact redirect URI match, errors can't be
+	// sent back to the client
+	client, err := findClient(ctx, uc.clientRepo, req.ClientID)
+	if err != nil {
+		return err
+	}
+	if err := validateAuthorizationRequest(client, req); err != nil {
+		return err
+	}
+
+	if client.RequirePushedAuthorizationRequests && req.RequestURI == "" {
+		return NewOAuthError(ErrorInvalidRequest, "client must use pushed authorization requests")
+	}
+
+	return nil
+}
+
+// validateAuthorizationRequest checks an authorization request against the
+// settings of the client
+func validateAuthorizationRequest(client *domain.Client, req AuthorizationRequest) error {
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return domain.ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != ResponseTypeCode {
+		return NewOAuthError(ErrorUnsupportedResponseType, "only the code response type is supported")
+	}
+	if !client.AllowsGrantType(GrantTypeAuthorizationCode) {
+		return NewOAuthError(ErrorUnauthorizedClient, "client is not allowed to use the authorization code flow")
+	}
+	if _, err := parsePrompt(req); err != nil {
+		return err
+	}
+	if req.CodeChallenge == "" {
+		return NewOAuthError(ErrorInvalidRequest, "code_challenge is required")
+	}
+	if req.CodeChallengeMethod != CodeChallengeMethodS256 {
+		return NewOAuthError(ErrorInvalidRequest, "code_challenge_method must be S256")
+	}
+	if !isValidCodeChallenge(req.CodeChallenge) {
+		return NewOAuthError(ErrorInvalidRequest, "invalid code_challenge")
+	}
+	scope, err := normalizeScope(req.Scope)
+	if err != nil || !clientAllowsScope(client, scope) {
+		return NewOAuthError(ErrorInvalidScope, "scope not allowed for this client")
+	}
+
+	return nil
+}
+
+func (uc *oauthUseCase) Authorize(ctx context.Context, req AuthorizationRequest, email, password string) (*AuthorizationResponse, error) {
+	if err := uc.ValidateAuthorizationRequest(ctx, req); err != nil {
+		return nil, err
+	}
+
+	user, err := uc.authUseCase.AuthenticateUser(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := uc.authUseCase.StartSSOSession(ctx, user.ID, req.SessionToken, req.Client)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := uc.authorizeUser(ctx, req, session, false)
+	if err != nil {
+		return nil, err
+	}
+	resp.Session = session
+	return resp, nil
+}
+
+func (uc *oauthUseCase) AuthorizeWithSession(ctx context.Context, req AuthorizationRequest) (*AuthorizationResponse, error) {
+	if err := uc.ValidateAuthorizationRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	prompt, err := parsePrompt(req)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := uc.authUseCase.FindSSOSession(ctx, req.SessionToken)
+	if err != nil && err != domain.ErrSessionNotFound {
+		return nil, err
+	}
+	if session == nil || !prompt.allowsSession(session) {
+		if prompt.none {
+			return nil, NewOAuthError(ErrorLoginRequired, "the user must sign in")
+		}
+		return nil, domain.ErrLoginRequired
+	}
+
+	return uc.authorizeUser(ctx, req, session, prompt.none)
+}
+
+// authorizeUser issues an authorization code to the user of an SSO session,
+// or a consent challenge if a third-party client needs the user's consent.
+// Without user interaction, missing consent is an error instead
+func (uc *oauthUseCase) authorizeUser(ctx context.Context, req AuthorizationRequest, session *SSOSessionResponse, noInteraction bool) (*AuthorizationResponse, error) {
+	if req.RequestURI != "" {
+		if err := uc.consumeRequestURI(ctx, req.RequestURI); err != nil {
+			return nil, err
+		}
+	}
+
+	client, err := findClient(ctx, uc.clientRepo, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+
+	scope, _ := normalizeScope(req.Scope)
+	consentRequired, err := uc.requiresConsent(ctx, client, session.UserID, scope)
+	if err != nil {
+		return nil, err
+	}
+	if consentRequired && noInteraction {
+		return nil, NewOAuthError(ErrorConsentRequired, "the user must consent to the requested scopes")
+	}
+
+	// A code awaiting consent is created right away, so the prompt only
+	// needs to carry the code as its challenge
+	now := time.Now()
+	expiresAt := now.Add(authorizationCodeTTL)
+	if consentRequired {
+		expiresAt = now.Add(consentTTL)
+	}
+	authorizationCode := &domain.AuthorizationCode{
+		CodeHash:        hashToken(code),
+		ClientID:        req.ClientID,
+		UserID:          session.UserID,
+		RedirectURI:     req.RedirectURI,
+		Scope:           scope,
+		Nonce:           req.Nonce,
+		State:           req.State,
+		CodeChallenge:   req.CodeChallenge,
+		ExpiresAt:       expiresAt,
+		ConsentPending:  consentRequired,
+		AuthenticatedAt: session.AuthenticatedAt,
+		IPAddress:       req.Client.IPAddress,
+		UserAgent:       req.Client.UserAgent,
+		SSOSessionID:    session.ID,
+	}
+	if err := uc.authorizationCodeRepo.Create(ctx, authorizationCode); err != nil {
+		return nil, fmt.Errorf("failed to save authorization code: %w", err)
+	}
+
+	if consentRequired {
+		return &AuthorizationResponse{
+			RedirectURI:      req.RedirectURI,
+			ConsentChallenge: code,
+			Consent: &ConsentPrompt{
+				ClientName: client.Name,
+				Scopes:     describeScopes(scope),
+			},
+		}, nil
+	}
+
+	return &AuthorizationResponse{
+		RedirectURI: req.RedirectURI,
+		Code:        code,
+		State:       req.State,
+	}, nil
+}
+
+func (uc *oauthUseCase) DecideConsent(ctx context.Context, req ConsentDecisionRequest) (*AuthorizationResponse, error) {
+	codeHash := hashToken(req.ConsentChallenge)
+	code, err := uc.authorizationCodeRepo.FindPendingConsent(ctx, codeHash)
+	if err != nil {
+		return nil, err
+	}
+
+	// A denied challenge is used up, so it can't be approved later
+	if !req.Approve {
+		if _, err := uc.authorizationCodeRepo.Consume(ctx, codeHash); err != nil {
+			if err == domain.ErrAuthCodeUsed {
+				return nil, domain.ErrAuthCodeNotFound
+			}
+			return nil, err
+		}
+		return &AuthorizationResponse{RedirectURI: code.RedirectURI, State: code.State}, nil
+	}
+
+	if err := uc.grantConsent(ctx, code.UserID, code.ClientID, code.Scope); err != nil {
+		return nil, err
+	}
+	if err := uc.authorizationCodeRepo.ApproveConsent(ctx, code.ID, time.Now().Add(authorizationCodeTTL)); err != nil {
+		return nil, err
+	}
+
+	return &AuthorizationResponse{
+		RedirectURI: code.RedirectURI,
+		Code:        req.ConsentChallenge,
+		State:       code.State,
+	}, nil
+}
+
+// requiresConsent checks if the user must consent before a client receives
+// tokens for a scope. First-party clients are trusted, third-party clients
+// need consent for every scope the user did not grant them yet
+func (uc *oauthUseCase) requiresConsent(ctx context.Context, client *domain.Client, userID, scope string) (bool, error) {
+	if client.FirstParty {
+		return false, nil
+	}
+
+	consent, err := uc.consentRepo.Find(ctx, userID, client.ID)
+	if err != nil {
+		if err == domain.ErrConsentNotFound {
+			return true, nil
+		}
+		return false, err
+	}
+	return !consent.Covers(scope), nil
+}
+
+// grantConsent adds scopes to the consent of a user for a client
+func (uc *oauthUseCase) grantConsent(ctx context.Context, userID, clientID, scope string) error {
+	granted := ""
+	consent, err := uc.consentRepo.Find(ctx, userID, clientID)
+	if err == nil {
+		granted = consent.Scope
+	} else if err != domain.ErrConsentNotFound {
+		return err
+	}
+
+	err = uc.consentRepo.Save(ctx, &domain.Consent{
+		UserID:   userID,
+		ClientID: clientID,
+		Scope:    mergeScopes(granted, scope),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save consent: %w", err)
+	}
+	return nil
+}
+
+func (uc *oauthUseCase) Token(ctx context.Context, req TokenRequest) (*AuthResponse, error) {
+	if req.GrantType == "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "grant_type is required")
+	}
+
+	client, err := uc.authenticateTokenClient(ctx, req.Credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.Contains(supportedGrantTypes, req.GrantType) {
+		return nil, NewOAuthError(ErrorUnsupportedGrantType, "unsupported grant type")
+	}
+	if !client.AllowsGrantType(req.GrantType) {
+		return nil, NewOAuthError(ErrorUnauthorizedClient, "client is not allowed to use this grant type")
+	}
+
+	// Issued tokens are bound to the key of a DPoP proof (RFC 9449 section 5)
+	// and to the client certificate of mutual TLS (RFC 8705 section 3)
+	if req.DPoP != nil {
+		jkt, err := uc.authUseCase.VerifyDPoPProof(ctx, *req.DPoP)
+		if err != nil {
+			return nil, dpopError(err)
+		}
+		req.cnf.JKT = jkt
+	}
+	if req.Credentials.Certificate != nil {
+		req.cnf.X5TS256 = jwt.CertificateThumbprint(req.Credentials.Certificate)
+	}
+
+	// Token exchange checks its targets against the exchange policy instead
+	if req.GrantType != GrantTypeTokenExchange {
+		for _, resource := range req.Resource {
+			if !isResourceIndicator(resource) {
+				return nil, NewOAuthError(ErrorInvalidTarget, "resource must be an absolute URI without fragment")
+			}
+			if !client.AllowsResource(resource) {
+				return nil, NewOAuthError(ErrorInvalidTarget, "client is not allowed to request tokens for this resource")
+			}
+		}
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return uc.exchangeAuthorizationCode(ctx, client, req)
+	case GrantTypeClientCredentials:
+		return uc.exchangeClientCredentials(client, req)
+	case GrantTypeDeviceCode:
+		return uc.exchangeDeviceCode(ctx, client, req)
+	case GrantTypeTokenExchange:
+		return uc.exchangeToken(ctx, client, req)
+	default:
+		return uc.exchangeRefreshToken(ctx, client, req)
+	}
+}
+
+// authenticateTokenClient authenticates the client at the token endpoint
+// Confidential clients must authenticate, public clients only identify
+// themselves and are bound to their codes through PKCE
+func (uc *oauthUseCase) authenticateTokenClient(ctx context.Context, credentials ClientCredentials) (*domain.Client, error) {
+	client, err := findClient(ctx, uc.clientRepo, clientIDOf(credentials))
+	if err != nil {
+		if err == domain.ErrInvalidClient {
+			return nil, NewOAuthError(ErrorInvalidClient, "unknown client")
+		}
+		return nil, err
+	}
+
+	// Public clients presenting credentials fail authentication too
+	if client.IsConfidential() || credentials.ClientSecret != "" || credentials.ClientAssertion != "" {
+		if err := uc.verifyClient(ctx, client, credentials); err != nil {
+			if err == domain.ErrInvalidClient {
+				return nil, NewOAuthError(ErrorInvalidClient, "client authentication failed")
+			}
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// exchangeAuthorizationCode handles the authorization_code grant
+func (uc *oauthUseCase) exchangeAuthorizationCode(ctx context.Context, client *domain.Client, req TokenRequest) (*AuthResponse, error) {
+	if req.Code == "" || req.CodeVerifier == "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "code and code_verifier are required")
+	}
+
+	code, err := uc.authorizationCodeRepo.Consume(ctx, hashToken(req.Code))
+	if err != nil {
+		if err == domain.ErrAuthCodeUsed {
+			uc.revokeReusedAuthorizationCode(ctx, hashToken(req.Code))
+		}
+		if err == domain.ErrAuthCodeNotFound || err == domain.ErrAuthCodeUsed {
+			return nil, NewOAuthError(ErrorInvalidGrant, "invalid authorization code")
+		}
+		return nil, err
+	}
+
+	if code.ConsentPending {
+		return nil, NewOAuthError(ErrorInvalidGrant, "authorization code awaits the user's consent")
+	}
+	if code.IsExpired() {
+		return nil, NewOAuthError(ErrorInvalidGrant, "authorization code expired")
+	}
+	if code.ClientID != client.ID {
+		return nil, NewOAuthError(ErrorInvalidGrant, "authorization code was issued to another client")
+	}
+	if code.RedirectURI != req.RedirectURI {
+		return nil, NewOAuthError(ErrorInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		return nil, NewOAuthError(ErrorInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	resp, err := uc.authUseCase.IssueTokens(ctx, IssueTokensRequest{
+		UserID:          code.UserID,
+		ClientID:        code.ClientID,
+		Scope:           code.Scope,
+		Nonce:           code.Nonce,
+		AuthenticatedAt: code.AuthenticatedAt,
+		Audience:        req.Resource,
+		Confirmation:    req.cnf,
+		Client: ClientMetadata{
+			IPAddress: code.IPAddress,
+			UserAgent: code.UserAgent,
+		},
+		SSOSessionID: code.SSOSessionID,
+	})
+	if err != nil {
+		switch err {
+		case domain.ErrUserNotFound, domain.ErrUserSuspended, domain.ErrInvalidClient:
+			return nil, NewOAuthError(ErrorInvalidGrant, err.Error())
+		}
+		return nil, err
+	}
+
+	// A replay of the code racing with this exchange may have found no tokens
+	// to revoke yet, so tokens that can't be recorded are revoked right away
+	if err := uc.recordIssuedTokens(ctx, code, resp.AccessToken); err != nil {
+		if revokeErr := uc.authUseCase.RevokeAuthorizationCodeTokens(ctx, code); revokeErr != nil {
+			log.Printf("failed to revoke tokens of authorization code %d: %v", code.ID, revokeErr)
+		}
+		if err == domain.ErrAuthCodeUsed {
+			return nil, NewOAuthError(ErrorInvalidGrant, "invalid authorization code")
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// recordIssuedTokens stores the session and access token issued for a code,
+// to revoke them if the code is used again
+func (uc *oauthUseCase) recordIssuedTokens(ctx context.Context, code *domain.AuthorizationCode, accessToken string) error {
+	claims, err := uc.jwtManager.ValidateToken(accessToken)
+	if err != nil {
+		return fmt.Errorf("failed to read issued access token: %w", err)
+	}
+	code.SessionID = claims.SessionID
+	code.AccessTokenID = claims.ID
+	code.AccessTokenExpiresAt = &claims.ExpiresAt.Time
+
+	err = uc.authorizationCodeRepo.RecordIssuedTokens(ctx, code.ID, code.SessionID, code.AccessTokenID, *code.AccessTokenExpiresAt)
+	if err != nil && err != domain.ErrAuthCodeUsed {
+		return fmt.Errorf("failed to record issued tokens: %w", err)
+	}
+	return err
+}
+
+// revokeReusedAuthorizationCode revokes the tokens issued for an authorization
+// code that is used again, since the code may have been stolen (RFC 6749
+// section 4.1.2). Failures are only logged, the request fails either way
+func (uc *oauthUseCase) revokeReusedAuthorizationCode(ctx context.Context, codeHash string) {
+	code, err := uc.authorizationCodeRepo.MarkReused(ctx, codeHash)
+	if err == nil {
+		err = uc.authUseCase.RevokeAuthorizationCodeTokens(ctx, code)
+	}
+	if err != nil {
+		log.Printf("failed to revoke tokens of reused authorization code: %v", err)
+	}
+}
+
+// exchangeRefreshToken handles the refresh_token grant
+func (uc *oauthUseCase) exchangeRefreshToken(ctx context.Context, client *domain.Client, req TokenRequest) (*AuthResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "refresh_token is required")
+	}
+
+	resp, err := uc.authUseCase.RefreshToken(ctx, RefreshTokenRequest{
+		RefreshToken: req.RefreshToken,
+		ClientID:     client.ID,
+		Audience:     req.Resource,
+		Confirmation: req.cnf,
+		Client:       req.Client,
+	})
+	if err != nil {
+		switch err {
+		case domain.ErrInvalidDPoPProof:
+			return nil, NewOAuthError(ErrorInvalidDPoPProof, "refresh token is bound to another DPoP key")
+		case domain.ErrCertificateMismatch:
+			return nil, NewOAuthError(ErrorInvalidGrant, "refresh token is bound to another client certificate")
+		case domain.ErrInvalidToken, domain.ErrRefreshTokenExpired, domain.ErrRefreshTokenRevoked,
+			domain.ErrRefreshTokenRotated, domain.ErrRefreshTokenReused, domain.ErrUserSuspended:
+			return nil, NewOAuthError(ErrorInvalidGrant, err.Error())
+		}
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// exchangeClientCredentials handles the client_credentials grant, which
+// issues an access token to the client itself, without refresh token
+func (uc *oauthUseCase) exchangeClientCredentials(client *domain.Client, req TokenRequest) (*AuthResponse, error) {
+	// Public clients can't prove their identity
+	if !client.IsConfidential() {
+		return nil, NewOAuthError(ErrorUnauthorizedClient, "client credentials require a confidential client")
+	}
+
+	// Scopes about the user are never granted here, as there is no user
+	scope, err := normalizeMachineScope(client, req.Scope)
+	if err != nil {
+		return nil, NewOAuthError(ErrorInvalidScope, "scope not allowed for this client")
+	}
+
+	lifetime := uc.jwtManager.GetAccessTokenDuration()
+	if client.AccessTokenLifetime > 0 {
+		lifetime = time.Duration(client.AccessTokenLifetime) * time.Second
+	}
+
+	claims := jwt.Claims{
+		ClientID: client.ID,
+		Scope:    scope,
+	}
+	claims.Audience = req.Resource
+	claims.Cnf = confirmationOf(req.cnf)
+	accessToken, err := uc.jwtManager.GenerateAccessTokenWithLifetime(claims, lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &AuthResponse{
+		AccessToken: accessToken,
+		TokenType:   tokenTypeOf(req.cnf),
+		ExpiresIn:   int(lifetime.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// isResourceIndicator checks if a value is an absolute URI without fragment,
+// as required of resource indicators (RFC 8707 section 2)
+func isResourceIndicator(resource string) bool {
+	u, err := url.Parse(resource)
+	return err == nil && u.IsAbs() && u.Fragment == ""
+}
+
+// randomToken returns a 256-bit random token, base64url encoded
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 hash under which a random token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errInternalAddress is returned when a client-supplied URL points at the
+// server's own network instead of the internet
+var errInternalAddress = errors.New("refusing to connect to an internal address")
+
+// newOutboundHTTPClient creates an HTTP client for URLs registered by
+// clients, such as jwks_uri and backchannel_logout_uri. Those URLs are
+// attacker-controlled, so the client refuses to connect to loopback, private
+// and link-local addresses. The address is checked when dialing, after DNS
+// resolution, so a host name resolving to an internal address is refused too.
+// Redirects aren't followed, since they could lead anywhere.
+func newOutboundHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if isInternalAddress(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errInternalAddress, addrPort.Addr())
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// No proxy from the environment, the dialer must see the real address
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isInternalAddress reports whether an address isn't reachable on the internet
+func isInternalAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() ||
+		addr.IsUnspecified() ||
+		sharedAddressSpace.Contains(addr)
+}
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598)
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// validateOutboundURL checks a client-supplied URL that the server will
+// connect to. Host names are checked again when connecting, this only
+// rejects URLs that are obviously internal at registration
+func validateOutboundURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.Fragment != "" {
+		return errors.New("must be an https URL")
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errInternalAddress
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && isInternalAddress(addr) {
+		return errInternalAddress
+	}
+	return nil
+}
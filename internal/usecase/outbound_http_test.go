@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIsInternalAddress(t *testing.T) {
+	tests := []struct {
+		addr     string
+		internal bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"::", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"8.8.8.8", false},
+		{"2001:4860:4860::8888", false},
+	}
+
+	for _, tt := range tests {
+		if got := isInternalAddress(netip.MustParseAddr(tt.addr)); got != tt.internal {
+			t.Errorf("isInternalAddress(%s) = %v, want %v", tt.addr, got, tt.internal)
+		}
+	}
+}
+
+func TestValidateOutboundURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{"https://client.example.com/jwks.json", true},
+		{"https://8.8.8.8/jwks.json", true},
+		{"http://client.example.com/jwks.json", false},
+		{"https://client.example.com/jwks.json#keys", false},
+		{"https://localhost/jwks.json", false},
+		{"https://api.localhost./jwks.json", false},
+		{"https://127.0.0.1:8443/jwks.json", false},
+		{"https://169.254.169.254/latest/meta-data", false},
+		{"https://[::1]/jwks.json", false},
+	}
+
+	for _, tt := range tests {
+		if err := validateOutboundURL(tt.url); (err == nil) != tt.valid {
+			t.Errorf("validateOutboundURL(%q) = %v, want valid %v", tt.url, err, tt.valid)
+		}
+	}
+}
+
+func TestOutboundHTTPClientRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer server.Close()
+
+	_, err := newOutboundHTTPClient(time.Second).Get(server.URL)
+	if !errors.Is(err, errInternalAddress) {
+		t.Fatalf("error = %v, want %v", err, errInternalAddress)
+	}
+}
+
+func TestOutboundHTTPClientDoesNotFollowRedirects(t *testing.T) {
+	client := newOutboundHTTPClient(time.Second)
+	// The loopback server is only reachable without the guarded dialer
+	client.Transport = http.DefaultTransport
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/internal" {
+			t.Error("redirect was followed")
+		}
+		http.Redirect(w, r, "/internal", http.StatusFound)
+	}))
+	defer server.Close()
+
+	resp, err := client.Get(server.URL + "/jwks.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusFound)
+	}
+}
+
+func TestClientKeyCache(t *testing.T) {
+	_, jwks := newTestClientKey(t)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(jwks))
+	}))
+	defer server.Close()
+
+	cache := newClientKeyCache()
+	cache.httpClient = server.Client()
+	ctx := context.Background()
+
+	t.Run("keyed by client", func(t *testing.T) {
+		fetches = 0
+		for range 2 {
+			if _, err := cache.get(ctx, "client", server.URL+"/a", false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if fetches != 1 {
+			t.Errorf("fetches = %d, want 1", fetches)
+		}
+
+		// A changed jwks_uri doesn't use the keys of the old one
+		if _, err := cache.get(ctx, "client", server.URL+"/b", false); err != nil {
+			t.Fatal(err)
+		}
+		if fetches != 2 || cache.entries["client"].uri != server.URL+"/b" {
+			t.Errorf("fetches = %d, uri = %q, want a fetch of the new jwks_uri", fetches, cache.entries["client"].uri)
+		}
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		clear(cache.entries)
+		now := time.Now()
+		for i := range maxClientKeyCacheEntries {
+			cache.entries[string(rune(0x1000+i))] = clientKeyEntry{fetchedAt: now}
+		}
+		cache.entries[string(rune(0x1000))] = clientKeyEntry{fetchedAt: now.Add(-time.Minute)}
+
+		if _, err := cache.get(ctx, "new", server.URL, false); err != nil {
+			t.Fatal(err)
+		}
+		if len(cache.entries) != maxClientKeyCacheEntries {
+			t.Errorf("entries = %d, want %d", len(cache.entries), maxClientKeyCacheEntries)
+		}
+		if _, ok := cache.entries[string(rune(0x1000))]; ok {
+			t.Error("the oldest entry wasn't evicted")
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	// RFC 7636 appendix B
+	const verifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	const challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		want      bool
+	}{
+		{"matching verifier", verifier, challenge, true},
+		{"other verifier", strings.Repeat("a", 43), challenge, false},
+		{"plain challenge", verifier, verifier, false},
+		{"verifier too short", "abc", base64URLSHA256("abc"), false},
+		{"verifier too long", strings.Repeat("a", 129), base64URLSHA256(strings.Repeat("a", 129)), false},
+		{"verifier with invalid characters", strings.Repeat("a", 42) + "+", base64URLSHA256(strings.Repeat("a", 42) + "+"), false},
+		{"empty verifier", "", challenge, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verifyCodeChallenge(tt.verifier, tt.challenge); got != tt.want {
+				t.Errorf("verifyCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidCodeChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		want      bool
+	}{
+		{"S256 challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", true},
+		{"padded", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM=", false},
+		{"too short", "E9Melhoa2OwvFrEMTJguCHao", false},
+		{"not base64url", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw+cM", false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidCodeChallenge(tt.challenge); got != tt.want {
+				t.Errorf("isValidCodeChallenge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func base64URLSHA256(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
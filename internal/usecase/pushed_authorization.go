@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RequestURIPrefix is the prefix of request URIs referencing pushed
+// authorization requests (RFC 9126 section 2.2)
+const RequestURIPrefix = "urn:ietf:params:oauth:request_uri:"
+
+// pushedAuthorizationRequestTTL is how long a pushed authorization request
+// can be used, long enough for the user to sign in
+const pushedAuthorizationRequestTTL = 5 * time.Minute
+
+func (uc *oauthUseCase) PushAuthorizationRequest(ctx context.Context, req AuthorizationRequest, credentials ClientCredentials) (*PushedAuthorizationResponse, error) {
+	// Clients authenticate like at the token endpoint
+	client, err := uc.authenticateTokenClient(ctx, credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.RequestURI != "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "request_uri must not be pushed")
+	}
+	if req.ClientID != "" && req.ClientID != client.ID {
+		return nil, NewOAuthError(ErrorInvalidRequest, "client_id does not match the authenticated client")
+	}
+	req.ClientID = client.ID
+
+	// Errors are returned to the client directly, so the redirect URI needs
+	// no special treatment
+	if err := validateAuthorizationRequest(client, req); err != nil {
+		if err == domain.ErrInvalidRedirectURI {
+			return nil, NewOAuthError(ErrorInvalidRequest, "redirect_uri is not registered for this client")
+		}
+		return nil, err
+	}
+	prompt, err := parsePrompt(req)
+	if err != nil {
+		return nil, err
+	}
+
+	requestURI, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate request URI: %w", err)
+	}
+	requestURI = RequestURIPrefix + requestURI
+
+	scope, _ := normalizeScope(req.Scope)
+	pushed := &domain.PushedAuthorizationRequest{
+		RequestURIHash:      hashToken(requestURI),
+		ClientID:            client.ID,
+		ResponseType:        req.ResponseType,
+		RedirectURI:         req.RedirectURI,
+		Scope:               scope,
+		State:               req.State,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Prompt:              req.Prompt,
+		MaxAge:              prompt.maxAge,
+		ExpiresAt:           time.Now().Add(pushedAuthorizationRequestTTL),
+	}
+	if err := uc.pushedAuthorizationRequestRepo.Create(ctx, pushed); err != nil {
+		return nil, fmt.Errorf("failed to save pushed authorization request: %w", err)
+	}
+
+	return &PushedAuthorizationResponse{
+		RequestURI: requestURI,
+		ExpiresIn:  int(pushedAuthorizationRequestTTL.Seconds()),
+	}, nil
+}
+
+func (uc *oauthUseCase) ResolveAuthorizationRequest(ctx context.Context, req AuthorizationRequest) (AuthorizationRequest, error) {
+	if req.RequestURI == "" {
+		return req, nil
+	}
+
+	pushed, err := uc.pushedAuthorizationRequestRepo.FindActive(ctx, hashToken(req.RequestURI))
+	if err != nil {
+		return req, err
+	}
+	// The client repeats its client_id, which must match (RFC 9126 section 4)
+	if pushed.ClientID != req.ClientID {
+		return req, domain.ErrRequestURINotFound
+	}
+
+	maxAge := ""
+	if pushed.MaxAge != nil {
+		maxAge = strconv.Itoa(*pushed.MaxAge)
+	}
+
+	// Parameters outside the pushed request are ignored
+	return AuthorizationRequest{
+		ResponseType:        pushed.ResponseType,
+		ClientID:            pushed.ClientID,
+		RedirectURI:         pushed.RedirectURI,
+		Scope:               pushed.Scope,
+		State:               pushed.State,
+		Nonce:               pushed.Nonce,
+		CodeChallenge:       pushed.CodeChallenge,
+		CodeChallengeMethod: pushed.CodeChallengeMethod,
+		Prompt:              pushed.Prompt,
+		MaxAge:              maxAge,
+		RequestURI:          req.RequestURI,
+		SessionToken:        req.SessionToken,
+		Client:              req.Client,
+	}, nil
+}
+
+// consumeRequestURI marks a pushed authorization request as used, so that
+// it completes only one authorization
+func (uc *oauthUseCase) consumeRequestURI(ctx context.Context, requestURI string) error {
+	pushed, err := uc.pushedAuthorizationRequestRepo.FindActive(ctx, hashToken(requestURI))
+	if err != nil {
+		return err
+	}
+	return uc.pushedAuthorizationRequestRepo.Consume(ctx, pushed.ID)
+}
@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testClientSecret = "client-secret"
+
+// newPARTest creates an oauthTest where web-app is a confidential client and
+// par-only must push its authorization requests
+func newPARTest(t *testing.T) *oauthTest {
+	t.Helper()
+
+	secretHash, err := bcrypt.GenerateFromPassword([]byte(testClientSecret), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test := newOAuthTest(t)
+	test.clients.clients["web-app"].SecretHash = string(secretHash)
+	test.clients.clients["par-only"] = &domain.Client{
+		ID:                                 "par-only",
+		RedirectURIs:                       []string{testRedirectURI},
+		GrantTypes:                         []string{GrantTypeAuthorizationCode},
+		Scopes:                             []string{ScopeOpenID, ScopeProfile},
+		RequirePushedAuthorizationRequests: true,
+	}
+	return test
+}
+
+func validPushedRequest() AuthorizationRequest {
+	return AuthorizationRequest{
+		ResponseType:        ResponseTypeCode,
+		RedirectURI:         testRedirectURI,
+		Scope:               "openid profile",
+		State:               "state",
+		Nonce:               "nonce",
+		CodeChallenge:       testCodeChallenge,
+		CodeChallengeMethod: CodeChallengeMethodS256,
+		Prompt:              PromptLogin,
+		MaxAge:              "300",
+	}
+}
+
+func TestPushAuthorizationRequest(t *testing.T) {
+	credentials := ClientCredentials{ClientID: "web-app", ClientSecret: testClientSecret}
+
+	tests := []struct {
+		name     string
+		modify   func(*AuthorizationRequest, *ClientCredentials)
+		wantCode string
+	}{
+		{"valid", func(*AuthorizationRequest, *ClientCredentials) {}, ""},
+		{"matching client_id", func(req *AuthorizationRequest, _ *ClientCredentials) { req.ClientID = "web-app" }, ""},
+		{"wrong secret", func(_ *AuthorizationRequest, c *ClientCredentials) { c.ClientSecret = "wrong" }, ErrorInvalidClient},
+		{"unknown client", func(_ *AuthorizationRequest, c *ClientCredentials) { c.ClientID = "unknown" }, ErrorInvalidClient},
+		{"nested request_uri", func(req *AuthorizationRequest, _ *ClientCredentials) { req.RequestURI = RequestURIPrefix + "x" }, ErrorInvalidRequest},
+		{"other client_id", func(req *AuthorizationRequest, _ *ClientCredentials) { req.ClientID = "par-only" }, ErrorInvalidRequest},
+		{"unregistered redirect_uri", func(req *AuthorizationRequest, _ *ClientCredentials) { req.RedirectURI = "https://evil.example.com/cb" }, ErrorInvalidRequest},
+		{"missing code_challenge", func(req *AuthorizationRequest, _ *ClientCredentials) { req.CodeChallenge = "" }, ErrorInvalidRequest},
+		{"unsupported response_type", func(req *AuthorizationRequest, _ *ClientCredentials) { req.ResponseType = "token" }, ErrorUnsupportedResponseType},
+		{"invalid max_age", func(req *AuthorizationRequest, _ *ClientCredentials) { req.MaxAge = "soon" }, ErrorInvalidRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newPARTest(t)
+			req, creds := validPushedRequest(), credentials
+			tt.modify(&req, &creds)
+
+			resp, err := test.PushAuthorizationRequest(context.Background(), req, creds)
+			if tt.wantCode != "" {
+				var oauthErr *OAuthError
+				if !errors.As(err, &oauthErr) || oauthErr.Code != tt.wantCode {
+					t.Fatalf("got %v, want %s", err, tt.wantCode)
+				}
+				if len(test.pushed.requests) != 0 {
+					t.Error("a rejected request was saved")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.HasPrefix(resp.RequestURI, RequestURIPrefix) {
+				t.Errorf("request_uri = %q, want the %q prefix", resp.RequestURI, RequestURIPrefix)
+			}
+			if resp.ExpiresIn != int(pushedAuthorizationRequestTTL.Seconds()) {
+				t.Errorf("expires_in = %d", resp.ExpiresIn)
+			}
+			saved, ok := test.pushed.requests[hashToken(resp.RequestURI)]
+			if !ok {
+				t.Fatal("the request wasn't saved under the hash of its request URI")
+			}
+			if saved.ClientID != "web-app" || saved.Scope != "openid profile" {
+				t.Errorf("saved request = %+v", saved)
+			}
+		})
+	}
+}
+
+func TestResolveAuthorizationRequest(t *testing.T) {
+	ctx := context.Background()
+	test := newPARTest(t)
+	resp, err := test.PushAuthorizationRequest(ctx, validPushedRequest(), ClientCredentials{ClientID: "web-app", ClientSecret: testClientSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("pushed parameters replace the front-channel ones", func(t *testing.T) {
+		resolved, err := test.ResolveAuthorizationRequest(ctx, AuthorizationRequest{
+			ClientID:    "web-app",
+			RequestURI:  resp.RequestURI,
+			RedirectURI: "https://evil.example.com/cb",
+			Scope:       "openid account",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved.RedirectURI != testRedirectURI || resolved.Scope != "openid profile" || resolved.State != "state" ||
+			resolved.CodeChallenge != testCodeChallenge || resolved.Prompt != PromptLogin || resolved.MaxAge != "300" {
+			t.Errorf("resolved request = %+v", resolved)
+		}
+		if resolved.RequestURI != resp.RequestURI {
+			t.Error("request_uri must be kept")
+		}
+		if err := test.ValidateAuthorizationRequest(ctx, resolved); err != nil {
+			t.Errorf("resolved request is invalid: %v", err)
+		}
+	})
+
+	t.Run("client_id must match", func(t *testing.T) {
+		_, err := test.ResolveAuthorizationRequest(ctx, AuthorizationRequest{ClientID: "par-only", RequestURI: resp.RequestURI})
+		if err != domain.ErrRequestURINotFound {
+			t.Errorf("got %v, want %v", err, domain.ErrRequestURINotFound)
+		}
+	})
+
+	t.Run("unknown request_uri", func(t *testing.T) {
+		_, err := test.ResolveAuthorizationRequest(ctx, AuthorizationRequest{ClientID: "web-app", RequestURI: RequestURIPrefix + "unknown"})
+		if err != domain.ErrRequestURINotFound {
+			t.Errorf("got %v, want %v", err, domain.ErrRequestURINotFound)
+		}
+	})
+
+	t.Run("single use", func(t *testing.T) {
+		if err := test.consumeRequestURI(ctx, resp.RequestURI); err != nil {
+			t.Fatal(err)
+		}
+		if err := test.consumeRequestURI(ctx, resp.RequestURI); err != domain.ErrRequestURINotFound {
+			t.Errorf("second use: got %v, want %v", err, domain.ErrRequestURINotFound)
+		}
+		_, err := test.ResolveAuthorizationRequest(ctx, AuthorizationRequest{ClientID: "web-app", RequestURI: resp.RequestURI})
+		if err != domain.ErrRequestURINotFound {
+			t.Errorf("resolving a used request: got %v, want %v", err, domain.ErrRequestURINotFound)
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		resp, err := test.PushAuthorizationRequest(ctx, validPushedRequest(), ClientCredentials{ClientID: "web-app", ClientSecret: testClientSecret})
+		if err != nil {
+			t.Fatal(err)
+		}
+		test.pushed.requests[hashToken(resp.RequestURI)].ExpiresAt = time.Now().Add(-time.Second)
+
+		_, err = test.ResolveAuthorizationRequest(ctx, AuthorizationRequest{ClientID: "web-app", RequestURI: resp.RequestURI})
+		if err != domain.ErrRequestURINotFound {
+			t.Errorf("got %v, want %v", err, domain.ErrRequestURINotFound)
+		}
+	})
+}
+
+func TestRequirePushedAuthorizationRequests(t *testing.T) {
+	test := newPARTest(t)
+	req := validPushedRequest()
+	req.ClientID = "par-only"
+
+	var oauthErr *OAuthError
+	err := test.ValidateAuthorizationRequest(context.Background(), req)
+	if !errors.As(err, &oauthErr) || oauthErr.Code != ErrorInvalidRequest {
+		t.Errorf("got %v, want %s", err, ErrorInvalidRequest)
+	}
+
+	req.RequestURI = RequestURIPrefix + "pushed"
+	if err := test.ValidateAuthorizationRequest(context.Background(), req); err != nil {
+		t.Errorf("got %v for a pushed request", err)
+	}
+}
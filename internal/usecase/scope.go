@@ -0,0 +1,119 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"slices"
+	"strings"
+)
+
+// OpenID Connect scopes
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// ScopeAccount grants access to the account management API (/auth). Only
+// first-party clients may request it
+const ScopeAccount = "account"
+
+// supportedScopes lists the scopes this service defines. They are all about
+// the user's identity, so they can't be granted to machine principals
+var supportedScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeAccount}
+
+// ScopeDefinition describes a scope to users asked for consent
+type ScopeDefinition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// scopeDefinitions describes the supported scopes
+var scopeDefinitions = map[string]string{
+	ScopeOpenID:  "Sign you in",
+	ScopeProfile: "See your name",
+	ScopeEmail:   "See your email address",
+	ScopeAccount: "Manage your account, sessions and connected apps",
+}
+
+// describeScopes returns the definitions of a space-separated scope string
+func describeScopes(scope string) []ScopeDefinition {
+	definitions := []ScopeDefinition{}
+	for _, s := range strings.Fields(scope) {
+		definitions = append(definitions, ScopeDefinition{Name: s, Description: scopeDefinitions[s]})
+	}
+	return definitions
+}
+
+// mergeScopes returns the union of two space-separated scope strings
+func mergeScopes(scope, other string) string {
+	scopes := strings.Fields(scope)
+	for _, s := range strings.Fields(other) {
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return strings.Join(scopes, " ")
+}
+
+// normalizeScope validates a space-separated scope string and returns it
+// without duplicates
+func normalizeScope(scope string) (string, error) {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !slices.Contains(supportedScopes, s) {
+			return "", domain.ErrInvalidScope
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return strings.Join(scopes, " "), nil
+}
+
+// isAPIScope checks if a scope is a valid scope token (RFC 6749 section 3.3)
+// not defined by this service. Administrators register such scopes of the
+// APIs machine clients call; only the client_credentials grant issues them
+func isAPIScope(s string) bool {
+	if s == "" || slices.Contains(supportedScopes, s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x21 || r > 0x7e || r == '"' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeMachineScope validates a space-separated scope string requested
+// by a client for itself and returns it without duplicates
+func normalizeMachineScope(client *domain.Client, scope string) (string, error) {
+	var scopes []string
+	for _, s := range strings.Fields(scope) {
+		if !isAPIScope(s) || !client.AllowsScope(s) {
+			return "", domain.ErrInvalidScope
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	return strings.Join(scopes, " "), nil
+}
+
+// clientAllowsScope checks if a client may request all scopes of a
+// space-separated scope string
+func clientAllowsScope(client *domain.Client, scope string) bool {
+	for _, s := range strings.Fields(scope) {
+		if !client.AllowsScope(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// hasScope checks if a space-separated scope string contains a scope
+func hasScope(scope, s string) bool {
+	return slices.Contains(strings.Fields(scope), s)
+}
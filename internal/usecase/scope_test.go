@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"errors"
+	"testing"
+)
+
+func TestNormalizeMachineScope(t *testing.T) {
+	client := &domain.Client{Scopes: []string{ScopeProfile, "orders.read", "orders.write"}}
+
+	tests := []struct {
+		name    string
+		scope   string
+		want    string
+		wantErr bool
+	}{
+		{"no scope", "", "", false},
+		{"registered API scope", "orders.read", "orders.read", false},
+		{"duplicates removed", "orders.read orders.write orders.read", "orders.read orders.write", false},
+		{"unregistered API scope", "orders.delete", "", true},
+		{"user scope registered on the client", ScopeProfile, "", true},
+		{"user scope", ScopeOpenID, "", true},
+		{"invalid characters", `orders"read`, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeMachineScope(client, tt.scope)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidScope) {
+					t.Fatalf("expected ErrInvalidScope, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateClientScopes(t *testing.T) {
+	tests := []struct {
+		name       string
+		grantTypes []string
+		scopes     []string
+		wantErr    bool
+	}{
+		{"user scopes", []string{GrantTypeClientCredentials}, []string{ScopeOpenID, ScopeEmail}, false},
+		{"API scopes with client credentials", []string{GrantTypeClientCredentials}, []string{"orders.read"}, false},
+		{"API scopes without client credentials", []string{GrantTypeRefreshToken}, []string{"orders.read"}, true},
+		{"invalid scope", []string{GrantTypeClientCredentials}, []string{`orders\read`}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &domain.Client{
+				Name:       "Worker",
+				SecretHash: "hash",
+				GrantTypes: tt.grantTypes,
+				Scopes:     tt.scopes,
+			}
+			err := validateClient(client)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/models"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Prompt values acted on at the authorization endpoint (OpenID Connect
+// Core 1.0 section 3.1.2.1). Other values are ignored
+const (
+	PromptNone  = "none"
+	PromptLogin = "login"
+)
+
+// ssoSessionTouchInterval limits how often the last activity of an SSO
+// session is written, which only needs to be accurate to the idle timeout
+const ssoSessionTouchInterval = time.Minute
+
+func (uc *authUseCase) StartSSOSession(ctx context.Context, userID, currentToken string, metadata ClientMetadata) (*SSOSessionResponse, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session token: %w", err)
+	}
+	now := time.Now()
+
+	// Signing in again keeps the browser's session of the same user, so that
+	// refresh tokens issued through it stay tied to it. The cookie changes
+	// anyway, so that a cookie planted before sign-in is of no use
+	current, err := uc.findActiveSSOSession(ctx, currentToken)
+	switch {
+	case err == domain.ErrSessionNotFound:
+	case err != nil:
+		return nil, err
+	case current.UserID == userID:
+		current.TokenHash = hashToken(token)
+		current.AuthenticatedAt = now
+		current.LastActiveAt = now
+		current.ExpiresAt = now.Add(uc.sessionCfg.AbsoluteTimeout)
+		current.IPAddress = metadata.IPAddress
+		current.UserAgent = metadata.UserAgent
+		if err := uc.ssoSessionRepo.Update(ctx, current); err != nil {
+			return nil, fmt.Errorf("failed to update session: %w", err)
+		}
+		return newSSOSessionResponse(current, token), nil
+	default:
+		// Another user signing in ends the session of the previous one
+		if _, err := uc.EndSSOSession(ctx, current.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	session := &domain.SSOSession{
+		ID:              models.NewNanoID(),
+		TokenHash:       hashToken(token),
+		UserID:          userID,
+		AuthenticatedAt: now,
+		LastActiveAt:    now,
+		ExpiresAt:       now.Add(uc.sessionCfg.AbsoluteTimeout),
+		IPAddress:       metadata.IPAddress,
+		UserAgent:       metadata.UserAgent,
+	}
+	if err := uc.ssoSessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+
+	return newSSOSessionResponse(session, token), nil
+}
+
+func (uc *authUseCase) FindSSOSession(ctx context.Context, token string) (*SSOSessionResponse, error) {
+	session, err := uc.findActiveSSOSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Since(session.LastActiveAt) > ssoSessionTouchInterval {
+		if err := uc.ssoSessionRepo.Touch(ctx, session.ID, time.Now()); err != nil {
+			return nil, fmt.Errorf("failed to update session: %w", err)
+		}
+	}
+
+	return newSSOSessionResponse(session, ""), nil
+}
+
+func (uc *authUseCase) EndSSOSession(ctx context.Context, sessionID string) ([]SessionResponse, error) {
+	tokens, err := uc.refreshTokenRepo.FindBySSOSessionID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.ssoSessionRepo.Revoke(ctx, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if err := uc.refreshTokenRepo.RevokeAllBySSOSessionID(ctx, sessionID); err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	uc.notifyLogout(ctx, tokens...)
+
+	// Each client session is represented by the latest token of its family
+	sessions := make([]SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		if token.IsExpired() {
+			continue
+		}
+		sessions = append(sessions, SessionResponse{
+			ID:         token.FamilyID,
+			ClientID:   token.ClientID,
+			CreatedAt:  token.AuthenticatedAt,
+			LastUsedAt: token.CreatedAt,
+			IPAddress:  token.IPAddress,
+			UserAgent:  token.UserAgent,
+		})
+	}
+
+	return sessions, nil
+}
+
+// findActiveSSOSession finds the session of a session cookie, returning
+// domain.ErrSessionNotFound unless it is active
+func (uc *authUseCase) findActiveSSOSession(ctx context.Context, token string) (*domain.SSOSession, error) {
+	if token == "" {
+		return nil, domain.ErrSessionNotFound
+	}
+
+	session, err := uc.ssoSessionRepo.FindByTokenHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if !session.IsActive(uc.sessionCfg.IdleTimeout) {
+		return nil, domain.ErrSessionNotFound
+	}
+
+	return session, nil
+}
+
+func newSSOSessionResponse(session *domain.SSOSession, token string) *SSOSessionResponse {
+	return &SSOSessionResponse{
+		ID:              session.ID,
+		UserID:          session.UserID,
+		Token:           token,
+		AuthenticatedAt: session.AuthenticatedAt,
+		ExpiresAt:       session.ExpiresAt,
+	}
+}
+
+// promptOptions are the parsed prompt and max_age parameters of an
+// authorization request
+type promptOptions struct {
+	none   bool
+	login  bool
+	maxAge *int
+}
+
+func parsePrompt(req AuthorizationRequest) (promptOptions, error) {
+	var prompt promptOptions
+	values := strings.Fields(req.Prompt)
+	for _, value := range values {
+		switch value {
+		case PromptNone:
+			prompt.none = true
+		case PromptLogin:
+			prompt.login = true
+		}
+	}
+	if prompt.none && len(values) > 1 {
+		return prompt, NewOAuthError(ErrorInvalidRequest, "prompt none must not be combined with other values")
+	}
+
+	if req.MaxAge != "" {
+		maxAge, err := strconv.Atoi(req.MaxAge)
+		if err != nil || maxAge < 0 {
+			return prompt, NewOAuthError(ErrorInvalidRequest, "max_age must be a non-negative integer")
+		}
+		prompt.maxAge = &maxAge
+	}
+
+	return prompt, nil
+}
+
+// allowsSession reports whether the user of an SSO session may be
+// authorized without signing in again
+func (p promptOptions) allowsSession(session *SSOSessionResponse) bool {
+	if p.login {
+		return false
+	}
+	if p.maxAge != nil && time.Since(session.AuthenticatedAt) > time.Duration(*p.maxAge)*time.Second {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,199 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/config"
+	"context"
+	"testing"
+	"time"
+)
+
+// newSSOSessionTest creates an authUseCase with in-memory SSO sessions and
+// refresh tokens
+func newSSOSessionTest() (*authUseCase, *fakeSSOSessionRepo, *fakeRefreshTokenRepo, *fakeLogoutNotifier) {
+	sessions := &fakeSSOSessionRepo{sessions: map[string]*domain.SSOSession{}}
+	tokens := &fakeRefreshTokenRepo{}
+	notifier := &fakeLogoutNotifier{}
+	uc := &authUseCase{
+		refreshTokenRepo: tokens,
+		ssoSessionRepo:   sessions,
+		logoutNotifier:   notifier,
+		sessionCfg:       &config.SessionConfig{IdleTimeout: 30 * time.Minute, AbsoluteTimeout: 12 * time.Hour},
+	}
+	return uc, sessions, tokens, notifier
+}
+
+func TestStartSSOSession(t *testing.T) {
+	uc, sessions, tokens, notifier := newSSOSessionTest()
+	ctx := context.Background()
+
+	first, err := uc.StartSSOSession(ctx, "user-1", "", ClientMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Token == "" || sessions.sessions[first.ID].TokenHash != hashToken(first.Token) {
+		t.Fatalf("expected a session with a hashed token, got %+v", first)
+	}
+
+	// Signing in again keeps the session but changes its token
+	again, err := uc.StartSSOSession(ctx, "user-1", first.Token, ClientMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != first.ID || again.Token == first.Token {
+		t.Errorf("expected session %s with a new token, got %+v", first.ID, again)
+	}
+	if _, err := uc.FindSSOSession(ctx, first.Token); err != domain.ErrSessionNotFound {
+		t.Errorf("expected the previous token to be replaced, got %v", err)
+	}
+
+	// Another user signing in ends the session and its refresh tokens
+	tokens.tokens = []*domain.RefreshToken{
+		{UserID: "user-1", ClientID: "web-app", FamilyID: "session-1", SSOSessionID: first.ID, ExpiresAt: time.Now().Add(time.Hour)},
+	}
+	other, err := uc.StartSSOSession(ctx, "user-2", again.Token, ClientMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.ID == first.ID || sessions.sessions[first.ID].RevokedAt == nil {
+		t.Error("expected a new session to replace the ended one")
+	}
+	if !tokens.tokens[0].IsRevoked || len(notifier.sessions) != 1 {
+		t.Error("expected the clients of the ended session to be logged out")
+	}
+}
+
+func TestFindSSOSession(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		session domain.SSOSession
+		wantErr error
+	}{
+		{"active", domain.SSOSession{LastActiveAt: now, ExpiresAt: now.Add(time.Hour)}, nil},
+		{"idle", domain.SSOSession{LastActiveAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}, domain.ErrSessionNotFound},
+		{"expired", domain.SSOSession{LastActiveAt: now, ExpiresAt: now.Add(-time.Second)}, domain.ErrSessionNotFound},
+		{"revoked", domain.SSOSession{LastActiveAt: now, ExpiresAt: now.Add(time.Hour), RevokedAt: &now}, domain.ErrSessionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, sessions, _, _ := newSSOSessionTest()
+			session := tt.session
+			session.ID = "sso-1"
+			session.TokenHash = hashToken("sso-token")
+			sessions.sessions[session.ID] = &session
+
+			if _, err := uc.FindSSOSession(context.Background(), "sso-token"); err != tt.wantErr {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	uc, _, _, _ := newSSOSessionTest()
+	if _, err := uc.FindSSOSession(context.Background(), ""); err != domain.ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound without a cookie, got %v", err)
+	}
+}
+
+func TestParsePrompt(t *testing.T) {
+	signedIn := &SSOSessionResponse{AuthenticatedAt: time.Now().Add(-time.Hour)}
+
+	tests := []struct {
+		name        string
+		req         AuthorizationRequest
+		wantOAuth   string
+		wantSession bool
+	}{
+		{"no prompt", AuthorizationRequest{}, "", true},
+		{"none", AuthorizationRequest{Prompt: "none"}, "", true},
+		{"login", AuthorizationRequest{Prompt: "login consent"}, "", false},
+		{"unknown values", AuthorizationRequest{Prompt: "select_account"}, "", true},
+		{"none with other values", AuthorizationRequest{Prompt: "none login"}, ErrorInvalidRequest, false},
+		{"recent enough", AuthorizationRequest{MaxAge: "7200"}, "", true},
+		{"signed in too long ago", AuthorizationRequest{MaxAge: "60"}, "", false},
+		{"invalid max_age", AuthorizationRequest{MaxAge: "-1"}, ErrorInvalidRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, err := parsePrompt(tt.req)
+			if tt.wantOAuth != "" {
+				expectOAuthError(t, err, tt.wantOAuth)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed := prompt.allowsSession(signedIn); allowed != tt.wantSession {
+				t.Errorf("allowsSession() = %v, want %v", allowed, tt.wantSession)
+			}
+		})
+	}
+}
+
+func TestAuthorizeWithSession(t *testing.T) {
+	ctx := context.Background()
+	newRequest := func(prompt string) AuthorizationRequest {
+		return AuthorizationRequest{
+			ResponseType:        ResponseTypeCode,
+			ClientID:            "web-app",
+			RedirectURI:         testRedirectURI,
+			Scope:               "openid",
+			State:               "client-state",
+			CodeChallenge:       testCodeChallenge,
+			CodeChallengeMethod: CodeChallengeMethodS256,
+			Prompt:              prompt,
+			SessionToken:        "sso-token",
+		}
+	}
+
+	t.Run("without session", func(t *testing.T) {
+		test := newOAuthTest(t)
+		if _, err := test.AuthorizeWithSession(ctx, newRequest("")); err != domain.ErrLoginRequired {
+			t.Errorf("expected the sign-in form, got %v", err)
+		}
+		_, err := test.AuthorizeWithSession(ctx, newRequest(PromptNone))
+		expectOAuthError(t, err, ErrorLoginRequired)
+	})
+
+	t.Run("with session", func(t *testing.T) {
+		test := newOAuthTest(t)
+		test.clients.clients["web-app"].FirstParty = true
+		test.auth.ssoSession = &SSOSessionResponse{ID: "sso-1", UserID: "user-1", Token: "sso-token", AuthenticatedAt: time.Now()}
+
+		resp, err := test.AuthorizeWithSession(ctx, newRequest(PromptNone))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code == "" || resp.State != "client-state" {
+			t.Fatalf("expected a code, got %+v", resp)
+		}
+		code := test.codes.codes[hashToken(resp.Code)]
+		if code.UserID != "user-1" || code.SSOSessionID != "sso-1" {
+			t.Errorf("expected the code to be issued through sso-1, got %+v", code)
+		}
+
+		if _, err := test.AuthorizeWithSession(ctx, newRequest(PromptLogin)); err != domain.ErrLoginRequired {
+			t.Errorf("expected prompt=login to show the sign-in form, got %v", err)
+		}
+	})
+
+	t.Run("without consent", func(t *testing.T) {
+		test := newOAuthTest(t)
+		test.auth.ssoSession = &SSOSessionResponse{ID: "sso-1", UserID: "user-1", Token: "sso-token", AuthenticatedAt: time.Now()}
+		if err := test.grantConsent(ctx, "user-1", "web-app", ScopeOpenID); err != nil {
+			t.Fatal(err)
+		}
+		req := newRequest(PromptNone)
+		req.Scope = "openid profile"
+
+		_, err := test.AuthorizeWithSession(ctx, req)
+		expectOAuthError(t, err, ErrorConsentRequired)
+
+		// The user is asked instead when interaction is allowed
+		req.Prompt = ""
+		resp, err := test.AuthorizeWithSession(ctx, req)
+		if err != nil || resp.ConsentChallenge == "" {
+			t.Errorf("expected a consent challenge, got %+v, %v", resp, err)
+		}
+	})
+}
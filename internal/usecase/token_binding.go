@@ -0,0 +1,24 @@
+package usecase
+
+import (
+	"auth-service/pkg/jwt"
+)
+
+// confirmationOf returns the cnf claim of tokens bound to keys, nil for
+// bearer tokens
+func confirmationOf(cnf jwt.Confirmation) *jwt.Confirmation {
+	if cnf.IsZero() {
+		return nil
+	}
+	return &cnf
+}
+
+// tokenTypeOf returns the type of access tokens bound to keys. Tokens bound
+// to a DPoP key have their own type, certificate-bound tokens remain bearer
+// tokens to clients (RFC 8705 section 3)
+func tokenTypeOf(cnf jwt.Confirmation) string {
+	if cnf.JKT != "" {
+		return TokenTypeDPoP
+	}
+	return "Bearer"
+}
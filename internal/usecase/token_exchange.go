@@ -0,0 +1,171 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TokenTypeAccessToken identifies access tokens in token exchange (RFC 8693 section 3)
+const TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+
+// exchangeToken handles the token exchange grant (RFC 8693), which lets a
+// service call another service on behalf of a user. The subject token is
+// exchanged for a down-scoped access token audienced to the target service,
+// naming the acting party in the act claim. No refresh token is issued
+func (uc *oauthUseCase) exchangeToken(ctx context.Context, client *domain.Client, req TokenRequest) (*AuthResponse, error) {
+	if !client.IsConfidential() {
+		return nil, NewOAuthError(ErrorUnauthorizedClient, "token exchange requires a confidential client")
+	}
+
+	if req.SubjectToken == "" || req.SubjectTokenType == "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "subject_token and subject_token_type are required")
+	}
+	if req.SubjectTokenType != TokenTypeAccessToken {
+		return nil, NewOAuthError(ErrorInvalidRequest, "unsupported subject_token_type")
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != TokenTypeAccessToken {
+		return nil, NewOAuthError(ErrorInvalidRequest, "unsupported requested_token_type")
+	}
+
+	subject, err := uc.validateExchangedToken(ctx, req.SubjectToken, req.cnf)
+	if err != nil {
+		return nil, err
+	}
+	if subject.IsMachine() {
+		return nil, NewOAuthError(ErrorInvalidRequest, "subject_token must represent a user")
+	}
+	if !client.AllowsExchangeSubjectClient(subject.ClientID) {
+		return nil, NewOAuthError(ErrorInvalidGrant, "client is not allowed to exchange tokens of this client")
+	}
+	// Only the service a token was sent to may exchange it
+	if !client.IsExchangeSubjectAudience(subject.Audience) {
+		return nil, NewOAuthError(ErrorInvalidGrant, "subject_token is not intended for this client")
+	}
+
+	// The client acts itself, unless it names another actor it holds a token of
+	actor := &jwt.Actor{Subject: client.ID}
+	if req.ActorToken != "" {
+		if req.ActorTokenType != TokenTypeAccessToken {
+			return nil, NewOAuthError(ErrorInvalidRequest, "unsupported actor_token_type")
+		}
+		actorClaims, err := uc.validateExchangedToken(ctx, req.ActorToken, req.cnf)
+		if err != nil {
+			return nil, err
+		}
+		if actorClaims.ClientID != client.ID {
+			return nil, NewOAuthError(ErrorInvalidGrant, "actor_token was issued to another client")
+		}
+		actor = &jwt.Actor{Subject: actorClaims.Subject, ClientID: actorClaims.ClientID}
+	} else if req.ActorTokenType != "" {
+		return nil, NewOAuthError(ErrorInvalidRequest, "actor_token_type requires actor_token")
+	}
+	// Prior actors of a delegation chain are kept
+	actor.Act = subject.Act
+
+	// Targets are named by audience, resource (RFC 8707) or both
+	audience := req.Resource
+	if req.Audience != "" {
+		audience = append(audience, req.Audience)
+	}
+	if len(audience) == 0 {
+		return nil, NewOAuthError(ErrorInvalidRequest, "audience or resource is required")
+	}
+	for _, target := range audience {
+		if !client.AllowsExchangeAudience(target) {
+			return nil, NewOAuthError(ErrorInvalidTarget, "client is not allowed to request tokens for this audience")
+		}
+	}
+
+	scope, err := exchangedScope(client, subject.Scope, req.Scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// The new token must not outlive the subject token
+	lifetime := uc.jwtManager.GetAccessTokenDuration()
+	if client.AccessTokenLifetime > 0 {
+		lifetime = time.Duration(client.AccessTokenLifetime) * time.Second
+	}
+	if remaining := time.Until(subject.ExpiresAt.Time); remaining < lifetime {
+		lifetime = remaining
+	}
+
+	claims := jwt.Claims{
+		UserID:       subject.UserID,
+		Email:        subject.Email,
+		SessionID:    subject.SessionID,
+		ClientID:     client.ID,
+		Scope:        scope,
+		TokenVersion: subject.TokenVersion,
+		Act:          actor,
+	}
+	claims.Audience = audience
+	claims.Cnf = confirmationOf(req.cnf)
+
+	accessToken, err := uc.jwtManager.GenerateAccessTokenWithLifetime(claims, lifetime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &AuthResponse{
+		AccessToken:     accessToken,
+		TokenType:       tokenTypeOf(req.cnf),
+		ExpiresIn:       int(lifetime.Seconds()),
+		Scope:           scope,
+		IssuedTokenType: TokenTypeAccessToken,
+	}, nil
+}
+
+// validateExchangedToken validates a subject or actor token, including
+// revocation checks. Tokens bound to a key are only accepted if the client
+// proved possession of the key with the exchange request (cnf), like when
+// the token is used at a resource server, so that a stolen bound token
+// can't be exchanged for a token without the binding
+func (uc *oauthUseCase) validateExchangedToken(ctx context.Context, token string, cnf jwt.Confirmation) (*jwt.Claims, error) {
+	claims, err := uc.authUseCase.ValidateAccessToken(ctx, token)
+	if err != nil {
+		if err == domain.ErrInvalidToken {
+			return nil, NewOAuthError(ErrorInvalidRequest, "invalid or expired token")
+		}
+		return nil, err
+	}
+
+	if jkt := claims.DPoPKeyThumbprint(); jkt != "" && jkt != cnf.JKT {
+		return nil, NewOAuthError(ErrorInvalidGrant, "token is bound to a DPoP key the client did not prove possession of")
+	}
+	if x5t := claims.CertificateThumbprint(); x5t != "" && x5t != cnf.X5TS256 {
+		return nil, NewOAuthError(ErrorInvalidGrant, "token is bound to a client certificate the client did not present")
+	}
+	return claims, nil
+}
+
+// exchangedScope returns the scope of an exchanged token, which is limited
+// to the scope of the subject token and the scopes of the client. Without a
+// requested scope, all scopes both allow are granted
+func exchangedScope(client *domain.Client, subjectScope, requestedScope string) (string, error) {
+	if requestedScope == "" {
+		var scopes []string
+		for _, s := range strings.Fields(subjectScope) {
+			if client.AllowsScope(s) {
+				scopes = append(scopes, s)
+			}
+		}
+		return strings.Join(scopes, " "), nil
+	}
+
+	scope, err := normalizeScope(requestedScope)
+	if err != nil || !clientAllowsScope(client, scope) {
+		return "", NewOAuthError(ErrorInvalidScope, "scope not allowed for this client")
+	}
+	for _, s := range strings.Fields(scope) {
+		if !hasScope(subjectScope, s) {
+			return "", NewOAuthError(ErrorInvalidScope, "scope exceeds the scope of the subject token")
+		}
+	}
+
+	return scope, nil
+}
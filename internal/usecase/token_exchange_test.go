@@ -0,0 +1,145 @@
+package usecase
+
+import (
+	"auth-service/internal/domain"
+	"auth-service/pkg/jwt"
+	"context"
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+// userToken returns the claims of a user's access token issued to a client
+func userToken(clientID string, audience ...string) *jwt.Claims {
+	claims := &jwt.Claims{UserID: "user-1", ClientID: clientID, Scope: "profile"}
+	claims.Subject = "user-1"
+	claims.Audience = audience
+	claims.ExpiresAt = gojwt.NewNumericDate(time.Now().Add(10 * time.Minute))
+	return claims
+}
+
+func TestExchangeTokenSubjectPolicy(t *testing.T) {
+	client := &domain.Client{
+		ID:                            "orders-service",
+		SecretHash:                    "hash",
+		Scopes:                        []string{ScopeProfile},
+		TokenExchangeAudiences:        []string{"https://billing.example.com"},
+		TokenExchangeSubjectClients:   []string{"web-app"},
+		TokenExchangeSubjectAudiences: []string{"https://orders.example.com"},
+	}
+	firstPartyClient := *client
+	firstPartyClient.TokenExchangeSubjectClients = []string{domain.FirstPartySubjectClient}
+	firstPartyClient.TokenExchangeSubjectAudiences = []string{testIssuer}
+	machine := &jwt.Claims{ClientID: "worker"}
+	machine.Audience = gojwt.ClaimStrings{"orders-service"}
+	machine.ExpiresAt = gojwt.NewNumericDate(time.Now().Add(time.Minute))
+
+	tests := []struct {
+		name     string
+		client   *domain.Client
+		subject  *jwt.Claims
+		wantCode string
+	}{
+		{"token of an allowed client for an audience", client, userToken("web-app", "https://orders.example.com"), ""},
+		{"token of an allowed client for the client ID", client, userToken("web-app", "orders-service"), ""},
+		{"token of the client itself", client, userToken("orders-service", "orders-service"), ""},
+		{"token for another service", client, userToken("web-app", "https://inventory.example.com"), ErrorInvalidGrant},
+		{"token without audience", client, userToken("web-app"), ErrorInvalidGrant},
+		{"token of another client", client, userToken("mobile-app", "https://orders.example.com"), ErrorInvalidGrant},
+		{"first-party token without entry", client, userToken("", testIssuer), ErrorInvalidGrant},
+		{"first-party token with entry", &firstPartyClient, userToken("", testIssuer), ""},
+		{"machine token", client, machine, ErrorInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newOAuthTest(t)
+			test.auth.tokens = map[string]*jwt.Claims{"subject": tt.subject}
+			req := TokenRequest{
+				SubjectToken:     "subject",
+				SubjectTokenType: TokenTypeAccessToken,
+				Audience:         "https://billing.example.com",
+			}
+
+			resp, err := test.exchangeToken(context.Background(), tt.client, req)
+			if tt.wantCode != "" {
+				expectOAuthError(t, err, tt.wantCode)
+				return
+			}
+			if err != nil || resp.AccessToken == "" {
+				t.Fatalf("expected a token, got %v", err)
+			}
+			claims, err := test.jwtManager.ValidateToken(resp.AccessToken)
+			if err != nil || claims.UserID != "user-1" || claims.Act == nil || claims.Act.Subject != tt.client.ID {
+				t.Fatalf("expected a token of user-1 acted on by %s, got %+v (%v)", tt.client.ID, claims, err)
+			}
+		})
+	}
+}
+
+func TestExchangeBoundTokens(t *testing.T) {
+	client := &domain.Client{
+		ID:                     "orders-service",
+		SecretHash:             "hash",
+		Scopes:                 []string{ScopeProfile},
+		TokenExchangeAudiences: []string{"https://billing.example.com"},
+	}
+	bound := func(cnf jwt.Confirmation) *jwt.Claims {
+		claims := userToken("orders-service", "orders-service")
+		claims.Cnf = &cnf
+		return claims
+	}
+	dpopKey := jwt.Confirmation{JKT: "dpop-key"}
+	certificate := jwt.Confirmation{X5TS256: "certificate"}
+
+	tests := []struct {
+		name     string
+		subject  *jwt.Claims
+		actor    *jwt.Claims
+		cnf      jwt.Confirmation
+		wantCode string
+	}{
+		{"DPoP-bound subject without proof", bound(dpopKey), nil, jwt.Confirmation{}, ErrorInvalidGrant},
+		{"DPoP-bound subject with proof of another key", bound(dpopKey), nil, jwt.Confirmation{JKT: "other-key"}, ErrorInvalidGrant},
+		{"DPoP-bound subject with proof", bound(dpopKey), nil, dpopKey, ""},
+		{"certificate-bound subject without certificate", bound(certificate), nil, jwt.Confirmation{}, ErrorInvalidGrant},
+		{"certificate-bound subject with certificate", bound(certificate), nil, certificate, ""},
+		{"DPoP-bound actor without proof", userToken("orders-service", "orders-service"), bound(dpopKey), jwt.Confirmation{}, ErrorInvalidGrant},
+		{"DPoP-bound actor with proof", userToken("orders-service", "orders-service"), bound(dpopKey), dpopKey, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			test := newOAuthTest(t)
+			test.auth.tokens = map[string]*jwt.Claims{"subject": tt.subject}
+			req := TokenRequest{
+				SubjectToken:     "subject",
+				SubjectTokenType: TokenTypeAccessToken,
+				Audience:         "https://billing.example.com",
+				cnf:              tt.cnf,
+			}
+			if tt.actor != nil {
+				test.auth.tokens["actor"] = tt.actor
+				req.ActorToken = "actor"
+				req.ActorTokenType = TokenTypeAccessToken
+			}
+
+			resp, err := test.exchangeToken(context.Background(), client, req)
+			if tt.wantCode != "" {
+				expectOAuthError(t, err, tt.wantCode)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The exchanged token keeps the binding
+			claims, err := test.jwtManager.ValidateToken(resp.AccessToken)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if claims.Cnf == nil || *claims.Cnf != tt.cnf {
+				t.Errorf("cnf = %v, want %v", claims.Cnf, tt.cnf)
+			}
+		})
+	}
+}
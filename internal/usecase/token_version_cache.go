@@ -0,0 +1,68 @@
+package usecase
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenVersionCacheTTL bounds how long another replica may keep accepting
+// access tokens after a user's token version was bumped
+const tokenVersionCacheTTL = 30 * time.Second
+
+// tokenVersionCache caches the current token version of users, so that
+// validating an access token does not query the database on every request
+type tokenVersionCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]tokenVersionEntry
+}
+
+type tokenVersionEntry struct {
+	version   int
+	active    bool // false for suspended or deleted users
+	expiresAt time.Time
+}
+
+func newTokenVersionCache(ttl time.Duration) *tokenVersionCache {
+	return &tokenVersionCache{
+		ttl:     ttl,
+		entries: make(map[string]tokenVersionEntry),
+	}
+}
+
+// get returns the cached entry for a user, if present and not expired
+func (c *tokenVersionCache) get(userID string) (tokenVersionEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[userID]
+	if !ok {
+		return tokenVersionEntry{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.entries, userID)
+		return tokenVersionEntry{}, false
+	}
+	return entry, true
+}
+
+// set caches the token version of a user and returns the new entry
+func (c *tokenVersionCache) set(userID string, version int, active bool) tokenVersionEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := tokenVersionEntry{
+		version:   version,
+		active:    active,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	c.entries[userID] = entry
+	return entry
+}
+
+func (c *tokenVersionCache) invalidate(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, userID)
+}
@@ -0,0 +1,20 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "family_id" character varying(16) NULL, ADD COLUMN "parent_id" bigint NULL, ADD COLUMN "rotated_at" timestamptz NULL;
+-- Backfill: every existing token starts its own family
+UPDATE "refresh_tokens" SET "family_id" = "id"::text WHERE "family_id" IS NULL;
+ALTER TABLE "refresh_tokens" ALTER COLUMN "family_id" SET NOT NULL;
+-- Create index "idx_refresh_tokens_family_id" to table: "refresh_tokens"
+CREATE INDEX "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+-- Create "security_events" table
+CREATE TABLE "security_events" (
+  "id" bigserial NOT NULL,
+  "user_id" character varying(16) NULL,
+  "type" character varying(64) NOT NULL,
+  "details" text NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_security_events_type" to table: "security_events"
+CREATE INDEX "idx_security_events_type" ON "security_events" ("type");
+-- Create index "idx_security_events_user_id" to table: "security_events"
+CREATE INDEX "idx_security_events_user_id" ON "security_events" ("user_id");
@@ -0,0 +1,8 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ALTER COLUMN "token" DROP NOT NULL, ADD COLUMN "token_hash" character varying(64) NULL;
+-- Drop index "idx_refresh_tokens_token" from table: "refresh_tokens"
+DROP INDEX "idx_refresh_tokens_token";
+-- Create index "idx_refresh_tokens_token_hash" to table: "refresh_tokens"
+CREATE UNIQUE INDEX "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+-- Existing plaintext tokens are hashed by the service on startup, since the
+-- HMAC secret (JWT_REFRESH_TOKEN_SECRET) is not available to migrations
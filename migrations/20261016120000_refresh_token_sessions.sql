@@ -0,0 +1,5 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "authenticated_at" timestamptz NULL, ADD COLUMN "ip_address" character varying(64) NULL, ADD COLUMN "user_agent" text NULL;
+-- Backfill: existing sessions started when their token was issued
+UPDATE "refresh_tokens" SET "authenticated_at" = COALESCE("created_at", now()) WHERE "authenticated_at" IS NULL;
+ALTER TABLE "refresh_tokens" ALTER COLUMN "authenticated_at" SET NOT NULL;
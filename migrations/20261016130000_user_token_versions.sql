@@ -0,0 +1,2 @@
+-- Modify "users" table
+ALTER TABLE "users" ADD COLUMN "token_version" bigint NOT NULL DEFAULT 0, ADD COLUMN "suspended_at" timestamptz NULL;
@@ -0,0 +1,12 @@
+-- Create "revoked_access_tokens" table
+CREATE TABLE "revoked_access_tokens" (
+  "jti" character varying(64) NOT NULL,
+  "user_id" character varying(16) NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("jti")
+);
+-- Create index "idx_revoked_access_tokens_expires_at" to table: "revoked_access_tokens"
+CREATE INDEX "idx_revoked_access_tokens_expires_at" ON "revoked_access_tokens" ("expires_at");
+-- Create index "idx_revoked_access_tokens_user_id" to table: "revoked_access_tokens"
+CREATE INDEX "idx_revoked_access_tokens_user_id" ON "revoked_access_tokens" ("user_id");
@@ -0,0 +1,9 @@
+-- Create "clients" table
+CREATE TABLE "clients" (
+  "id" character varying(255) NOT NULL,
+  "secret_hash" character varying(60) NULL,
+  "name" text NOT NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
@@ -0,0 +1,4 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "client_id" character varying(255) NULL;
+-- Create index "idx_refresh_tokens_client_id" to table: "refresh_tokens"
+CREATE INDEX "idx_refresh_tokens_client_id" ON "refresh_tokens" ("client_id");
@@ -0,0 +1,2 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "scope" text NULL;
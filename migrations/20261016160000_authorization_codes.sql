@@ -0,0 +1,30 @@
+-- Create "authorization_codes" table
+CREATE TABLE "authorization_codes" (
+  "id" bigserial NOT NULL,
+  "code_hash" character varying(64) NOT NULL,
+  "client_id" character varying(255) NOT NULL,
+  "user_id" character varying(16) NOT NULL,
+  "redirect_uri" text NOT NULL,
+  "scope" text NULL,
+  "nonce" text NULL,
+  "code_challenge" character varying(128) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "authenticated_at" timestamptz NOT NULL,
+  "ip_address" character varying(64) NULL,
+  "user_agent" text NULL,
+  "session_id" character varying(16) NULL,
+  "access_token_id" character varying(64) NULL,
+  "access_token_expires_at" timestamptz NULL,
+  "reused_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_authorization_codes_code_hash" to table: "authorization_codes"
+CREATE UNIQUE INDEX "idx_authorization_codes_code_hash" ON "authorization_codes" ("code_hash");
+-- Create index "idx_authorization_codes_expires_at" to table: "authorization_codes"
+CREATE INDEX "idx_authorization_codes_expires_at" ON "authorization_codes" ("expires_at");
+-- Create index "idx_authorization_codes_user_id" to table: "authorization_codes"
+CREATE INDEX "idx_authorization_codes_user_id" ON "authorization_codes" ("user_id");
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "redirect_uris" text NULL;
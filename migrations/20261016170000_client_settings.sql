@@ -0,0 +1,5 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "grant_types" text NULL, ADD COLUMN "scopes" text NULL, ADD COLUMN "allowed_origins" text NULL, ADD COLUMN "access_token_lifetime" bigint NOT NULL DEFAULT 0, ADD COLUMN "refresh_token_lifetime" bigint NOT NULL DEFAULT 0;
+-- Keep existing clients working: clients with redirect URIs use the authorization code flow
+UPDATE "clients" SET "scopes" = '["openid","profile","email"]';
+UPDATE "clients" SET "grant_types" = '["authorization_code","refresh_token"]' WHERE "redirect_uris" IS NOT NULL;
@@ -0,0 +1,12 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "token_endpoint_auth_method" character varying(64) NULL, ADD COLUMN "jwks" text NULL, ADD COLUMN "tls_client_auth_subject_dn" text NULL;
+-- Create "used_client_assertions" table
+CREATE TABLE "used_client_assertions" (
+  "client_id" character varying(255) NOT NULL,
+  "jti" character varying(255) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("client_id", "jti")
+);
+-- Create index "idx_used_client_assertions_expires_at" to table: "used_client_assertions"
+CREATE INDEX "idx_used_client_assertions_expires_at" ON "used_client_assertions" ("expires_at");
@@ -0,0 +1,23 @@
+-- Create "device_codes" table
+CREATE TABLE "device_codes" (
+  "id" bigserial NOT NULL,
+  "device_code_hash" character varying(64) NOT NULL,
+  "user_code" character varying(8) NOT NULL,
+  "client_id" character varying(255) NOT NULL,
+  "scope" text NULL,
+  "status" character varying(16) NOT NULL,
+  "user_id" character varying(16) NULL,
+  "authenticated_at" timestamptz NULL,
+  "poll_interval" bigint NOT NULL,
+  "last_polled_at" timestamptz NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_device_codes_device_code_hash" to table: "device_codes"
+CREATE UNIQUE INDEX "idx_device_codes_device_code_hash" ON "device_codes" ("device_code_hash");
+-- Create index "idx_device_codes_expires_at" to table: "device_codes"
+CREATE INDEX "idx_device_codes_expires_at" ON "device_codes" ("expires_at");
+-- Create index "idx_device_codes_user_code" to table: "device_codes"
+CREATE UNIQUE INDEX "idx_device_codes_user_code" ON "device_codes" ("user_code");
@@ -0,0 +1,2 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "token_exchange_audiences" text NULL, ADD COLUMN "token_exchange_subject_clients" text NULL, ADD COLUMN "token_exchange_subject_audiences" text NULL;
@@ -0,0 +1,2 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "resources" text NULL;
@@ -0,0 +1,15 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "first_party" boolean NOT NULL DEFAULT false;
+-- Modify "authorization_codes" table
+ALTER TABLE "authorization_codes" ADD COLUMN "state" text NULL, ADD COLUMN "consent_pending" boolean NOT NULL DEFAULT false;
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "access_token_id" character varying(64) NULL, ADD COLUMN "access_token_expires_at" timestamptz NULL;
+-- Create "consents" table
+CREATE TABLE "consents" (
+  "user_id" character varying(16) NOT NULL,
+  "client_id" character varying(255) NOT NULL,
+  "scope" text NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("user_id", "client_id")
+);
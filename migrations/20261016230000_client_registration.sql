@@ -0,0 +1,2 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "registration_access_token_hash" character varying(64) NULL, ADD COLUMN "jwks_uri" text NULL;
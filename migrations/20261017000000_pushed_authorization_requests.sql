@@ -0,0 +1,23 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "require_pushed_authorization_requests" boolean NOT NULL DEFAULT false;
+-- Create "pushed_authorization_requests" table
+CREATE TABLE "pushed_authorization_requests" (
+  "id" bigserial NOT NULL,
+  "request_uri_hash" character varying(64) NOT NULL,
+  "client_id" character varying(255) NOT NULL,
+  "response_type" character varying(32) NOT NULL,
+  "redirect_uri" text NOT NULL,
+  "scope" text NULL,
+  "state" text NULL,
+  "nonce" text NULL,
+  "code_challenge" character varying(128) NOT NULL,
+  "code_challenge_method" character varying(16) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "used_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_pushed_authorization_requests_expires_at" to table: "pushed_authorization_requests"
+CREATE INDEX "idx_pushed_authorization_requests_expires_at" ON "pushed_authorization_requests" ("expires_at");
+-- Create index "idx_pushed_authorization_requests_request_uri_hash" to table: "pushed_authorization_requests"
+CREATE UNIQUE INDEX "idx_pushed_authorization_requests_request_uri_hash" ON "pushed_authorization_requests" ("request_uri_hash");
@@ -0,0 +1,12 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "dpop_jkt" character varying(64) NULL;
+-- Create "used_dpop_proofs" table
+CREATE TABLE "used_dpop_proofs" (
+  "jkt" character varying(64) NOT NULL,
+  "jti" character varying(255) NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NULL,
+  PRIMARY KEY ("jkt", "jti")
+);
+-- Create index "idx_used_dpop_proofs_expires_at" to table: "used_dpop_proofs"
+CREATE INDEX "idx_used_dpop_proofs_expires_at" ON "used_dpop_proofs" ("expires_at");
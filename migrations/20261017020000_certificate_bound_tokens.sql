@@ -0,0 +1,2 @@
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "x5t_s256" character varying(64) NULL;
@@ -0,0 +1,21 @@
+-- Modify "clients" table
+ALTER TABLE "clients" ADD COLUMN "post_logout_redirect_uris" text NULL, ADD COLUMN "backchannel_logout_uri" text NULL, ADD COLUMN "frontchannel_logout_uri" text NULL;
+-- Create "logout_deliveries" table
+CREATE TABLE "logout_deliveries" (
+  "id" bigserial NOT NULL,
+  "client_id" character varying(255) NOT NULL,
+  "user_id" character varying(16) NOT NULL,
+  "session_id" character varying(16) NOT NULL,
+  "status" character varying(16) NOT NULL DEFAULT 'pending',
+  "attempts" bigint NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL,
+  "last_error" text NULL,
+  "delivered_at" timestamptz NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_logout_deliveries_client_id" to table: "logout_deliveries"
+CREATE INDEX "idx_logout_deliveries_client_id" ON "logout_deliveries" ("client_id");
+-- Create index "idx_logout_deliveries_next_attempt_at" to table: "logout_deliveries"
+CREATE INDEX "idx_logout_deliveries_next_attempt_at" ON "logout_deliveries" ("next_attempt_at");
@@ -0,0 +1,29 @@
+-- Create "sso_sessions" table
+CREATE TABLE "sso_sessions" (
+  "id" character varying(16) NOT NULL,
+  "token_hash" character varying(64) NOT NULL,
+  "user_id" character varying(16) NOT NULL,
+  "authenticated_at" timestamptz NOT NULL,
+  "last_active_at" timestamptz NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz NULL,
+  "ip_address" character varying(64) NULL,
+  "user_agent" text NULL,
+  "created_at" timestamptz NULL,
+  "updated_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_sso_sessions_token_hash" to table: "sso_sessions"
+CREATE UNIQUE INDEX "idx_sso_sessions_token_hash" ON "sso_sessions" ("token_hash");
+-- Create index "idx_sso_sessions_user_id" to table: "sso_sessions"
+CREATE INDEX "idx_sso_sessions_user_id" ON "sso_sessions" ("user_id");
+-- Create index "idx_sso_sessions_expires_at" to table: "sso_sessions"
+CREATE INDEX "idx_sso_sessions_expires_at" ON "sso_sessions" ("expires_at");
+-- Modify "refresh_tokens" table
+ALTER TABLE "refresh_tokens" ADD COLUMN "sso_session_id" character varying(16) NULL;
+-- Create index "idx_refresh_tokens_sso_session_id" to table: "refresh_tokens"
+CREATE INDEX "idx_refresh_tokens_sso_session_id" ON "refresh_tokens" ("sso_session_id");
+-- Modify "authorization_codes" table
+ALTER TABLE "authorization_codes" ADD COLUMN "sso_session_id" character varying(16) NULL;
+-- Modify "pushed_authorization_requests" table
+ALTER TABLE "pushed_authorization_requests" ADD COLUMN "prompt" character varying(64) NULL, ADD COLUMN "max_age" bigint NULL;
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,15 +13,31 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	Admin        AdminConfig
+	Device       DeviceConfig
+	Registration RegistrationConfig
+	Session      SessionConfig
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
 	Port string
 	Env  string
+	// ClientCertHeader is the header a TLS-terminating proxy forwards the
+	// client certificate in (URL-encoded PEM); empty to only trust TLS connections
+	ClientCertHeader string
+	// TLSCertFile and TLSKeyFile enable serving HTTPS directly, requesting
+	// client certificates for mutual TLS (RFC 8705)
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSClientCAFile holds the CAs tls_client_auth client certificates must
+	// be issued by; without it, tls_client_auth is unavailable, while
+	// self_signed_tls_client_auth and certificate-bound tokens accept any
+	// certificate
+	TLSClientCAFile string
 }
 
 // DatabaseConfig holds database configuration
@@ -34,10 +52,39 @@ type DatabaseConfig struct {
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
+	Issuer               string // OpenID Connect issuer identifier, the public base URL of the service
+	Audience             string // audience of access tokens for the service's own API; defaults to the issuer
 	PrivateKeyPath       string
 	PublicKeyPath        string
+	VerificationKeyPaths []string // retired and next public keys, published in JWKS
+	SigningAlgorithm     string   // RS256, ES256 or EdDSA; inferred from the private key if empty
+	AllowedAlgorithms    []string // algorithms accepted on validation; defaults to those of the key ring
 	AccessTokenDuration  time.Duration
 	RefreshTokenDuration time.Duration
+	RefreshTokenSecret   string // HMAC key for hashing refresh tokens at rest
+}
+
+// AdminConfig holds admin API configuration
+type AdminConfig struct {
+	APIKey string // bearer key for the admin API; the API is disabled if empty
+}
+
+// DeviceConfig holds device authorization grant configuration (RFC 8628)
+type DeviceConfig struct {
+	// VerificationURI is the page of the first-party web app where signed-in
+	// users enter user codes; defaults to /device on the issuer
+	VerificationURI string
+}
+
+// RegistrationConfig holds dynamic client registration configuration (RFC 7591)
+type RegistrationConfig struct {
+	InitialAccessToken string // bearer token for registering clients; registration is disabled if empty
+}
+
+// SessionConfig holds browser SSO session configuration
+type SessionConfig struct {
+	IdleTimeout     time.Duration // sessions end after this long without use
+	AbsoluteTimeout time.Duration // sessions end this long after the user signed in
 }
 
 // Load loads configuration from environment variables
@@ -49,6 +96,11 @@ func Load() (*Config, error) {
 		Server: ServerConfig{
 			Port: getEnv("PORT", "3000"),
 			Env:  getEnv("APP_ENV", "development"),
+
+			ClientCertHeader: getEnv("CLIENT_CERT_HEADER", ""),
+			TLSCertFile:      getEnv("TLS_CERT_FILE", ""),
+			TLSKeyFile:       getEnv("TLS_KEY_FILE", ""),
+			TLSClientCAFile:  getEnv("TLS_CLIENT_CA_FILE", ""),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -59,12 +111,40 @@ func Load() (*Config, error) {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
+			Issuer:               strings.TrimSuffix(getEnv("JWT_ISSUER", "http://localhost:3000"), "/"),
+			Audience:             getEnv("JWT_AUDIENCE", ""),
 			PrivateKeyPath:       getEnv("JWT_PRIVATE_KEY_PATH", "./keys/private_key.pem"),
 			PublicKeyPath:        getEnv("JWT_PUBLIC_KEY_PATH", "./keys/public_key.pem"),
+			VerificationKeyPaths: getEnvAsSlice("JWT_VERIFICATION_KEY_PATHS"),
+			SigningAlgorithm:     getEnv("JWT_SIGNING_ALGORITHM", ""),
+			AllowedAlgorithms:    getEnvAsSlice("JWT_ALLOWED_ALGORITHMS"),
 			AccessTokenDuration:  parseDuration(getEnv("JWT_ACCESS_TOKEN_DURATION", "15m")),
 			RefreshTokenDuration: parseDuration(getEnv("JWT_REFRESH_TOKEN_DURATION", "168h")),
+			RefreshTokenSecret:   getEnv("JWT_REFRESH_TOKEN_SECRET", ""),
+		},
+		Admin: AdminConfig{
+			APIKey: getEnv("ADMIN_API_KEY", ""),
+		},
+		Device: DeviceConfig{
+			VerificationURI: getEnv("DEVICE_VERIFICATION_URI", ""),
+		},
+		Registration: RegistrationConfig{
+			InitialAccessToken: getEnv("REGISTRATION_INITIAL_ACCESS_TOKEN", ""),
+		},
+		Session: SessionConfig{
+			IdleTimeout:     parseDuration(getEnv("SSO_SESSION_IDLE_TIMEOUT", "30m")),
+			AbsoluteTimeout: parseDuration(getEnv("SSO_SESSION_ABSOLUTE_TIMEOUT", "12h")),
 		},
 	}
+	if cfg.Device.VerificationURI == "" {
+		cfg.Device.VerificationURI = cfg.JWT.Issuer + "/device"
+	}
+
+	// A client could set the header itself when connecting to the server
+	// directly, only a proxy in front of it can be trusted to set it
+	if cfg.Server.ClientCertHeader != "" && cfg.Server.TLSCertFile != "" {
+		return nil, errors.New("CLIENT_CERT_HEADER can't be used with TLS_CERT_FILE")
+	}
 
 	return cfg, nil
 }
@@ -99,3 +179,13 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsSlice(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
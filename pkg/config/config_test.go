@@ -0,0 +1,16 @@
+package config
+
+import "testing"
+
+func TestLoadRejectsClientCertHeaderWithTLS(t *testing.T) {
+	t.Setenv("CLIENT_CERT_HEADER", "X-Client-Cert")
+	t.Setenv("TLS_CERT_FILE", "server.crt")
+	if _, err := Load(); err == nil {
+		t.Error("expected CLIENT_CERT_HEADER with TLS_CERT_FILE to be rejected")
+	}
+
+	t.Setenv("TLS_CERT_FILE", "")
+	if _, err := Load(); err != nil {
+		t.Errorf("CLIENT_CERT_HEADER behind a proxy: %v", err)
+	}
+}
@@ -44,6 +44,17 @@ func AutoMigrate(db *gorm.DB) error {
 	err := db.AutoMigrate(
 		&domain.User{},
 		&domain.RefreshToken{},
+		&domain.SecurityEvent{},
+		&domain.RevokedAccessToken{},
+		&domain.Client{},
+		&domain.AuthorizationCode{},
+		&domain.UsedClientAssertion{},
+		&domain.UsedDPoPProof{},
+		&domain.DeviceCode{},
+		&domain.Consent{},
+		&domain.PushedAuthorizationRequest{},
+		&domain.LogoutDelivery{},
+		&domain.SSOSession{},
 	)
 
 	if err != nil {
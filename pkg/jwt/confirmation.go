@@ -0,0 +1,27 @@
+package jwt
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+)
+
+// Confirmation holds the keys a token is bound to (RFC 7800)
+type Confirmation struct {
+	// JKT is the JWK thumbprint of the DPoP proof key (RFC 9449 section 6.1)
+	JKT string `json:"jkt,omitempty"`
+	// X5TS256 is the thumbprint of the mutual TLS client certificate (RFC 8705 section 3.1)
+	X5TS256 string `json:"x5t#S256,omitempty"`
+}
+
+// IsZero checks if the confirmation binds to no key, as for bearer tokens
+func (c Confirmation) IsZero() bool {
+	return c == Confirmation{}
+}
+
+// CertificateThumbprint computes the x5t#S256 thumbprint of a certificate:
+// the base64url encoded SHA-256 hash of its DER encoding
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
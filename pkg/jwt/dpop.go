@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// dpopProofType is the "typ" header of DPoP proofs (RFC 9449 section 4.2)
+const dpopProofType = "dpop+jwt"
+
+// DPoPProof holds the claims of a DPoP proof whose signature was verified
+// with the public key in its header. Checking the claims against the
+// request is left to the caller
+type DPoPProof struct {
+	ID              string
+	Method          string
+	URL             string
+	IssuedAt        time.Time
+	AccessTokenHash string // only set on proofs presented with an access token
+	Nonce           string
+	JKT             string // JWK thumbprint of the proof key
+}
+
+// dpopClaims represents the claims of a DPoP proof
+type dpopClaims struct {
+	Method          string `json:"htm"`
+	URL             string `json:"htu"`
+	AccessTokenHash string `json:"ath,omitempty"`
+	Nonce           string `json:"nonce,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// ParseDPoPProof verifies a DPoP proof (RFC 9449 section 4.3) against the
+// public key in its "jwk" header and returns its claims
+func ParseDPoPProof(proof string) (*DPoPProof, error) {
+	var jkt string
+	claims := &dpopClaims{}
+	_, err := jwt.ParseWithClaims(proof, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+			return nil, fmt.Errorf("unexpected token type %q", typ)
+		}
+
+		header, ok := token.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("missing jwk header")
+		}
+		jsonBytes, err := json.Marshal(header)
+		if err != nil {
+			return nil, err
+		}
+		var jwk jsonWebKey
+		if err := json.Unmarshal(jsonBytes, &jwk); err != nil {
+			return nil, fmt.Errorf("invalid jwk header: %w", err)
+		}
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			return nil, err
+		}
+
+		jkt, err = thumbprint(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return publicKey, nil
+	}, jwt.WithValidMethods(SupportedAlgorithms))
+	if err != nil {
+		return nil, fmt.Errorf("invalid DPoP proof: %w", err)
+	}
+
+	if claims.ID == "" || claims.Method == "" || claims.URL == "" || claims.IssuedAt == nil {
+		return nil, fmt.Errorf("invalid DPoP proof: jti, htm, htu and iat are required")
+	}
+
+	return &DPoPProof{
+		ID:              claims.ID,
+		Method:          claims.Method,
+		URL:             claims.URL,
+		IssuedAt:        claims.IssuedAt.Time,
+		AccessTokenHash: claims.AccessTokenHash,
+		Nonce:           claims.Nonce,
+		JKT:             jkt,
+	}, nil
+}
+
+// DPoPAccessTokenHash computes the ath claim of DPoP proofs presented with
+// an access token: the base64url encoded SHA-256 hash of the token
+func DPoPAccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// DPoPNonce returns the server-provided DPoP nonce of a time window (RFC 9449
+// section 8). Nonces are derived from the refresh token secret, so that all
+// replicas issue and accept the same nonces without shared state
+func (m *JWTManager) DPoPNonce(window int64) string {
+	mac := hmac.New(sha256.New, m.refreshTokenSecret)
+	mac.Write([]byte("dpop-nonce:" + strconv.FormatInt(window, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
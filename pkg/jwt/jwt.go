@@ -1,140 +1,316 @@
 package jwt
 
 import (
+	"auth-service/pkg/config"
+	"crypto"
+	"crypto/hmac"
 	"crypto/rand"
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/base64"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"encoding/pem"
 	"fmt"
-	"math/big"
-	"os"
+	"slices"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// JWTManager handles JWT operations with RS256
+// JWTManager handles JWT operations with RS256, ES256 or EdDSA
+// Tokens are signed with the active key of the key ring and verified
+// against any key in the ring, selected by the "kid" header
 type JWTManager struct {
-	privateKey           *rsa.PrivateKey
-	publicKey            *rsa.PublicKey
+	issuer               string
+	audience             string
+	activeKey            *signingKey
+	keys                 []*signingKey
+	keysByID             map[string]*signingKey
+	allowedAlgorithms    []string
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
+	refreshTokenSecret   []byte
 }
 
 // Claims represents the JWT claims
+// Tokens of machine principals (client credentials grant) have no user,
+// and their subject is the client ID
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID    string `json:"user_id,omitempty"`
+	Email     string `json:"email,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	// ClientID is the OAuth client the token was issued to, empty for first-party apps
+	ClientID string `json:"client_id,omitempty"`
+	// Scope is the space-separated list of granted scopes
+	Scope string `json:"scope,omitempty"`
+	// TokenVersion is the user's token version at issuance
+	TokenVersion int `json:"ver"`
+	// Act is the party acting on behalf of the subject, set on tokens
+	// obtained through token exchange
+	Act *Actor `json:"act,omitempty"`
+	// Cnf is the key the token is bound to, set on sender-constrained tokens
+	Cnf *Confirmation `json:"cnf,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Actor identifies the party acting on behalf of the subject of a token
+// (RFC 8693 section 4.1). Prior actors of a delegation chain are nested
+type Actor struct {
+	Subject  string `json:"sub"`
+	ClientID string `json:"client_id,omitempty"`
+	Act      *Actor `json:"act,omitempty"`
+}
+
+// IsMachine checks if the token was issued to a client acting on its own
+// behalf rather than to a user
+func (c *Claims) IsMachine() bool {
+	return c.UserID == ""
+}
+
+// DPoPKeyThumbprint returns the thumbprint of the DPoP key the token is
+// bound to, empty if it is not bound to one
+func (c *Claims) DPoPKeyThumbprint() string {
+	if c.Cnf == nil {
+		return ""
+	}
+	return c.Cnf.JKT
+}
+
+// CertificateThumbprint returns the thumbprint of the client certificate the
+// token is bound to, empty if it is not bound to one
+func (c *Claims) CertificateThumbprint() string {
+	if c.Cnf == nil {
+		return ""
+	}
+	return c.Cnf.X5TS256
+}
+
+// IDTokenClaims represents the claims of an OpenID Connect ID token
+// Profile and email claims are only set when the matching scope was granted
+type IDTokenClaims struct {
+	Email           string `json:"email,omitempty"`
+	Name            string `json:"name,omitempty"`
+	Nonce           string `json:"nonce,omitempty"`
+	AuthTime        int64  `json:"auth_time,omitempty"` // Unix time of the user's authentication
+	AccessTokenHash string `json:"at_hash,omitempty"`
+	SessionID       string `json:"sid,omitempty"`
+	AuthorizedParty string `json:"azp,omitempty"`
 	jwt.RegisteredClaims
 }
 
 // NewJWTManager creates a new JWT manager
-func NewJWTManager(privateKeyPath, publicKeyPath string, accessTokenDuration, refreshTokenDuration time.Duration) (*JWTManager, error) {
-	privateKey, err := loadPrivateKey(privateKeyPath)
+func NewJWTManager(cfg *config.JWTConfig) (*JWTManager, error) {
+	if cfg.RefreshTokenSecret == "" {
+		return nil, fmt.Errorf("refresh token secret is required")
+	}
+
+	privateKey, derivedPublicKey, err := loadPrivateKey(cfg.PrivateKeyPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load private key: %w", err)
 	}
 
-	publicKey, err := loadPublicKey(publicKeyPath)
+	publicKey, err := loadPublicKey(cfg.PublicKeyPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load public key: %w", err)
 	}
 
-	return &JWTManager{
-		privateKey:           privateKey,
-		publicKey:            publicKey,
-		accessTokenDuration:  accessTokenDuration,
-		refreshTokenDuration: refreshTokenDuration,
-	}, nil
+	if !publicKeysEqual(derivedPublicKey, publicKey) {
+		return nil, fmt.Errorf("public key %s does not match private key %s", cfg.PublicKeyPath, cfg.PrivateKeyPath)
+	}
+
+	activeKey, err := newSigningKey(privateKey, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.SigningAlgorithm != "" && cfg.SigningAlgorithm != activeKey.alg {
+		return nil, fmt.Errorf("signing algorithm %s does not match %s private key", cfg.SigningAlgorithm, activeKey.alg)
+	}
+
+	m := &JWTManager{
+		issuer:               cfg.Issuer,
+		audience:             cfg.Audience,
+		activeKey:            activeKey,
+		keysByID:             make(map[string]*signingKey),
+		accessTokenDuration:  cfg.AccessTokenDuration,
+		refreshTokenDuration: cfg.RefreshTokenDuration,
+		refreshTokenSecret:   []byte(cfg.RefreshTokenSecret),
+	}
+	m.addKey(activeKey)
+	if m.audience == "" {
+		m.audience = m.issuer
+	}
+
+	// Load retired and next keys, which are only used for verification
+	for _, path := range cfg.VerificationKeyPaths {
+		publicKey, err := loadPublicKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load verification key %s: %w", path, err)
+		}
+
+		key, err := newSigningKey(nil, publicKey)
+		if err != nil {
+			return nil, err
+		}
+		m.addKey(key)
+	}
+
+	// Accept the algorithms of all keys in the ring unless restricted
+	m.allowedAlgorithms = cfg.AllowedAlgorithms
+	if len(m.allowedAlgorithms) == 0 {
+		for _, key := range m.keys {
+			m.allowedAlgorithms = appendUnique(m.allowedAlgorithms, key.alg)
+		}
+	}
+	for _, alg := range m.allowedAlgorithms {
+		if _, ok := signingMethods[alg]; !ok {
+			return nil, fmt.Errorf("unsupported signing algorithm: %s", alg)
+		}
+	}
+
+	return m, nil
 }
 
-// GenerateAccessToken generates a new access token
-func (m *JWTManager) GenerateAccessToken(userID string, email string) (string, error) {
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTokenDuration)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "auth-service",
-			Subject:   userID,
-		},
+// GenerateAccessToken generates a new access token with the default lifetime
+// The registered claims (expiry, issuer, subject, unique ID) are set by the manager
+func (m *JWTManager) GenerateAccessToken(claims Claims) (string, error) {
+	return m.GenerateAccessTokenWithLifetime(claims, m.accessTokenDuration)
+}
+
+// GenerateAccessTokenWithLifetime generates a new access token that expires after lifetime
+// The audience is kept, defaulting to the service's own API, and the other
+// registered claims are set by the manager
+func (m *JWTManager) GenerateAccessTokenWithLifetime(claims Claims, lifetime time.Duration) (string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	subject := claims.UserID
+	if claims.IsMachine() {
+		if claims.ClientID == "" {
+			return "", fmt.Errorf("access token requires a user or client")
+		}
+		subject = claims.ClientID
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-	return token.SignedString(m.privateKey)
+	audience := claims.Audience
+	if len(audience) == 0 {
+		audience = jwt.ClaimStrings{m.audience}
+	}
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(lifetime)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		Issuer:    m.issuer,
+		Subject:   subject,
+		Audience:  audience,
+		ID:        jti,
+	}
+
+	token := jwt.NewWithClaims(signingMethods[m.activeKey.alg], claims)
+	token.Header["kid"] = m.activeKey.id
+	return token.SignedString(m.activeKey.privateKey)
+}
+
+// GenerateIDToken generates an OpenID Connect ID token for a user, audienced
+// to the client that requested it. The registered claims are set by the
+// manager, and at_hash is computed from the access token issued alongside
+func (m *JWTManager) GenerateIDToken(userID, clientID, accessToken string, claims IDTokenClaims) (string, error) {
+	atHash, err := accessTokenHash(m.activeKey.alg, accessToken)
+	if err != nil {
+		return "", err
+	}
+	claims.AccessTokenHash = atHash
+	claims.AuthorizedParty = clientID
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(m.accessTokenDuration)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		Issuer:    m.issuer,
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{clientID},
+	}
+
+	token := jwt.NewWithClaims(signingMethods[m.activeKey.alg], claims)
+	token.Header["kid"] = m.activeKey.id
+	return token.SignedString(m.activeKey.privateKey)
 }
 
 // GenerateRefreshToken generates a cryptographically secure random refresh token
 // Returns the token string and expiration time
 func (m *JWTManager) GenerateRefreshToken(userID string) (string, time.Time, error) {
-	// Generate 32 bytes (256 bits) of random data
-	tokenBytes := make([]byte, 32)
-	if _, err := rand.Read(tokenBytes); err != nil {
+	// Generate 32 bytes (256 bits) of random data, hex encoded for easy
+	// storage and transmission
+	tokenString, err := randomHex(32)
+	if err != nil {
 		return "", time.Time{}, fmt.Errorf("failed to generate random token: %w", err)
 	}
 
-	// Encode to hex string for easy storage and transmission
-	tokenString := hex.EncodeToString(tokenBytes)
 	expiresAt := time.Now().Add(m.refreshTokenDuration)
 
 	return tokenString, expiresAt, nil
 }
 
+// HashRefreshToken returns the keyed hash under which a refresh token is stored
+// Only the hash is persisted, so stored values cannot be used as tokens
+func (m *JWTManager) HashRefreshToken(token string) string {
+	mac := hmac.New(sha256.New, m.refreshTokenSecret)
+	mac.Write([]byte(token))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
 // ValidateToken validates a JWT access token and returns the claims
-func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Verify signing method
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return m.publicKey, nil
-	})
+// The audience is only checked if required by an option
+func (m *JWTManager) ValidateToken(tokenString string, opts ...ValidationOption) (*Claims, error) {
+	var options validationOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.keyFunc, jwt.WithValidMethods(m.allowedAlgorithms))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("invalid token")
 	}
 
-	return nil, fmt.Errorf("invalid token")
+	if claims.Issuer != m.issuer {
+		return nil, fmt.Errorf("unexpected issuer: %s", claims.Issuer)
+	}
+	if options.audience != "" && !slices.Contains(claims.Audience, options.audience) {
+		return nil, fmt.Errorf("token is not intended for %s", options.audience)
+	}
+
+	return claims, nil
 }
 
-// GetPublicKey returns the public key
-func (m *JWTManager) GetPublicKey() *rsa.PublicKey {
-	return m.publicKey
+// GetPublicKey returns the public key of the active signing key
+func (m *JWTManager) GetPublicKey() crypto.PublicKey {
+	return m.activeKey.publicKey
 }
 
 // GetJWKS returns the JSON Web Key Set
+// The active key is listed first, followed by retired and next keys
 func (m *JWTManager) GetJWKS() (map[string]interface{}, error) {
-	// Get the modulus and exponent from the public key
-	n := m.publicKey.N
-	e := m.publicKey.E
-
-	// Convert to base64 URL encoding
-	nBytes := n.Bytes()
-	nBase64 := base64.RawURLEncoding.EncodeToString(nBytes)
-
-	eBytes := big.NewInt(int64(e)).Bytes()
-	eBase64 := base64.RawURLEncoding.EncodeToString(eBytes)
+	keys := make([]map[string]interface{}, 0, len(m.keys))
+	for _, key := range m.keys {
+		jwk, err := publicJWK(key.publicKey)
+		if err != nil {
+			return nil, err
+		}
+		jwk["kid"] = key.id
+		jwk["use"] = "sig"
+		jwk["alg"] = key.alg
+		keys = append(keys, jwk)
+	}
 
 	// Create JWKS structure
 	jwks := map[string]interface{}{
-		"keys": []map[string]interface{}{
-			{
-				"kty": "RSA",
-				"use": "sig",
-				"alg": "RS256",
-				"n":   nBase64,
-				"e":   eBase64,
-			},
-		},
+		"keys": keys,
 	}
 
 	return jwks, nil
@@ -160,62 +336,85 @@ func (m *JWTManager) GetAccessTokenDuration() time.Duration {
 	return m.accessTokenDuration
 }
 
-// loadPrivateKey loads RSA private key from PEM file
-func loadPrivateKey(path string) (*rsa.PrivateKey, error) {
-	keyData, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
+// GetAudience returns the audience of access tokens for the service's own API
+func (m *JWTManager) GetAudience() string {
+	return m.audience
+}
 
-	block, _ := pem.Decode(keyData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
-	}
+// GetIssuer returns the OpenID Connect issuer identifier
+func (m *JWTManager) GetIssuer() string {
+	return m.issuer
+}
 
-	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		// Try PKCS8 format
-		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
-		if err != nil {
-			return nil, err
-		}
+// GetSigningAlgorithm returns the algorithm of the active signing key
+func (m *JWTManager) GetSigningAlgorithm() string {
+	return m.activeKey.alg
+}
 
-		var ok bool
-		privateKey, ok = key.(*rsa.PrivateKey)
-		if !ok {
-			return nil, fmt.Errorf("not an RSA private key")
-		}
+// GetActiveKeyID returns the key ID of the active signing key
+func (m *JWTManager) GetActiveKeyID() string {
+	return m.activeKey.id
+}
+
+// randomHex returns n cryptographically secure random bytes, hex encoded
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	return privateKey, nil
+// addKey adds a key to the key ring, ignoring duplicates
+func (m *JWTManager) addKey(key *signingKey) {
+	if _, exists := m.keysByID[key.id]; exists {
+		return
+	}
+	m.keys = append(m.keys, key)
+	m.keysByID[key.id] = key
 }
 
-// loadPublicKey loads RSA public key from PEM file
-func loadPublicKey(path string) (*rsa.PublicKey, error) {
-	keyData, err := os.ReadFile(path)
+// keyFunc returns the public key to verify a token issued by the manager
+func (m *JWTManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	key, err := m.verificationKey(token)
 	if err != nil {
 		return nil, err
 	}
 
-	block, _ := pem.Decode(keyData)
-	if block == nil {
-		return nil, fmt.Errorf("failed to decode PEM block")
+	// Verify signing method matches the key, so a key can't be used with another algorithm
+	if token.Method.Alg() != key.alg {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 	}
+	return key.publicKey, nil
+}
 
-	publicKey, err := x509.ParsePKCS1PublicKey(block.Bytes)
-	if err != nil {
-		// Try PKIX format
-		key, err := x509.ParsePKIXPublicKey(block.Bytes)
-		if err != nil {
-			return nil, err
-		}
+// verificationKey selects the key for a token by its "kid" header
+// Tokens issued before key IDs were introduced carry no "kid" and are
+// verified against the active key
+func (m *JWTManager) verificationKey(token *jwt.Token) (*signingKey, error) {
+	kidHeader, ok := token.Header["kid"]
+	if !ok {
+		return m.activeKey, nil
+	}
 
-		var ok bool
-		publicKey, ok = key.(*rsa.PublicKey)
-		if !ok {
-			return nil, fmt.Errorf("not an RSA public key")
-		}
+	kid, ok := kidHeader.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid kid header")
 	}
 
-	return publicKey, nil
+	key, ok := m.keysByID[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+
+	return key, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
 }
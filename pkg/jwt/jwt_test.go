@@ -0,0 +1,110 @@
+package jwt
+
+import (
+	"auth-service/pkg/config"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://auth.example.com"
+
+// newTestManager creates a JWT manager with a fresh Ed25519 key
+func newTestManager(t *testing.T) *JWTManager {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "private_key.pem")
+	publicPath := filepath.Join(dir, "public_key.pem")
+	for path, block := range map[string]*pem.Block{
+		privatePath: {Type: "PRIVATE KEY", Bytes: privateDER},
+		publicPath:  {Type: "PUBLIC KEY", Bytes: publicDER},
+	} {
+		if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manager, err := NewJWTManager(&config.JWTConfig{
+		Issuer:               testIssuer,
+		PrivateKeyPath:       privatePath,
+		PublicKeyPath:        publicPath,
+		AccessTokenDuration:  15 * time.Minute,
+		RefreshTokenDuration: time.Hour,
+		RefreshTokenSecret:   "test-secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+// signClaims signs claims with the manager's key, without setting any
+func signClaims(t *testing.T, m *JWTManager, claims Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(signingMethods[m.activeKey.alg], claims)
+	token.Header["kid"] = m.activeKey.id
+	signed, err := token.SignedString(m.activeKey.privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestValidateTokenIssuerAndAudience(t *testing.T) {
+	m := newTestManager(t)
+	registered := func(issuer string, audience ...string) jwt.RegisteredClaims {
+		return jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "user-1",
+			Audience:  audience,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		}
+	}
+
+	tests := []struct {
+		name    string
+		claims  jwt.RegisteredClaims
+		options []ValidationOption
+		wantErr bool
+	}{
+		{"own API", registered(testIssuer, testIssuer), []ValidationOption{WithAudience(testIssuer)}, false},
+		{"audience not checked", registered(testIssuer, "https://orders.example.com"), nil, false},
+		{"other audience", registered(testIssuer, "https://orders.example.com"), []ValidationOption{WithAudience(testIssuer)}, true},
+		{"one of several audiences", registered(testIssuer, "https://orders.example.com", testIssuer), []ValidationOption{WithAudience(testIssuer)}, false},
+		{"other issuer", registered("https://other.example.com", testIssuer), nil, true},
+		{"pre-configurable issuer without audience", registered("auth-service"), []ValidationOption{WithAudience(testIssuer)}, true},
+		{"pre-configurable issuer, audience not checked", registered("auth-service"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := signClaims(t, m, Claims{UserID: "user-1", RegisteredClaims: tt.claims})
+			_, err := m.ValidateToken(token, tt.options...)
+			if tt.wantErr != (err != nil) {
+				t.Errorf("got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
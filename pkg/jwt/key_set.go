@@ -0,0 +1,106 @@
+package jwt
+
+import (
+	"crypto"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// KeySet is a set of public keys registered by a client, used to verify
+// the JWTs the client signs
+type KeySet struct {
+	keys []*signingKey
+}
+
+// ParseKeySet parses a JSON Web Key Set (RFC 7517) of public keys
+// Keys meant for encryption are skipped. Keys without "kid" are identified
+// by their JWK thumbprint
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &jwks); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	set := &KeySet{}
+	for i, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d: %w", i, err)
+		}
+
+		key, err := newSigningKey(nil, publicKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %d: %w", i, err)
+		}
+		if jwk.Kid != "" {
+			key.id = jwk.Kid
+		}
+		set.keys = append(set.keys, key)
+	}
+
+	if len(set.keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no signing keys")
+	}
+
+	return set, nil
+}
+
+// Contains checks if a public key is in the set
+func (s *KeySet) Contains(publicKey crypto.PublicKey) bool {
+	for _, key := range s.keys {
+		if publicKeysEqual(key.publicKey, publicKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifyAssertion verifies the signature and expiry of a JWT signed by the
+// owner of the key set, such as a client assertion (RFC 7523), and returns
+// its claims. The caller checks issuer, subject, audience and jti
+func (s *KeySet) VerifyAssertion(tokenString string) (*jwt.RegisteredClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+		// Without "kid", every key of the algorithm is tried
+		kid, _ := token.Header["kid"].(string)
+
+		var keySet jwt.VerificationKeySet
+		for _, key := range s.keys {
+			if key.alg == token.Method.Alg() && (kid == "" || key.id == kid) {
+				keySet.Keys = append(keySet.Keys, key.publicKey)
+			}
+		}
+		if len(keySet.Keys) == 0 {
+			return nil, fmt.Errorf("no key found for kid %q and algorithm %s", kid, token.Method.Alg())
+		}
+		return keySet, nil
+	}, jwt.WithValidMethods(SupportedAlgorithms), jwt.WithExpirationRequired())
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims, ok := token.Claims.(*jwt.RegisteredClaims); ok && token.Valid {
+		return claims, nil
+	}
+
+	return nil, fmt.Errorf("invalid token")
+}
+
+// UnverifiedSubject returns the subject of a JWT without verifying it, to
+// find the keys it must be verified with
+func UnverifiedSubject(tokenString string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return "", err
+	}
+
+	return claims.Subject, nil
+}
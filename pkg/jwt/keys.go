@@ -0,0 +1,288 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// signingMethods maps supported algorithms to their signing methods
+var signingMethods = map[string]jwt.SigningMethod{
+	AlgorithmRS256: jwt.SigningMethodRS256,
+	AlgorithmES256: jwt.SigningMethodES256,
+	AlgorithmEdDSA: jwt.SigningMethodEdDSA,
+}
+
+// SupportedAlgorithms lists the signing algorithms of the supported key types
+var SupportedAlgorithms = []string{AlgorithmRS256, AlgorithmES256, AlgorithmEdDSA}
+
+// minRSAKeySize is the minimum size of RSA keys accepted from clients
+const minRSAKeySize = 2048
+
+// signingKey is a key in the key ring
+// privateKey is nil for verification-only (retired or next) keys
+type signingKey struct {
+	id         string
+	alg        string
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// newSigningKey creates a key ring entry identified by its JWK thumbprint
+func newSigningKey(privateKey crypto.PrivateKey, publicKey crypto.PublicKey) (*signingKey, error) {
+	alg, err := algorithmForKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := thumbprint(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key ID: %w", err)
+	}
+
+	return &signingKey{
+		id:         kid,
+		alg:        alg,
+		privateKey: privateKey,
+		publicKey:  publicKey,
+	}, nil
+}
+
+// algorithmForKey returns the signing algorithm used with a public key
+func algorithmForKey(publicKey crypto.PublicKey) (string, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return AlgorithmRS256, nil
+	case *ecdsa.PublicKey:
+		if key.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported elliptic curve %s, only P-256 is supported", key.Curve.Params().Name)
+		}
+		return AlgorithmES256, nil
+	case ed25519.PublicKey:
+		return AlgorithmEdDSA, nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// publicJWK returns the required JWK members of a public key
+func publicJWK(publicKey crypto.PublicKey) (map[string]interface{}, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		// Convert modulus and exponent to base64 URL encoding
+		return map[string]interface{}{
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case *ecdsa.PublicKey:
+		// Uncompressed point: 0x04 || X || Y, with fixed-size coordinates
+		point, err := key.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		size := (len(point) - 1) / 2
+		return map[string]interface{}{
+			"kty": "EC",
+			"crv": key.Curve.Params().Name,
+			"x":   base64.RawURLEncoding.EncodeToString(point[1 : 1+size]),
+			"y":   base64.RawURLEncoding.EncodeToString(point[1+size:]),
+		}, nil
+	case ed25519.PublicKey:
+		return map[string]interface{}{
+			"kty": "OKP",
+			"crv": "Ed25519",
+			"x":   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", publicKey)
+	}
+}
+
+// jsonWebKey represents the members of a JWK (RFC 7517) used by supported keys
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d"`
+}
+
+// publicKey returns the public key of a JWK, the reverse of publicJWK
+func (jwk *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	if jwk.D != "" {
+		return nil, fmt.Errorf("private keys are not accepted")
+	}
+
+	switch jwk.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		key := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if key.N.BitLen() < minRSAKeySize {
+			return nil, fmt.Errorf("RSA keys must have at least %d bits", minRSAKeySize)
+		}
+		return key, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return nil, fmt.Errorf("unsupported elliptic curve %s, only P-256 is supported", jwk.Crv)
+		}
+		x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+		y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+		if errX != nil || errY != nil || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("invalid EC coordinates")
+		}
+		// Rejects points that are not on the curve
+		point := append(append([]byte{4}, x...), y...)
+		return ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s, only Ed25519 is supported", jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.Kty)
+	}
+}
+
+// thumbprint computes the RFC 7638 JWK thumbprint of a public key
+// It is used as the key ID so that the same key always gets the same "kid"
+func thumbprint(publicKey crypto.PublicKey) (string, error) {
+	jwk, err := publicJWK(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	// encoding/json sorts map keys, which yields the canonical member order
+	jsonBytes, err := json.Marshal(jwk)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(jsonBytes)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// accessTokenHash computes the at_hash claim of an ID token: the left half of
+// the access token hash, using the hash function of the signing algorithm
+func accessTokenHash(alg, accessToken string) (string, error) {
+	var sum []byte
+	switch alg {
+	case AlgorithmRS256, AlgorithmES256:
+		digest := sha256.Sum256([]byte(accessToken))
+		sum = digest[:]
+	case AlgorithmEdDSA:
+		// Ed25519 signs with SHA-512
+		digest := sha512.Sum512([]byte(accessToken))
+		sum = digest[:]
+	default:
+		return "", fmt.Errorf("unsupported signing algorithm: %s", alg)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2]), nil
+}
+
+// loadPrivateKey loads an RSA, ECDSA or Ed25519 private key from PEM file
+func loadPrivateKey(path string) (crypto.PrivateKey, crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, &key.PublicKey, nil
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, &key.PublicKey, nil
+	}
+
+	// Try PKCS8 format
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, &key.PublicKey, nil
+	case *ecdsa.PrivateKey:
+		return key, &key.PublicKey, nil
+	case ed25519.PrivateKey:
+		return key, key.Public(), nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// loadPublicKey loads an RSA, ECDSA or Ed25519 public key from PEM file
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	// Try PKIX format
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// readPEM reads the first PEM block of a file
+func readPEM(path string) (*pem.Block, error) {
+	keyData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(keyData)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode PEM block")
+	}
+
+	return block, nil
+}
+
+// publicKeysEqual checks if two public keys are the same key
+func publicKeysEqual(a, b crypto.PublicKey) bool {
+	key, ok := a.(interface{ Equal(crypto.PublicKey) bool })
+	return ok && key.Equal(b)
+}
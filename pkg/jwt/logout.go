@@ -0,0 +1,76 @@
+package jwt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// logoutTokenType is the "typ" header of logout tokens
+const logoutTokenType = "logout+jwt"
+
+// BackchannelLogoutEvent is the event of logout tokens
+// (OpenID Connect Back-Channel Logout 1.0 section 2.4)
+const BackchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+// logoutTokenDuration is how long relying parties may accept a logout token
+const logoutTokenDuration = 2 * time.Minute
+
+// logoutTokenClaims represents the claims of a logout token
+type logoutTokenClaims struct {
+	SessionID string                            `json:"sid,omitempty"`
+	Events    map[string]map[string]interface{} `json:"events"`
+	jwt.RegisteredClaims
+}
+
+// GenerateLogoutToken generates a logout token telling a client that the
+// session of a user has ended. Logout tokens never carry a nonce, so they
+// can't be mistaken for ID tokens
+func (m *JWTManager) GenerateLogoutToken(userID, clientID, sessionID string) (string, error) {
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
+	claims := logoutTokenClaims{
+		SessionID: sessionID,
+		Events: map[string]map[string]interface{}{
+			BackchannelLogoutEvent: {},
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(logoutTokenDuration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			Issuer:    m.issuer,
+			Subject:   userID,
+			Audience:  jwt.ClaimStrings{clientID},
+			ID:        jti,
+		},
+	}
+
+	token := jwt.NewWithClaims(signingMethods[m.activeKey.alg], claims)
+	token.Header["kid"] = m.activeKey.id
+	token.Header["typ"] = logoutTokenType
+	return token.SignedString(m.activeKey.privateKey)
+}
+
+// ParseIDTokenHint verifies an ID token issued by the manager and returns
+// its claims. Expired ID tokens are accepted, as relying parties usually
+// send the ID token of the login when the user logs out much later
+func (m *JWTManager) ParseIDTokenHint(tokenString string) (*IDTokenClaims, error) {
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc,
+		jwt.WithValidMethods(m.allowedAlgorithms), jwt.WithoutClaimsValidation())
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Issuer != m.issuer {
+		return nil, fmt.Errorf("unexpected issuer: %s", claims.Issuer)
+	}
+	if claims.Subject == "" || claims.AuthorizedParty == "" {
+		return nil, fmt.Errorf("not an ID token")
+	}
+
+	return claims, nil
+}
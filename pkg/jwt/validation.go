@@ -0,0 +1,15 @@
+package jwt
+
+// ValidationOption configures additional checks of ValidateToken
+type ValidationOption func(*validationOptions)
+
+type validationOptions struct {
+	audience string
+}
+
+// WithAudience requires tokens to be intended for an audience (RFC 8707)
+func WithAudience(audience string) ValidationOption {
+	return func(o *validationOptions) {
+		o.audience = audience
+	}
+}
@@ -14,8 +14,14 @@ type BaseModelNanoID struct {
 // BeforeCreate generates a NanoID before creating the entity
 func (m *BaseModelNanoID) BeforeCreate(tx *gorm.DB) (err error) {
 	if m.ID == "" {
-		// Use unambiguous alphabet: no 0, 1, I, O, l, o
-		m.ID, _ = gonanoid.Generate("23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz", 16)
+		m.ID = NewNanoID()
 	}
 	return
 }
+
+// NewNanoID generates a 16-character NanoID
+func NewNanoID() string {
+	// Use unambiguous alphabet: no 0, 1, I, O, l, o
+	id, _ := gonanoid.Generate("23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz", 16)
+	return id
+}
@@ -1,16 +1,35 @@
 #!/bin/bash
 
-# Script to generate RSA keys for JWT signing
-# These keys are used for RS256 algorithm
+# Script to generate keys for JWT signing
+# Usage: ./scripts/generate_keys.sh [rsa|ec|ed25519]
+#   rsa     - RSA 4096 keys for RS256 (default)
+#   ec      - ECDSA P-256 keys for ES256
+#   ed25519 - Ed25519 keys for EdDSA
 
-echo "Generating RSA keys for JWT..."
+KEY_TYPE=${1:-rsa}
+
+echo "Generating $KEY_TYPE keys for JWT..."
 
 # Create keys directory if it doesn't exist
 mkdir -p keys
 
-# Generate private key (4096 bits for strong security)
 echo "Generating private key..."
-openssl genrsa -out keys/private_key.pem 4096
+case "$KEY_TYPE" in
+    rsa)
+        # 4096 bits for strong security
+        openssl genrsa -out keys/private_key.pem 4096
+        ;;
+    ec)
+        openssl genpkey -algorithm EC -pkeyopt ec_paramgen_curve:P-256 -out keys/private_key.pem
+        ;;
+    ed25519)
+        openssl genpkey -algorithm ed25519 -out keys/private_key.pem
+        ;;
+    *)
+        echo "Error: Unknown key type '$KEY_TYPE' (expected rsa, ec or ed25519)"
+        exit 1
+        ;;
+esac
 
 if [ $? -ne 0 ]; then
     echo "Error: Failed to generate private key"
@@ -19,7 +38,7 @@ fi
 
 # Generate public key from private key
 echo "Generating public key..."
-openssl rsa -in keys/private_key.pem -pubout -out keys/public_key.pem
+openssl pkey -in keys/private_key.pem -pubout -out keys/public_key.pem
 
 if [ $? -ne 0 ]; then
     echo "Error: Failed to generate public key"
@@ -31,7 +50,7 @@ chmod 600 keys/private_key.pem
 chmod 644 keys/public_key.pem
 
 echo ""
-echo "✅ RSA keys generated successfully!"
+echo "✅ $KEY_TYPE keys generated successfully!"
 echo ""
 echo "Private key: keys/private_key.pem"
 echo "Public key:  keys/public_key.pem"